@@ -0,0 +1,128 @@
+package main
+
+import (
+	"time"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+var ErrSalarioInvalido = erros.Definir("folha.salario_invalido", erros.Validacao).Sentinela("salário deve ser positivo")
+
+// Funcionario é o que a folha precisa saber de cada pessoa. O salário é o
+// bruto mensal em centavos.
+type Funcionario struct {
+	Nome        string
+	Salario     int
+	Dependentes int
+}
+
+// ParcelaINSS é quanto uma faixa da tabela contribuiu para o desconto.
+type ParcelaINSS struct {
+	Faixa    Faixa
+	Base     int
+	Desconto int
+}
+
+// Holerite é o resultado do cálculo de um funcionário numa competência,
+// com tudo o que é preciso para conferir cada desconto.
+type Holerite struct {
+	Funcionario Funcionario
+	Competencia time.Time
+	Tabela      string
+
+	Bruto int
+
+	INSS         int
+	ParcelasINSS []ParcelaINSS
+
+	// BaseIRRF é o bruto menos as deduções escolhidas: INSS e dependentes
+	// (dedução legal) ou o desconto simplificado, o que for mais vantajoso.
+	BaseIRRF             int
+	DeducaoDependentes   int
+	DescontoSimplificado bool
+	FaixaIRRF            Faixa
+	IRRF                 int
+
+	Liquido int
+
+	// FGTS é depositado pelo empregador e não sai do salário; aparece no
+	// holerite só como informação.
+	FGTS int
+}
+
+// Descontos soma o que sai do salário bruto.
+func (h Holerite) Descontos() int {
+	return h.INSS + h.IRRF
+}
+
+// CalcularFolha monta o holerite de um funcionário com as tabelas que valem
+// na competência.
+func (t Tabelas) CalcularFolha(f Funcionario, competencia time.Time) (Holerite, error) {
+	if f.Salario <= 0 {
+		return Holerite{}, ErrSalarioInvalido
+	}
+	ano, irrf, err := t.Para(competencia)
+	if err != nil {
+		return Holerite{}, err
+	}
+
+	h := Holerite{
+		Funcionario: f,
+		Competencia: competencia,
+		Tabela:      ano.Fonte,
+		Bruto:       f.Salario,
+	}
+	h.INSS, h.ParcelasINSS = calcularINSS(f.Salario, ano.INSS)
+
+	// O desconto simplificado substitui todas as deduções legais; vale o
+	// que der a menor base.
+	h.DeducaoDependentes = f.Dependentes * irrf.DeducaoDependente
+	h.BaseIRRF = f.Salario - h.INSS - h.DeducaoDependentes
+	if simplificada := f.Salario - irrf.DescontoSimplificado; simplificada < h.BaseIRRF {
+		h.BaseIRRF = simplificada
+		h.DescontoSimplificado = true
+	}
+	h.BaseIRRF = max(h.BaseIRRF, 0)
+	h.FaixaIRRF, h.IRRF = calcularIRRF(h.BaseIRRF, irrf.Faixas)
+
+	h.Liquido = h.Bruto - h.Descontos()
+	h.FGTS = arredondar(f.Salario * ano.FGTS)
+	return h, nil
+}
+
+// calcularINSS aplica cada alíquota só sobre a parte do salário que cai na
+// sua faixa. O que passa do teto (a última faixa) não contribui. O total é
+// arredondado uma vez só, sobre a soma exata; por isso
+// as parcelas arredondadas podem diferir do total em um centavo.
+func calcularINSS(salario int, faixas []Faixa) (int, []ParcelaINSS) {
+	exato := 0
+	var parcelas []ParcelaINSS
+	inicio := 0
+	for _, f := range faixas {
+		if salario <= inicio {
+			break
+		}
+		base := min(salario, f.Ate) - inicio
+		exato += base * f.Aliquota
+		parcelas = append(parcelas, ParcelaINSS{Faixa: f, Base: base, Desconto: arredondar(base * f.Aliquota)})
+		inicio = f.Ate
+	}
+	return arredondar(exato), parcelas
+}
+
+// calcularIRRF usa a alíquota da faixa onde a base cai e abate a parcela a
+// deduzir, que é o que torna o cálculo progressivo.
+func calcularIRRF(base int, faixas []Faixa) (Faixa, int) {
+	for _, f := range faixas {
+		if f.Ate == 0 || base <= f.Ate {
+			return f, max(arredondar(base*f.Aliquota)-f.Deducao, 0)
+		}
+	}
+	return Faixa{}, 0 // inalcançável: a última faixa não tem limite
+}
+
+// arredondar converte centavos × pontos-base em centavos, arredondando
+// meio centavo para cima.
+func arredondar(centavosPontosBase int) int {
+	return (centavosPontosBase + 5000) / 10000
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func tabelasPadrao(t *testing.T) Tabelas {
+	t.Helper()
+	tabelas, err := TabelasPadrao()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tabelas
+}
+
+func competencia(ano int, mes time.Month) time.Time {
+	return time.Date(ano, mes, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCalcularFolha(t *testing.T) {
+	tabelas := tabelasPadrao(t)
+	casos := []struct {
+		nome                 string
+		funcionario          Funcionario
+		competencia          time.Time
+		inss, irrf, base     int
+		descontoSimplificado bool
+		tabela               string
+	}{
+		// o holerite do Lucios no programa de exemplo
+		{"exemplo", Funcionario{Nome: "Lucios", Salario: 600000, Dependentes: 1}, competencia(2025, 6), 64960, 51049, 516081, false, "2025.json"},
+		{"dois dependentes", Funcionario{Salario: 300000, Dependentes: 2}, competencia(2025, 6), 25341, 0, 236741, false, "2025.json"},
+		// 3.000,00 - 607,20 = 2.392,80, abaixo do limite de isenção
+		{"desconto simplificado", Funcionario{Salario: 300000}, competencia(2025, 6), 25341, 0, 239280, true, "2025.json"},
+		{"tabela de 2024", Funcionario{Salario: 300000}, competencia(2024, 3), 25882, 1320, 243520, true, "2024.json"},
+		{"salário mínimo", Funcionario{Salario: 151800}, competencia(2025, 1), 11385, 0, 95320, true, "2025.json"},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			h, err := tabelas.CalcularFolha(c.funcionario, c.competencia)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.INSS != c.inss || h.IRRF != c.irrf || h.BaseIRRF != c.base || h.DescontoSimplificado != c.descontoSimplificado {
+				t.Errorf("INSS %d, IRRF %d, base %d, simplificado %v; quero %d, %d, %d, %v",
+					h.INSS, h.IRRF, h.BaseIRRF, h.DescontoSimplificado, c.inss, c.irrf, c.base, c.descontoSimplificado)
+			}
+			if h.Tabela != c.tabela {
+				t.Errorf("tabela %q, quero %q", h.Tabela, c.tabela)
+			}
+			if h.Liquido != h.Bruto-h.INSS-h.IRRF || h.Descontos() != h.INSS+h.IRRF {
+				t.Errorf("líquido %d com bruto %d e descontos %d", h.Liquido, h.Bruto, h.Descontos())
+			}
+			if h.FGTS != c.funcionario.Salario*8/100 {
+				t.Errorf("FGTS %d, quero 8%% de %d", h.FGTS, c.funcionario.Salario)
+			}
+		})
+	}
+}
+
+func TestCalcularFolhaErros(t *testing.T) {
+	tabelas := tabelasPadrao(t)
+	if _, err := tabelas.CalcularFolha(Funcionario{Salario: 0}, competencia(2025, 6)); !errors.Is(err, ErrSalarioInvalido) {
+		t.Errorf("salário zero: erro = %v, quero ErrSalarioInvalido", err)
+	}
+	if _, err := tabelas.CalcularFolha(Funcionario{Salario: 100000}, competencia(2023, 12)); !errors.Is(err, ErrTabelaNaoEncontrada) {
+		t.Errorf("competência sem tabela: erro = %v, quero ErrTabelaNaoEncontrada", err)
+	}
+}
+
+// Em maio de 2025 a faixa de isenção do IRRF subiu para 2.428,80 e o
+// desconto simplificado para 607,20: o mesmo salário paga imposto em abril
+// e não paga em maio.
+func TestCalcularFolhaTrocaDeTabelaEmMaio2025(t *testing.T) {
+	tabelas := tabelasPadrao(t)
+	f := Funcionario{Salario: 300000}
+	casos := []struct {
+		mes        time.Month
+		base, irrf int
+	}{
+		{time.January, 243520, 1320},
+		{time.April, 243520, 1320},
+		{time.May, 239280, 0},
+		{time.December, 239280, 0},
+	}
+	for _, c := range casos {
+		h, err := tabelas.CalcularFolha(f, competencia(2025, c.mes))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.BaseIRRF != c.base || h.IRRF != c.irrf {
+			t.Errorf("%s: base %d, IRRF %d; quero %d, %d", c.mes, h.BaseIRRF, h.IRRF, c.base, c.irrf)
+		}
+	}
+}
+
+func TestCalcularINSSProgressivo(t *testing.T) {
+	faixas := tabelasPadrao(t)[2025].INSS
+	casos := []struct {
+		nome     string
+		salario  int
+		total    int
+		parcelas []int
+	}{
+		{"primeira faixa", 100000, 7500, []int{7500}},
+		{"no limite da primeira", 151800, 11385, []int{11385}},
+		{"um centavo acima", 151801, 11385, []int{11385, 0}},
+		{"três faixas", 300000, 25341, []int{11385, 11483, 2473}},
+		{"exemplo", 600000, 64960, []int{11385, 11483, 16763, 25328}},
+		{"no teto", 815741, 95163, []int{11385, 11483, 16763, 55532}},
+		{"acima do teto", 2000000, 95163, []int{11385, 11483, 16763, 55532}},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			total, parcelas := calcularINSS(c.salario, faixas)
+			if total != c.total {
+				t.Errorf("INSS = %d, quero %d", total, c.total)
+			}
+			if len(parcelas) != len(c.parcelas) {
+				t.Fatalf("%d parcelas, quero %d", len(parcelas), len(c.parcelas))
+			}
+			base := 0
+			for i, p := range parcelas {
+				if p.Desconto != c.parcelas[i] {
+					t.Errorf("parcela %d = %d, quero %d", i, p.Desconto, c.parcelas[i])
+				}
+				base += p.Base
+			}
+			if base != min(c.salario, 815741) {
+				t.Errorf("soma das bases = %d, quero o salário limitado ao teto", base)
+			}
+		})
+	}
+}
+
+func TestCalcularIRRF(t *testing.T) {
+	faixas := tabelasPadrao(t)[2025].IRRF[1].Faixas
+	casos := []struct {
+		base, aliquota, irrf int
+	}{
+		{0, 0, 0},
+		{242880, 0, 0}, // limite da isenção
+		{242881, 750, 0},
+		{250000, 750, 534},
+		{282665, 750, 2984},
+		{282666, 1500, 2984},
+		{466468, 2250, 37406},
+		{466469, 2750, 37406},
+		{516081, 2750, 51049},
+	}
+	for _, c := range casos {
+		faixa, irrf := calcularIRRF(c.base, faixas)
+		if faixa.Aliquota != c.aliquota || irrf != c.irrf {
+			t.Errorf("base %d: alíquota %d, IRRF %d; quero %d, %d", c.base, faixa.Aliquota, irrf, c.aliquota, c.irrf)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+var (
+	ErrSemSalario       = erros.Definir("historico.sem_salario", erros.NaoEncontrado).Sentinela("sem salário na data")
+	ErrReajusteInvalido = erros.Definir("historico.reajuste_invalido", erros.Validacao).Sentinela("reajuste deixaria o salário sem valor positivo")
+)
+
+// vigenciaSalario é um valor de salário que passa a valer numa data e vale
+// até a próxima vigência do mesmo funcionário. Salario zero marca o
+// desligamento.
+type vigenciaSalario struct {
+	Desde   time.Time
+	Salario int
+	Motivo  string
+}
+
+// HistoricoSalarial guarda, por funcionário, a lista de vigências ordenada
+// por data. Diferente de salary[nome] = valor, nada é sobrescrito: alterar
+// um salário acrescenta uma vigência nova e o valor antigo continua
+// respondendo para as datas anteriores.
+type HistoricoSalarial struct {
+	mu        sync.RWMutex
+	vigencias map[string][]vigenciaSalario
+}
+
+func NovoHistoricoSalarial() *HistoricoSalarial {
+	return &HistoricoSalarial{vigencias: map[string][]vigenciaSalario{}}
+}
+
+// Definir registra que o salário do funcionário passa a ser salario (em
+// centavos) a partir de desde. Uma vigência que já exista na mesma data é
+// substituída.
+func (h *HistoricoSalarial) Definir(nome string, desde time.Time, salario int, motivo string) error {
+	if salario <= 0 {
+		return ErrSalarioInvalido
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.registrar(nome, vigenciaSalario{Desde: dia(desde), Salario: salario, Motivo: motivo})
+	return nil
+}
+
+// Desligar encerra o salário do funcionário a partir de desde. O histórico
+// anterior continua disponível.
+func (h *HistoricoSalarial) Desligar(nome string, desde time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.salarioEm(nome, dia(desde)); !ok {
+		return fmt.Errorf("%w: %s em %s", ErrSemSalario, nome, desde.Format(time.DateOnly))
+	}
+	h.registrar(nome, vigenciaSalario{Desde: dia(desde), Motivo: "desligamento"})
+	return nil
+}
+
+// SalarioEm responde qual era o salário do funcionário na data.
+func (h *HistoricoSalarial) SalarioEm(nome string, data time.Time) (int, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	salario, ok := h.salarioEm(nome, dia(data))
+	if !ok {
+		return 0, fmt.Errorf("%w: %s em %s", ErrSemSalario, nome, data.Format(time.DateOnly))
+	}
+	return salario, nil
+}
+
+// Em devolve a foto da folha na data, no mesmo formato do mapa salary
+// (mas em centavos). Quem ainda não tinha sido contratado ou já tinha sido
+// desligado fica de fora.
+func (h *HistoricoSalarial) Em(data time.Time) map[string]int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	foto := map[string]int{}
+	for nome := range h.vigencias {
+		if salario, ok := h.salarioEm(nome, dia(data)); ok {
+			foto[nome] = salario
+		}
+	}
+	return foto
+}
+
+// Reajuste é um aumento percentual (em pontos-base, 5% = 500) ou um valor
+// fixo em centavos. Valores negativos reduzem o salário.
+type Reajuste struct {
+	Percentual int
+	Valor      int
+	Motivo     string
+}
+
+func (r Reajuste) aplicar(salario int) int {
+	if r.Percentual != 0 {
+		return arredondar(salario * (10000 + r.Percentual))
+	}
+	return salario + r.Valor
+}
+
+// Reajustar aplica o reajuste, a partir de desde, a todos os funcionários
+// ativos na data, ou só aos nomes informados (que precisam estar ativos).
+// Vigências posteriores a desde já registradas continuam valendo nas datas
+// delas. A operação é tudo ou nada: se algum nome não estiver ativo ou algum
+// salário ficaria sem valor positivo, nada é alterado.
+func (h *HistoricoSalarial) Reajustar(desde time.Time, reajuste Reajuste, nomes ...string) error {
+	desde = dia(desde)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	escolhidos := len(nomes) > 0
+	if !escolhidos {
+		for nome := range h.vigencias {
+			nomes = append(nomes, nome)
+		}
+	}
+
+	novos := map[string]int{}
+	for _, nome := range nomes {
+		atual, ok := h.salarioEm(nome, desde)
+		if !ok {
+			if escolhidos {
+				return fmt.Errorf("%w: %s em %s", ErrSemSalario, nome, desde.Format(time.DateOnly))
+			}
+			continue // desligado ou ainda não contratado: não há o que reajustar
+		}
+		novo := reajuste.aplicar(atual)
+		if novo <= 0 {
+			return fmt.Errorf("%w: %s", ErrReajusteInvalido, nome)
+		}
+		novos[nome] = novo
+	}
+
+	motivo := reajuste.Motivo
+	if motivo == "" {
+		motivo = "reajuste"
+	}
+	for nome, salario := range novos {
+		h.registrar(nome, vigenciaSalario{Desde: desde, Salario: salario, Motivo: motivo})
+	}
+	return nil
+}
+
+// DiferencaSalarial é uma linha do relatório entre duas datas.
+type DiferencaSalarial struct {
+	Nome     string
+	Antes    int
+	Depois   int
+	Situacao string
+	Motivos  []string
+}
+
+// Diferenca devolve Depois - Antes, em centavos.
+func (d DiferencaSalarial) Diferenca() int {
+	return d.Depois - d.Antes
+}
+
+// Comparar monta o relatório de quem mudou entre as duas datas: admitidos,
+// desligados e reajustados, com os motivos das vigências no intervalo. Quem
+// não mudou aparece como "sem alteração". O resultado vem ordenado por nome.
+func (h *HistoricoSalarial) Comparar(de, ate time.Time) []DiferencaSalarial {
+	de, ate = dia(de), dia(ate)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var linhas []DiferencaSalarial
+	for nome, vigencias := range h.vigencias {
+		antes, ativoAntes := h.salarioEm(nome, de)
+		depois, ativoDepois := h.salarioEm(nome, ate)
+		if !ativoAntes && !ativoDepois {
+			continue
+		}
+		linha := DiferencaSalarial{Nome: nome, Antes: antes, Depois: depois}
+		for _, v := range vigencias {
+			if v.Salario != 0 && v.Desde.After(de) && !v.Desde.After(ate) {
+				linha.Motivos = append(linha.Motivos, v.Motivo)
+			}
+		}
+		switch {
+		case !ativoAntes:
+			linha.Situacao = "admitido"
+		case !ativoDepois:
+			linha.Situacao = "desligado"
+		case antes != depois:
+			linha.Situacao = "reajustado"
+		default:
+			linha.Situacao = "sem alteração"
+		}
+		linhas = append(linhas, linha)
+	}
+	sort.Slice(linhas, func(i, j int) bool { return linhas[i].Nome < linhas[j].Nome })
+	return linhas
+}
+
+// ImprimirComparacao escreve o relatório de Comparar como tabela de texto,
+// com o total da folha nas duas datas.
+func ImprimirComparacao(w io.Writer, de, ate time.Time, linhas []DiferencaSalarial) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Folha de %s a %s\n", de.Format("02/01/2006"), ate.Format("02/01/2006"))
+	fmt.Fprintf(&b, "%-12s %12s %12s %12s %8s  %s\n", "Nome", "Antes", "Depois", "Diferença", "%", "Situação")
+	totalAntes, totalDepois := 0, 0
+	for _, l := range linhas {
+		fmt.Fprintf(&b, "%-12s %12s %12s %12s %8s  %s\n", l.Nome,
+			formatarReais(l.Antes), formatarReais(l.Depois), formatarReais(l.Diferenca()),
+			variacao(l.Antes, l.Depois), situacaoComMotivos(l))
+		totalAntes += l.Antes
+		totalDepois += l.Depois
+	}
+	fmt.Fprintf(&b, "%-12s %12s %12s %12s %8s\n", "Total",
+		formatarReais(totalAntes), formatarReais(totalDepois), formatarReais(totalDepois-totalAntes),
+		variacao(totalAntes, totalDepois))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func situacaoComMotivos(l DiferencaSalarial) string {
+	if len(l.Motivos) == 0 {
+		return l.Situacao
+	}
+	return l.Situacao + " (" + strings.Join(l.Motivos, ", ") + ")"
+}
+
+// variacao escreve a variação percentual com uma casa, ou "-" quando não
+// há valor anterior.
+func variacao(antes, depois int) string {
+	if antes == 0 {
+		return "-"
+	}
+	decimos := (depois - antes) * 1000 / antes
+	sinal := "+"
+	if decimos < 0 {
+		sinal, decimos = "-", -decimos
+	}
+	return fmt.Sprintf("%s%d,%d", sinal, decimos/10, decimos%10)
+}
+
+// registrar deve ser chamado com h.mu travado.
+func (h *HistoricoSalarial) registrar(nome string, nova vigenciaSalario) {
+	vigencias := h.vigencias[nome]
+	i, existe := slices.BinarySearchFunc(vigencias, nova.Desde, func(v vigenciaSalario, data time.Time) int {
+		return v.Desde.Compare(data)
+	})
+	if existe {
+		vigencias[i] = nova
+	} else {
+		vigencias = slices.Insert(vigencias, i, nova)
+	}
+	h.vigencias[nome] = vigencias
+}
+
+// salarioEm procura a última vigência até a data. Deve ser chamado com h.mu
+// travado (para leitura, pelo menos).
+func (h *HistoricoSalarial) salarioEm(nome string, data time.Time) (int, bool) {
+	vigencias := h.vigencias[nome]
+	i := sort.Search(len(vigencias), func(i int) bool { return vigencias[i].Desde.After(data) })
+	if i == 0 || vigencias[i-1].Salario == 0 {
+		return 0, false
+	}
+	return vigencias[i-1].Salario, true
+}
+
+// dia descarta a hora: as vigências valem por dia inteiro.
+func dia(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func data(ano int, mes time.Month, d int) time.Time {
+	return time.Date(ano, mes, d, 0, 0, 0, 0, time.UTC)
+}
+
+// historicoDeExemplo: Ana desde 10/01 com aumento em 01/03 e desligamento
+// em 15/06; Bia desde 01/02; Caio só a partir de 01/09.
+func historicoDeExemplo(t *testing.T) *HistoricoSalarial {
+	t.Helper()
+	h := NovoHistoricoSalarial()
+	for _, d := range []struct {
+		nome    string
+		desde   time.Time
+		salario int
+	}{
+		{"Ana", data(2025, 1, 10), 300_000},
+		{"Ana", data(2025, 3, 1), 350_000},
+		{"Bia", data(2025, 2, 1), 500_000},
+		{"Caio", data(2025, 9, 1), 400_000},
+	} {
+		if err := h.Definir(d.nome, d.desde, d.salario, "contratação"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.Desligar("Ana", data(2025, 6, 15)); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestHistoricoEmNasBordasDaVigencia(t *testing.T) {
+	h := historicoDeExemplo(t)
+	for _, c := range []struct {
+		quando time.Time
+		quero  map[string]int
+	}{
+		{data(2025, 1, 9), map[string]int{}},
+		// a vigência vale desde o primeiro instante do dia, até o último
+		{data(2025, 1, 10), map[string]int{"Ana": 300_000}},
+		{time.Date(2025, 1, 10, 23, 59, 59, 0, time.UTC), map[string]int{"Ana": 300_000}},
+		{data(2025, 2, 28), map[string]int{"Ana": 300_000, "Bia": 500_000}},
+		{data(2025, 3, 1), map[string]int{"Ana": 350_000, "Bia": 500_000}},
+		{data(2025, 6, 14), map[string]int{"Ana": 350_000, "Bia": 500_000}},
+		{data(2025, 6, 15), map[string]int{"Bia": 500_000}},
+		{data(2025, 9, 1), map[string]int{"Bia": 500_000, "Caio": 400_000}},
+	} {
+		if got := h.Em(c.quando); !maps.Equal(got, c.quero) {
+			t.Errorf("Em(%s) = %v, quero %v", c.quando.Format(time.DateTime), got, c.quero)
+		}
+	}
+
+	// Definir na mesma data substitui a vigência em vez de acrescentar outra.
+	if err := h.Definir("Bia", data(2025, 2, 1), 550_000, "correção"); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Em(data(2025, 2, 1))["Bia"]; got != 550_000 {
+		t.Errorf("salário da Bia em 01/02 = %d, quero 550000 depois da correção", got)
+	}
+	if got := len(h.vigencias["Bia"]); got != 1 {
+		t.Errorf("Bia tem %d vigências, quero 1", got)
+	}
+}
+
+func TestReajustarTodosOsAtivos(t *testing.T) {
+	h := historicoDeExemplo(t)
+	if err := h.Reajustar(data(2025, 7, 1), Reajuste{Percentual: 550}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ana (desligada) e Caio (não contratado) ficam de fora.
+	quero := map[string]int{"Bia": 527_500}
+	if got := h.Em(data(2025, 7, 1)); !maps.Equal(got, quero) {
+		t.Errorf("Em(01/07) = %v, quero %v", got, quero)
+	}
+	if got := h.Em(data(2025, 6, 30))["Bia"]; got != 500_000 {
+		t.Errorf("Bia em 30/06 = %d, quero 500000: o reajuste não é retroativo", got)
+	}
+	if got, err := h.SalarioEm("Caio", data(2025, 9, 1)); err != nil || got != 400_000 {
+		t.Errorf("Caio em 01/09 = %d, %v; quero 400000", got, err)
+	}
+	for _, l := range h.Comparar(data(2025, 6, 30), data(2025, 7, 1)) {
+		if l.Nome == "Bia" && !slices.Equal(l.Motivos, []string{"reajuste"}) {
+			t.Errorf("motivos da Bia = %v, quero o motivo padrão", l.Motivos)
+		}
+	}
+}
+
+func TestReajustarNaoMexeNasVigenciasPosteriores(t *testing.T) {
+	h := historicoDeExemplo(t)
+	// promoção já registrada para agosto
+	if err := h.Definir("Bia", data(2025, 8, 1), 700_000, "promoção"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Reajustar(data(2025, 5, 1), Reajuste{Valor: 33_333, Motivo: "dissídio"}, "Bia"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		quando time.Time
+		quero  int
+	}{
+		{data(2025, 4, 30), 500_000},
+		{data(2025, 5, 1), 533_333},
+		{data(2025, 7, 31), 533_333},
+		// a promoção continua com o valor registrado, sem o reajuste somado
+		{data(2025, 8, 1), 700_000},
+	} {
+		if got, err := h.SalarioEm("Bia", c.quando); err != nil || got != c.quero {
+			t.Errorf("SalarioEm(Bia, %s) = %d, %v; quero %d", c.quando.Format(time.DateOnly), got, err, c.quero)
+		}
+	}
+}
+
+func TestReajustarTudoOuNada(t *testing.T) {
+	h := historicoDeExemplo(t)
+	antes := h.Em(data(2025, 4, 1))
+
+	// Caio ainda não foi contratado em 01/04: nem a Bia é reajustada.
+	if err := h.Reajustar(data(2025, 4, 1), Reajuste{Percentual: 1_000}, "Bia", "Caio"); !errors.Is(err, ErrSemSalario) {
+		t.Errorf("Reajustar com nome inativo = %v, quero ErrSemSalario", err)
+	}
+	// Uma redução que zera o salário da Ana barra a da Bia também.
+	if err := h.Reajustar(data(2025, 4, 1), Reajuste{Valor: -350_000}); !errors.Is(err, ErrReajusteInvalido) {
+		t.Errorf("Reajustar que zera salário = %v, quero ErrReajusteInvalido", err)
+	}
+	if err := h.Reajustar(data(2025, 4, 1), Reajuste{Percentual: -10_000}); !errors.Is(err, ErrReajusteInvalido) {
+		t.Errorf("Reajustar de -100%% = %v, quero ErrReajusteInvalido", err)
+	}
+	if got := h.Em(data(2025, 4, 1)); !maps.Equal(got, antes) {
+		t.Errorf("Em(01/04) = %v depois de reajustes recusados, quero %v", got, antes)
+	}
+	if got := len(h.vigencias["Bia"]); got != 1 {
+		t.Errorf("Bia tem %d vigências depois de reajustes recusados, quero 1", got)
+	}
+}
+
+func TestReajustePercentualArredonda(t *testing.T) {
+	for _, c := range []struct {
+		salario, pontosBase, quero int
+	}{
+		{333_333, 550, 351_666}, // 351.666,315
+		{100_001, 500, 105_001}, // 105.001,05
+		{100_010, 500, 105_011}, // 105.010,5 sobe
+		{200_000, -1_000, 180_000},
+	} {
+		if got := (Reajuste{Percentual: c.pontosBase}).aplicar(c.salario); got != c.quero {
+			t.Errorf("%d com %d pontos-base = %d, quero %d", c.salario, c.pontosBase, got, c.quero)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Imprimir escreve o holerite no formato de contracheque: proventos,
+// descontos, líquido e as bases usadas no cálculo.
+func (h Holerite) Imprimir(w io.Writer) error {
+	var b strings.Builder
+	linha := strings.Repeat("-", 64)
+
+	fmt.Fprintln(&b, linha)
+	fmt.Fprintf(&b, "HOLERITE  %-36s competência %s\n", h.Funcionario.Nome, h.Competencia.Format("01/2006"))
+	fmt.Fprintln(&b, linha)
+	fmt.Fprintf(&b, "%-34s %14s %14s\n", "Descrição", "Proventos", "Descontos")
+	fmt.Fprintf(&b, "%-34s %14s %14s\n", "Salário base", formatarReais(h.Bruto), "")
+	fmt.Fprintf(&b, "%-34s %14s %14s\n", "INSS", "", formatarReais(h.INSS))
+	for _, p := range h.ParcelasINSS {
+		fmt.Fprintf(&b, "  %-32s %14s %14s\n",
+			fmt.Sprintf("%s%% sobre %s", formatarPercentual(p.Faixa.Aliquota), formatarReais(p.Base)),
+			"", "("+formatarReais(p.Desconto)+")")
+	}
+	fmt.Fprintf(&b, "%-34s %14s %14s\n", "IRRF "+formatarPercentual(h.FaixaIRRF.Aliquota)+"%", "", formatarReais(h.IRRF))
+	fmt.Fprintln(&b, linha)
+	fmt.Fprintf(&b, "%-34s %14s %14s\n", "Totais", formatarReais(h.Bruto), formatarReais(h.Descontos()))
+	fmt.Fprintf(&b, "%-34s %29s\n", "Líquido a receber", formatarReais(h.Liquido))
+	fmt.Fprintln(&b, linha)
+
+	deducao := fmt.Sprintf("INSS + %d dependente(s) %s", h.Funcionario.Dependentes, formatarReais(h.DeducaoDependentes))
+	if h.DescontoSimplificado {
+		deducao = "desconto simplificado"
+	}
+	fmt.Fprintf(&b, "Base IRRF: %s (%s)\n", formatarReais(h.BaseIRRF), deducao)
+	fmt.Fprintf(&b, "Base FGTS: %s   FGTS do mês: %s\n", formatarReais(h.Bruto), formatarReais(h.FGTS))
+	fmt.Fprintf(&b, "Tabela: %s\n", h.Tabela)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// formatarReais escreve centavos como "1.234,56".
+func formatarReais(centavos int) string {
+	sinal := ""
+	if centavos < 0 {
+		sinal = "-"
+		centavos = -centavos
+	}
+	inteiro := strconv.Itoa(centavos / 100)
+	for i := len(inteiro) - 3; i > 0; i -= 3 {
+		inteiro = inteiro[:i] + "." + inteiro[i:]
+	}
+	return fmt.Sprintf("%s%s,%02d", sinal, inteiro, centavos%100)
+}
+
+// formatarPercentual escreve pontos-base como "7,5" ou "14".
+func formatarPercentual(pontosBase int) string {
+	if pontosBase%100 == 0 {
+		return strconv.Itoa(pontosBase / 100)
+	}
+	decimais := strings.TrimRight(fmt.Sprintf("%02d", pontosBase%100), "0")
+	return fmt.Sprintf("%d,%s", pontosBase/100, decimais)
+}
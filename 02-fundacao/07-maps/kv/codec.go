@@ -0,0 +1,21 @@
+package kv
+
+import "encoding/json"
+
+// Codec converte chaves e valores para bytes e de volta.
+type Codec interface {
+	Codificar(v any) ([]byte, error)
+	Decodificar(dados []byte, destino any) error
+}
+
+// JSON é o Codec padrão. Serve para qualquer tipo que encoding/json saiba
+// serializar; campos não exportados de structs não são gravados.
+type JSON struct{}
+
+func (JSON) Codificar(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSON) Decodificar(dados []byte, destino any) error {
+	return json.Unmarshal(dados, destino)
+}
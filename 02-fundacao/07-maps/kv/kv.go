@@ -0,0 +1,397 @@
+// Package kv é um armazenamento chave-valor embutido e durável: um mapa em
+// memória cujas alterações vão antes para um log (write-ahead log) em disco.
+//
+// No diretório do Store ficam dois arquivos:
+//
+//   - snapshot: o estado completo no momento da última compactação;
+//   - wal: as alterações feitas depois dela, na ordem em que aconteceram.
+//
+// Abrir lê o snapshot e reaplica o WAL por cima. Um registro cortado no fim
+// do WAL (o processo caiu no meio de uma escrita) é descartado e o arquivo é
+// truncado no último registro íntegro. Um registro inválido seguido de
+// outros íntegros não é queda, é corrupção: Abrir devolve ErrWALCorrompido
+// e não mexe no arquivo.
+package kv
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+// ErrFechado é retornado por operações num Store já fechado.
+var ErrFechado = erros.Definir("kv.fechado", erros.Conflito).Sentinela("kv: store fechado")
+
+// ModoSync diz quando o WAL é sincronizado com o disco (fsync).
+type ModoSync int
+
+const (
+	// SyncSempre sincroniza a cada escrita: nada confirmado se perde, ao
+	// custo de um fsync por operação.
+	SyncSempre ModoSync = iota
+	// SyncPeriodico sincroniza a cada Opcoes.IntervaloSync. Uma queda da
+	// máquina pode perder as escritas do último intervalo; uma queda só do
+	// processo, não, porque os dados já estão no cache do sistema.
+	SyncPeriodico
+	// SyncNunca deixa a sincronização para o sistema operacional.
+	SyncNunca
+)
+
+// Opcoes configura um Store. O valor zero é válido: fsync a cada escrita e
+// compactação manual.
+type Opcoes struct {
+	Sync          ModoSync
+	IntervaloSync time.Duration // usado com SyncPeriodico; padrão de 1s
+
+	// CompactarAcimaDe dispara a compactação automática quando o WAL passa
+	// desse tamanho em bytes. Zero desliga.
+	CompactarAcimaDe int64
+
+	// Codecs de chave e valor. O padrão é JSON.
+	Chaves  Codec
+	Valores Codec
+}
+
+// Recuperacao descreve o que Abrir encontrou no WAL.
+type Recuperacao struct {
+	Registros  int   // registros reaplicados
+	Descartado int64 // bytes descartados no fim do WAL
+}
+
+// Store é um mapa K -> V persistido em disco. É seguro para uso concorrente.
+type Store[K cmp.Ordered, V any] struct {
+	dir    string
+	opcoes Opcoes
+
+	mu          sync.RWMutex
+	dados       map[K]V
+	wal         *os.File
+	tamanhoWAL  int64
+	sujo        bool
+	fechado     bool
+	recuperacao Recuperacao
+	// erroCompactacao é a última falha da compactação automática. A
+	// escrita que a disparou já estava no WAL, então não é dela o erro.
+	erroCompactacao error
+
+	parar       chan struct{}
+	feito       chan struct{}
+	pararUmaVez sync.Once
+}
+
+// Abrir abre (ou cria) o Store no diretório.
+func Abrir[K cmp.Ordered, V any](dir string, opcoes Opcoes) (*Store[K, V], error) {
+	if opcoes.Chaves == nil {
+		opcoes.Chaves = JSON{}
+	}
+	if opcoes.Valores == nil {
+		opcoes.Valores = JSON{}
+	}
+	if opcoes.IntervaloSync <= 0 {
+		opcoes.IntervaloSync = time.Second
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	s := &Store[K, V]{dir: dir, opcoes: opcoes, dados: map[K]V{}}
+	if err := s.carregarSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := s.recuperarWAL(); err != nil {
+		return nil, err
+	}
+
+	if opcoes.Sync == SyncPeriodico {
+		s.parar, s.feito = make(chan struct{}), make(chan struct{})
+		go s.sincronizarPeriodicamente()
+	}
+	return s, nil
+}
+
+// Recuperacao informa o que foi reaplicado e descartado ao abrir.
+func (s *Store[K, V]) Recuperacao() Recuperacao {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.recuperacao
+}
+
+func (s *Store[K, V]) Get(chave K) (V, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	valor, ok := s.dados[chave]
+	return valor, ok
+}
+
+func (s *Store[K, V]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.dados)
+}
+
+// Set grava no WAL e só então altera o mapa: se a escrita falhar, o valor
+// em memória continua o anterior. Uma falha da compactação automática que
+// vem depois não desfaz a escrita e não é retornada aqui; veja
+// ErroCompactacao.
+func (s *Store[K, V]) Set(chave K, valor V) error {
+	k, err := s.opcoes.Chaves.Codificar(chave)
+	if err != nil {
+		return fmt.Errorf("kv: chave: %w", err)
+	}
+	v, err := s.opcoes.Valores.Codificar(valor)
+	if err != nil {
+		return fmt.Errorf("kv: valor: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.registrar(opSet, k, v); err != nil {
+		return err
+	}
+	s.dados[chave] = valor
+	s.compactarSeGrande()
+	return nil
+}
+
+// Delete remove a chave. Remover uma chave que não existe não é erro e não
+// escreve nada no WAL.
+func (s *Store[K, V]) Delete(chave K) error {
+	k, err := s.opcoes.Chaves.Codificar(chave)
+	if err != nil {
+		return fmt.Errorf("kv: chave: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.dados[chave]; !ok {
+		return s.erroSeFechado()
+	}
+	if err := s.registrar(opDelete, k, nil); err != nil {
+		return err
+	}
+	delete(s.dados, chave)
+	s.compactarSeGrande()
+	return nil
+}
+
+// Todos percorre o Store em ordem crescente de chave. A iteração trabalha
+// sobre uma cópia tirada no início, então o Store pode ser alterado durante
+// o laço.
+func (s *Store[K, V]) Todos() iter.Seq2[K, V] {
+	return s.Intervalo(nil, nil)
+}
+
+// Intervalo percorre, em ordem, as chaves em [de, ate). Um limite nil fica
+// em aberto.
+func (s *Store[K, V]) Intervalo(de, ate *K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		s.mu.RLock()
+		chaves := slices.Sorted(maps.Keys(s.dados))
+		copia := maps.Clone(s.dados)
+		s.mu.RUnlock()
+
+		for _, k := range chaves {
+			if de != nil && k < *de {
+				continue
+			}
+			if ate != nil && k >= *ate {
+				return
+			}
+			if !yield(k, copia[k]) {
+				return
+			}
+		}
+	}
+}
+
+// Sync força o fsync do WAL.
+func (s *Store[K, V]) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.erroSeFechado(); err != nil {
+		return err
+	}
+	return s.sincronizar()
+}
+
+// Compactar grava o estado atual como snapshot e começa um WAL vazio.
+func (s *Store[K, V]) Compactar() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.erroSeFechado(); err != nil {
+		return err
+	}
+	return s.compactar()
+}
+
+// ErroCompactacao devolve a falha da última compactação automática, ou nil
+// se ela deu certo. O Store continua consistente depois de uma falha: o WAL
+// segue crescendo e a próxima escrita acima do limite tenta de novo.
+func (s *Store[K, V]) ErroCompactacao() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.erroCompactacao
+}
+
+// Fechar sincroniza e fecha o WAL. Chamadas seguintes retornam ErrFechado.
+func (s *Store[K, V]) Fechar() error {
+	if s.parar != nil {
+		s.pararUmaVez.Do(func() {
+			close(s.parar)
+			<-s.feito
+		})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fechado {
+		return ErrFechado
+	}
+	s.fechado = true
+	return errors.Join(s.wal.Sync(), s.wal.Close())
+}
+
+func (s *Store[K, V]) sincronizarPeriodicamente() {
+	defer close(s.feito)
+	ticker := time.NewTicker(s.opcoes.IntervaloSync)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.parar:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if !s.fechado {
+				s.sincronizar()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// registrar acrescenta um registro ao WAL. Se a escrita ou, com SyncSempre,
+// o fsync falhar, o arquivo volta ao tamanho anterior: o registro não foi
+// confirmado e não pode reaparecer na próxima abertura. Deve ser chamado com
+// s.mu travado.
+func (s *Store[K, V]) registrar(op byte, chave, valor []byte) error {
+	if err := s.erroSeFechado(); err != nil {
+		return err
+	}
+	registro := codificarRegistro(op, chave, valor)
+	if _, err := s.wal.Write(registro); err != nil {
+		// desfaz a escrita parcial; senão os próximos registros ficariam
+		// depois de um pedaço inválido e a recuperação recusaria o WAL
+		s.desfazerEscrita()
+		return fmt.Errorf("kv: wal: %w", err)
+	}
+	if s.opcoes.Sync == SyncSempre {
+		if err := s.wal.Sync(); err != nil {
+			s.desfazerEscrita()
+			return fmt.Errorf("kv: fsync: %w", err)
+		}
+	} else {
+		s.sujo = true
+	}
+	s.tamanhoWAL += int64(len(registro))
+	return nil
+}
+
+// desfazerEscrita corta o WAL de volta em s.tamanhoWAL. Deve ser chamado
+// com s.mu travado.
+func (s *Store[K, V]) desfazerEscrita() {
+	s.wal.Truncate(s.tamanhoWAL)
+	s.wal.Seek(s.tamanhoWAL, io.SeekStart)
+}
+
+func (s *Store[K, V]) sincronizar() error {
+	if !s.sujo {
+		return nil
+	}
+	if err := s.wal.Sync(); err != nil {
+		return fmt.Errorf("kv: fsync: %w", err)
+	}
+	s.sujo = false
+	return nil
+}
+
+// compactarSeGrande deve ser chamado com s.mu travado.
+func (s *Store[K, V]) compactarSeGrande() {
+	if s.opcoes.CompactarAcimaDe > 0 && s.tamanhoWAL > s.opcoes.CompactarAcimaDe {
+		s.erroCompactacao = s.compactar()
+	}
+}
+
+// compactar deve ser chamado com s.mu travado.
+//
+// O snapshot novo é escrito num arquivo temporário, sincronizado e
+// renomeado por cima do antigo; só depois o WAL é zerado. Se o processo cair
+// entre as duas etapas, o WAL antigo é reaplicado sobre o snapshot novo, o
+// que não muda nada: o snapshot já é o resultado desse mesmo WAL.
+func (s *Store[K, V]) compactar() error {
+	temporario := filepath.Join(s.dir, arquivoSnapshot+".tmp")
+	f, err := os.OpenFile(temporario, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	for _, chave := range slices.Sorted(maps.Keys(s.dados)) {
+		k, err := s.opcoes.Chaves.Codificar(chave)
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("kv: chave: %w", err)
+		}
+		v, err := s.opcoes.Valores.Codificar(s.dados[chave])
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("kv: valor: %w", err)
+		}
+		if _, err := f.Write(codificarRegistro(opSet, k, v)); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := errors.Join(f.Sync(), f.Close()); err != nil {
+		return err
+	}
+	if err := os.Rename(temporario, filepath.Join(s.dir, arquivoSnapshot)); err != nil {
+		return err
+	}
+	if err := sincronizarDiretorio(s.dir); err != nil {
+		return err
+	}
+
+	if err := s.wal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := s.wal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.tamanhoWAL = 0
+	s.sujo = true
+	return s.sincronizar()
+}
+
+func (s *Store[K, V]) erroSeFechado() error {
+	if s.fechado {
+		return ErrFechado
+	}
+	return nil
+}
+
+// sincronizarDiretorio garante que o rename chegou ao disco.
+func sincronizarDiretorio(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	return errors.Join(d.Sync(), d.Close())
+}
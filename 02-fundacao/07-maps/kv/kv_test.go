@@ -0,0 +1,278 @@
+package kv
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// passoWAL é o que se espera do Store se o WAL terminar logo depois de uma
+// operação: o tamanho do arquivo naquele ponto e o mapa resultante.
+type passoWAL struct {
+	fim    int64
+	estado map[string]int
+}
+
+// TestRecuperacaoAposQueda simula quedas no meio de escritas. Primeiro grava
+// operações aleatórias (com uma compactação no meio) anotando o tamanho do
+// WAL e o estado esperado depois de cada uma. Depois, em cada rodada, copia
+// o snapshot e um pedaço do WAL cortado num ponto aleatório, às vezes com
+// lixo no final, reabre e confere que o Store voltou exatamente ao estado da
+// última operação inteira antes do corte, e que continua aceitando escritas.
+func TestRecuperacaoAposQueda(t *testing.T) {
+	operacoes, rodadas := 400, 500
+	if testing.Short() {
+		rodadas = 50
+	}
+	semente := time.Now().UnixNano()
+	t.Logf("semente %d", semente)
+	aleatorio := rand.New(rand.NewSource(semente))
+
+	origem := t.TempDir()
+	passos := gravarOperacoes(t, origem, operacoes, aleatorio)
+	snapshot, err := os.ReadFile(filepath.Join(origem, "snapshot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wal, err := os.ReadFile(filepath.Join(origem, "wal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for rodada := range rodadas {
+		corte := aleatorio.Int63n(int64(len(wal)) + 1)
+		conteudo := append([]byte{}, wal[:corte]...)
+		if aleatorio.Intn(2) == 0 {
+			lixo := make([]byte, 1+aleatorio.Intn(32))
+			aleatorio.Read(lixo)
+			conteudo = append(conteudo, lixo...)
+		}
+
+		esperado := passos[0]
+		for _, p := range passos {
+			if p.fim <= corte {
+				esperado = p
+			}
+		}
+
+		descartado, err := reabrirEConferir(t.TempDir(), snapshot, conteudo, esperado)
+		if err != nil {
+			t.Fatalf("rodada %d (corte em %d de %d bytes): %v", rodada, corte, len(wal), err)
+		}
+		if want := int64(len(conteudo)) - esperado.fim; descartado != want {
+			t.Fatalf("rodada %d: descartou %d bytes, esperado %d", rodada, descartado, want)
+		}
+	}
+}
+
+// gravarOperacoes devolve os passos a partir da compactação: o primeiro é o
+// estado do snapshot (WAL vazio) e cada seguinte é uma operação a mais.
+func gravarOperacoes(t *testing.T, dir string, operacoes int, aleatorio *rand.Rand) []passoWAL {
+	t.Helper()
+	store, err := Abrir[string, int](dir, Opcoes{Sync: SyncNunca})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Fechar()
+
+	estado := map[string]int{}
+	var passos []passoWAL
+	for i := range operacoes {
+		if i == operacoes/2 {
+			if err := store.Compactar(); err != nil {
+				t.Fatal(err)
+			}
+			passos = []passoWAL{{fim: 0, estado: maps.Clone(estado)}}
+		}
+
+		chave := fmt.Sprintf("chave-%02d", aleatorio.Intn(20))
+		if _, existe := estado[chave]; existe && aleatorio.Intn(4) == 0 {
+			if err := store.Delete(chave); err != nil {
+				t.Fatal(err)
+			}
+			delete(estado, chave)
+		} else {
+			valor := aleatorio.Intn(1_000_000)
+			if err := store.Set(chave, valor); err != nil {
+				t.Fatal(err)
+			}
+			estado[chave] = valor
+		}
+
+		if i >= operacoes/2 {
+			info, err := os.Stat(filepath.Join(dir, "wal"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			passos = append(passos, passoWAL{fim: info.Size(), estado: maps.Clone(estado)})
+		}
+	}
+	return passos
+}
+
+func reabrirEConferir(dir string, snapshot, wal []byte, esperado passoWAL) (int64, error) {
+	if err := os.WriteFile(filepath.Join(dir, "snapshot"), snapshot, 0o644); err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "wal"), wal, 0o644); err != nil {
+		return 0, err
+	}
+
+	store, err := Abrir[string, int](dir, Opcoes{})
+	if err != nil {
+		return 0, err
+	}
+	descartado := store.Recuperacao().Descartado
+	if err := conferirEstado(store, esperado.estado); err != nil {
+		store.Fechar()
+		return 0, err
+	}
+
+	// depois da recuperação o log tem que continuar utilizável
+	if err := store.Set("depois-da-queda", 1); err != nil {
+		store.Fechar()
+		return 0, err
+	}
+	if err := store.Fechar(); err != nil {
+		return 0, err
+	}
+	store, err = Abrir[string, int](dir, Opcoes{})
+	if err != nil {
+		return 0, err
+	}
+	defer store.Fechar()
+	if n := store.Recuperacao().Descartado; n != 0 {
+		return 0, fmt.Errorf("segunda abertura ainda descartou %d bytes", n)
+	}
+	esperadoDepois := maps.Clone(esperado.estado)
+	esperadoDepois["depois-da-queda"] = 1
+	return descartado, conferirEstado(store, esperadoDepois)
+}
+
+// conferirEstado compara o Store com o mapa esperado, inclusive a ordem
+// da iteração.
+func conferirEstado(store *Store[string, int], esperado map[string]int) error {
+	anterior, n := "", 0
+	for chave, valor := range store.Todos() {
+		if n > 0 && chave <= anterior {
+			return fmt.Errorf("iteração fora de ordem: %q depois de %q", chave, anterior)
+		}
+		if v, ok := esperado[chave]; !ok || v != valor {
+			return fmt.Errorf("%s = %d, esperado %d (presente: %v)", chave, valor, v, ok)
+		}
+		anterior = chave
+		n++
+	}
+	if n != len(esperado) {
+		return fmt.Errorf("%d chaves, esperado %d", n, len(esperado))
+	}
+	return nil
+}
+
+func TestCompactacaoAutomaticaComFalhaNaoDesfazEscrita(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Abrir[string, int](dir, Opcoes{Sync: SyncNunca, CompactarAcimaDe: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Fechar()
+
+	// Um diretório no lugar do arquivo temporário faz a compactação falhar.
+	temporario := filepath.Join(dir, arquivoSnapshot+".tmp")
+	if err := os.Mkdir(temporario, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("a", 1); err != nil {
+		t.Fatalf("Set com a compactação falhando: %v", err)
+	}
+	if err := store.Delete("a"); err != nil {
+		t.Fatalf("Delete com a compactação falhando: %v", err)
+	}
+	if store.ErroCompactacao() == nil {
+		t.Fatal("ErroCompactacao = nil, quero a falha da compactação")
+	}
+
+	if err := os.Remove(temporario); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("b", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ErroCompactacao(); err != nil {
+		t.Fatalf("ErroCompactacao depois de compactar = %v, quero nil", err)
+	}
+	store.Fechar()
+
+	store, err = Abrir[string, int](dir, Opcoes{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Fechar()
+	if err := conferirEstado(store, map[string]int{"b": 2}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWALCorrompidoNoMeio(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Abrir[string, int](dir, Opcoes{Sync: SyncNunca})
+	if err != nil {
+		t.Fatal(err)
+	}
+	caminho := filepath.Join(dir, arquivoWAL)
+	var fins []int64
+	for i := range 5 {
+		if err := store.Set(fmt.Sprintf("chave-%d", i), i); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(caminho)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fins = append(fins, info.Size())
+	}
+	if err := store.Fechar(); err != nil {
+		t.Fatal(err)
+	}
+	original, err := os.ReadFile(caminho)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// troca o último byte de um registro (o valor dele)
+	corromper := func(registro int) {
+		t.Helper()
+		wal := append([]byte{}, original...)
+		wal[fins[registro]-1] ^= 0xFF
+		if err := os.WriteFile(caminho, wal, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	corromper(2)
+	if _, err := Abrir[string, int](dir, Opcoes{}); !errors.Is(err, ErrWALCorrompido) {
+		t.Fatalf("Abrir com o terceiro de cinco registros corrompido = %v, quero ErrWALCorrompido", err)
+	}
+	if info, _ := os.Stat(caminho); info.Size() != int64(len(original)) {
+		t.Errorf("o WAL corrompido foi alterado: %d bytes, eram %d", info.Size(), len(original))
+	}
+
+	// o mesmo estrago no último registro é uma escrita interrompida
+	corromper(4)
+	store, err = Abrir[string, int](dir, Opcoes{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Fechar()
+	if r := store.Recuperacao(); r.Registros != 4 || r.Descartado != fins[4]-fins[3] {
+		t.Errorf("Recuperacao = %+v, quero 4 registros e %d bytes descartados", r, fins[4]-fins[3])
+	}
+	if store.Len() != 4 {
+		t.Errorf("%d chaves, quero 4", store.Len())
+	}
+}
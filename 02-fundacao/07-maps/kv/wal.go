@@ -0,0 +1,227 @@
+package kv
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+const (
+	arquivoSnapshot = "snapshot"
+	arquivoWAL      = "wal"
+
+	opSet    byte = 1
+	opDelete byte = 2
+
+	// cabecalho: tamanho do corpo (4 bytes) + CRC-32 do corpo (4 bytes)
+	tamanhoCabecalho = 8
+	// limite de sanidade: um tamanho maior que isso num cabeçalho só pode
+	// ser lixo de uma escrita interrompida
+	tamanhoMaximoCorpo = 64 << 20
+)
+
+var (
+	// ErrCorrompido é retornado quando o snapshot não passa na verificação.
+	ErrCorrompido = erros.Definir("kv.corrompido", erros.Interno).Sentinela("kv: snapshot corrompido")
+	// ErrWALCorrompido é retornado quando há um registro inválido no meio
+	// do WAL. Registros ruins só no fim não são erro: são descartados.
+	ErrWALCorrompido = erros.Definir("kv.wal_corrompido", erros.Interno).Sentinela("kv: wal corrompido")
+)
+
+var tabelaCRC = crc32.MakeTable(crc32.Castagnoli)
+
+// codificarRegistro monta um registro do log:
+//
+//	tamanho uint32 | crc uint32 | op byte | tamanho da chave uvarint | chave | valor
+//
+// O CRC cobre o corpo (de op até o fim), o que permite reconhecer um
+// registro escrito pela metade.
+func codificarRegistro(op byte, chave, valor []byte) []byte {
+	corpo := make([]byte, 0, 1+binary.MaxVarintLen64+len(chave)+len(valor))
+	corpo = append(corpo, op)
+	corpo = binary.AppendUvarint(corpo, uint64(len(chave)))
+	corpo = append(corpo, chave...)
+	corpo = append(corpo, valor...)
+
+	registro := make([]byte, tamanhoCabecalho, tamanhoCabecalho+len(corpo))
+	binary.LittleEndian.PutUint32(registro[0:4], uint32(len(corpo)))
+	binary.LittleEndian.PutUint32(registro[4:8], crc32.Checksum(corpo, tabelaCRC))
+	return append(registro, corpo...)
+}
+
+// lerRegistro lê o próximo registro. Devolve io.EOF no fim limpo do
+// arquivo e errRegistroInvalido se o que há ali não é um registro íntegro.
+func lerRegistro(r *bufio.Reader) (op byte, chave, valor []byte, tamanho int64, err error) {
+	var cabecalho [tamanhoCabecalho]byte
+	if _, err := io.ReadFull(r, cabecalho[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, nil, nil, 0, io.EOF
+		}
+		return 0, nil, nil, 0, errRegistroInvalido
+	}
+	n := binary.LittleEndian.Uint32(cabecalho[0:4])
+	if n == 0 || n > tamanhoMaximoCorpo {
+		return 0, nil, nil, 0, errRegistroInvalido
+	}
+	corpo := make([]byte, n)
+	if _, err := io.ReadFull(r, corpo); err != nil {
+		return 0, nil, nil, 0, errRegistroInvalido
+	}
+	if crc32.Checksum(corpo, tabelaCRC) != binary.LittleEndian.Uint32(cabecalho[4:8]) {
+		return 0, nil, nil, 0, errRegistroInvalido
+	}
+	op, chave, valor, err = lerCorpo(corpo)
+	return op, chave, valor, int64(tamanhoCabecalho) + int64(n), err
+}
+
+// lerCorpo separa op, chave e valor de um corpo cujo CRC já foi conferido.
+func lerCorpo(corpo []byte) (op byte, chave, valor []byte, err error) {
+	op = corpo[0]
+	tamanhoChave, lidos := binary.Uvarint(corpo[1:])
+	if lidos <= 0 || uint64(len(corpo)-1-lidos) < tamanhoChave || (op != opSet && op != opDelete) {
+		return 0, nil, nil, errRegistroInvalido
+	}
+	inicio := 1 + lidos
+	return op, corpo[inicio : inicio+int(tamanhoChave)], corpo[inicio+int(tamanhoChave):], nil
+}
+
+var errRegistroInvalido = errors.New("registro inválido")
+
+// aplicar decodifica um registro e o aplica em s.dados.
+func (s *Store[K, V]) aplicar(op byte, k, v []byte) error {
+	var chave K
+	if err := s.opcoes.Chaves.Decodificar(k, &chave); err != nil {
+		return fmt.Errorf("kv: chave: %w", err)
+	}
+	if op == opDelete {
+		delete(s.dados, chave)
+		return nil
+	}
+	var valor V
+	if err := s.opcoes.Valores.Decodificar(v, &valor); err != nil {
+		return fmt.Errorf("kv: valor: %w", err)
+	}
+	s.dados[chave] = valor
+	return nil
+}
+
+// carregarSnapshot lê o snapshot, se houver. O snapshot é sempre escrito
+// inteiro e renomeado, então qualquer registro inválido nele é corrupção de
+// verdade e vira erro.
+func (s *Store[K, V]) carregarSnapshot() error {
+	f, err := os.Open(filepath.Join(s.dir, arquivoSnapshot))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		op, k, v, _, err := lerRegistro(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return ErrCorrompido
+		}
+		if err := s.aplicar(op, k, v); err != nil {
+			return err
+		}
+	}
+}
+
+// recuperarWAL reaplica o WAL e o deixa aberto para novas escritas. Na
+// primeira falha, se não houver nenhum registro íntegro depois dela, o resto
+// do arquivo é tratado como uma escrita interrompida: é descartado e o
+// arquivo é truncado ali, para que as próximas escritas não fiquem depois de
+// lixo. Se houver, o WAL está corrompido no meio e truncar jogaria fora
+// escritas confirmadas.
+func (s *Store[K, V]) recuperarWAL() error {
+	f, err := os.OpenFile(filepath.Join(s.dir, arquivoWAL), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	var valido int64
+	r := bufio.NewReader(f)
+	for {
+		op, k, v, tamanho, err := lerRegistro(r)
+		if err != nil {
+			break
+		}
+		if err := s.aplicar(op, k, v); err != nil {
+			f.Close()
+			return err
+		}
+		valido += tamanho
+		s.recuperacao.Registros++
+	}
+
+	if valido < info.Size() {
+		depois, err := haRegistroDepois(f, valido+1, info.Size())
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if depois {
+			f.Close()
+			return fmt.Errorf("%w: registro inválido na posição %d", ErrWALCorrompido, valido)
+		}
+		s.recuperacao.Descartado = info.Size() - valido
+		if err := f.Truncate(valido); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if _, err := f.Seek(valido, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	s.wal, s.tamanhoWAL = f, valido
+	return nil
+}
+
+// haRegistroDepois procura, de inicio até o fim do arquivo, um registro
+// íntegro. Uma escrita interrompida deixa no máximo um registro pela metade
+// no final; achar um inteiro depois do ponto da falha quer dizer que ela não
+// estava no fim. Só roda quando a recuperação já encontrou um problema,
+// então ler o resto do arquivo de uma vez é aceitável.
+func haRegistroDepois(f *os.File, inicio, tamanho int64) (bool, error) {
+	resto, err := io.ReadAll(io.NewSectionReader(f, inicio, tamanho-inicio))
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i+tamanhoCabecalho < len(resto); i++ {
+		n := int(binary.LittleEndian.Uint32(resto[i : i+4]))
+		if n == 0 || n > len(resto)-i-tamanhoCabecalho {
+			continue
+		}
+		corpo := resto[i+tamanhoCabecalho : i+tamanhoCabecalho+n]
+		if crc32.Checksum(corpo, tabelaCRC) != binary.LittleEndian.Uint32(resto[i+4:i+8]) {
+			continue
+		}
+		if _, _, _, err := lerCorpo(corpo); err == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -1,8 +1,27 @@
 package main
 
-import "fmt" // Import the fmt package for formatted I/O (like printing to the console)
+import (
+	"flag"
+	"fmt" // Import the fmt package for formatted I/O (like printing to the console)
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"02-fundacao/02-fundacao/07-maps/kv"
+	"02-fundacao/02-fundacao/07-maps/syncmap"
+)
 
 func main() {
+	tabelasDir := flag.String("tabelas", "", "directory with <year>.json payroll tables (default: the embedded ones)")
+	competencia := flag.String("competencia", "2025-06", "payroll month (YYYY-MM)")
+	formato := flag.String("relatorio", "texto", "salary report format: texto, markdown or csv")
+	ordem := flag.String("ordem", "nome", "salary report order: nome, salario or cargo")
+	dados := flag.String("dados", "", "directory where the salary map is persisted between runs")
+	flag.Parse()
+
 	// --- Declaring and Initializing Maps ---
 
 	// A map is a powerful data structure in Go that stores key-value pairs.
@@ -74,4 +93,170 @@ func main() {
 	for _, currentSalary := range salary { // Again, using 'currentSalary' for clarity
 		fmt.Printf("O salário é R$%d\n", currentSalary)
 	}
-}
\ No newline at end of file
+
+	// --- Using Maps to Run a Payroll ---
+
+	// The salary map only holds gross amounts in reais. A second map with the
+	// same keys holds the number of dependents; a missing key simply reads as
+	// zero, which is exactly the default we want.
+	dependentes := map[string]int{"Isis": 2, "Lucios": 1}
+
+	// The bracket tables themselves are a map too: year -> table.
+	tabelas, err := TabelasPadrao()
+	if *tabelasDir != "" {
+		tabelas, err = CarregarTabelas(os.DirFS(*tabelasDir))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tabelas:", err)
+		os.Exit(1)
+	}
+	mes, err := time.Parse("2006-01", *competencia)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "competência:", err)
+		os.Exit(1)
+	}
+
+	// Map iteration order is random, so sort the keys for a stable payroll.
+	fmt.Println("\n--- Payroll ---")
+	nomes := make([]string, 0, len(salary))
+	for name := range salary {
+		nomes = append(nomes, name)
+	}
+	slices.Sort(nomes)
+	for _, name := range nomes {
+		holerite, err := tabelas.CalcularFolha(Funcionario{
+			Nome:        name,
+			Salario:     salary[name] * 100,
+			Dependentes: dependentes[name],
+		}, mes)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, name+":", err)
+			continue
+		}
+		holerite.Imprimir(os.Stdout)
+	}
+
+	// --- Keeping History Instead of Overwriting ---
+
+	// salary["Lucios"] = 6000 overwrote whatever was there before. The
+	// HistoricoSalarial keeps a sorted list of effective-dated values per key
+	// instead, so past dates still answer with the salary of the time.
+	historico := NovoHistoricoSalarial()
+	contratacao := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, s := range map[string]int{"Maria": 1600, "Isis": 3000, "João": 1500} {
+		historico.Definir(name, contratacao, s*100, "admissão")
+	}
+	historico.Desligar("João", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	historico.Definir("Lucios", time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), 6000*100, "admissão")
+	historico.Reajustar(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), Reajuste{Percentual: 550, Motivo: "dissídio 5,5%"})
+	historico.Reajustar(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), Reajuste{Valor: 300 * 100, Motivo: "promoção"}, "Maria")
+
+	marco := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	if s, err := historico.SalarioEm("Maria", marco); err == nil {
+		fmt.Printf("\nMaria's salary on %s: R$ %s\n", marco.Format(time.DateOnly), formatarReais(s))
+	}
+	fmt.Println()
+	julho := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	ImprimirComparacao(os.Stdout, contratacao, julho, historico.Comparar(contratacao, julho))
+
+	// --- Reproducible Reports ---
+
+	// Ranging over a map prints rows in a different order on every run. A
+	// report collects the entries into a slice and sorts it, with pt-BR
+	// collation for names, so the same data always renders the same way.
+	equipe := []LinhaSalario{
+		{Nome: "Érica", Cargo: "Gerente", Salario: 950000},
+		{Nome: "Eduardo", Cargo: "Analista", Salario: 480000},
+		{Nome: "Fábio", Cargo: "Analista", Salario: 480000},
+		{Nome: "ana", Cargo: "Estagiária", Salario: 150000},
+		{Nome: "Álvaro", Cargo: "Desenvolvedor", Salario: 720000},
+	}
+	for _, l := range LinhasDoMapa(historico.Em(julho)) {
+		l.Cargo = "Desenvolvedor"
+		equipe = append(equipe, l)
+	}
+
+	ordens := map[string]Ordenacao{
+		"nome":    PorNome,
+		"salario": PorSalario.Decrescente(),
+		"cargo":   PorTexto(func(l LinhaSalario) string { return l.Cargo }).Entao(PorSalario.Decrescente()),
+	}
+	formatos := map[string]func(Relatorio, io.Writer) error{
+		"texto":    Relatorio.Texto,
+		"markdown": Relatorio.Markdown,
+		"csv":      Relatorio.CSV,
+	}
+	ordenacao, okOrdem := ordens[*ordem]
+	escrever, okFormato := formatos[*formato]
+	if !okOrdem || !okFormato {
+		fmt.Fprintln(os.Stderr, "unknown -ordem or -relatorio")
+		os.Exit(2)
+	}
+	fmt.Println()
+	escrever(NovoRelatorio("Salários em "+julho.Format("01/2006"), equipe, ordenacao), os.Stdout)
+
+	// --- Sharing a Map Between Goroutines ---
+
+	// A plain map must not be written by one goroutine while others use it.
+	// SyncMap guards it with a mutex, expires entries after a TTL, keeps at
+	// most MaxEntradas (dropping the least recently used) and, in
+	// GetOrCompute, runs the loader once per key even when many goroutines
+	// ask for the same payslip at the same time.
+	var calculos atomic.Int32
+	holerites := syncmap.Novo(syncmap.Opcoes[string, Holerite]{
+		TTL:         time.Hour,
+		MaxEntradas: 2,
+		AoRemover: func(name string, _ Holerite, motivo syncmap.Motivo) {
+			fmt.Printf("payslip cache: %s left (%s)\n", name, motivo)
+		},
+	})
+	carregar := func(name string) func() (Holerite, error) {
+		return func() (Holerite, error) {
+			calculos.Add(1)
+			return tabelas.CalcularFolha(Funcionario{Nome: name, Salario: salary[name] * 100, Dependentes: dependentes[name]}, mes)
+		}
+	}
+	fmt.Println()
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			holerites.GetOrCompute("Maria", carregar("Maria"))
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("10 concurrent lookups for Maria, %d calculation(s)\n", calculos.Load())
+	// With room for only two payslips, each new name pushes out the one
+	// used longest ago.
+	for _, name := range nomes {
+		holerites.GetOrCompute(name, carregar(name))
+	}
+	fmt.Printf("%d calculations in total, %d payslips cached\n", calculos.Load(), holerites.Len())
+
+	// --- Persisting a Map ---
+
+	// Everything above lives only in memory and is gone when the program
+	// exits. kv.Store has the same get/set/delete shape as a map, but every
+	// change goes to a write-ahead log on disk first, so the next run sees it.
+	if *dados != "" {
+		store, err := kv.Abrir[string, int](*dados, kv.Opcoes{})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "kv:", err)
+			os.Exit(1)
+		}
+		defer store.Fechar()
+
+		fmt.Printf("\n--- Salaries persisted by the previous run (%d) ---\n", store.Len())
+		for name, s := range store.Todos() { // in key order, unlike a map
+			fmt.Printf("%s: R$%d\n", name, s)
+		}
+		for name, s := range salary {
+			if err := store.Set(name, s); err != nil {
+				fmt.Fprintln(os.Stderr, "kv:", err)
+				os.Exit(1)
+			}
+		}
+		store.Delete("João") // mirrors delete(salary, "João") above
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// LinhaSalario é uma linha do relatório.
+type LinhaSalario struct {
+	Nome    string
+	Cargo   string
+	Salario int
+}
+
+// LinhasDoMapa converte um mapa nome -> salário em centavos, como o
+// devolvido por HistoricoSalarial.Em, em linhas de relatório.
+func LinhasDoMapa(salarios map[string]int) []LinhaSalario {
+	linhas := make([]LinhaSalario, 0, len(salarios))
+	for nome, salario := range salarios {
+		linhas = append(linhas, LinhaSalario{Nome: nome, Salario: salario})
+	}
+	return linhas
+}
+
+// Ordenacao compara duas linhas, no formato de cmp.Compare.
+type Ordenacao func(a, b LinhaSalario) int
+
+var (
+	PorNome    Ordenacao = func(a, b LinhaSalario) int { return CompararPtBR(a.Nome, b.Nome) }
+	PorSalario Ordenacao = func(a, b LinhaSalario) int { return cmp.Compare(a.Salario, b.Salario) }
+)
+
+// PorTexto ordena por uma chave de texto qualquer, com a mesma colação de PorNome.
+func PorTexto(chave func(LinhaSalario) string) Ordenacao {
+	return func(a, b LinhaSalario) int { return CompararPtBR(chave(a), chave(b)) }
+}
+
+// PorNumero ordena por uma chave numérica qualquer.
+func PorNumero(chave func(LinhaSalario) int) Ordenacao {
+	return func(a, b LinhaSalario) int { return cmp.Compare(chave(a), chave(b)) }
+}
+
+// Decrescente inverte a ordenação.
+func (o Ordenacao) Decrescente() Ordenacao {
+	return func(a, b LinhaSalario) int { return o(b, a) }
+}
+
+// Entao desempata com as próximas ordenações, na ordem dada.
+func (o Ordenacao) Entao(proximas ...Ordenacao) Ordenacao {
+	return func(a, b LinhaSalario) int {
+		if c := o(a, b); c != 0 {
+			return c
+		}
+		for _, p := range proximas {
+			if c := p(a, b); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+}
+
+// Ordenar devolve uma cópia das linhas ordenada. Depois da ordenação pedida,
+// desempata por nome, cargo e salário e por fim pelos bytes do nome, então
+// o resultado não depende da ordem de entrada (que, vinda de um mapa, muda
+// a cada execução).
+func Ordenar(linhas []LinhaSalario, ordem Ordenacao) []LinhaSalario {
+	ordenadas := slices.Clone(linhas)
+	if ordem == nil {
+		ordem = PorNome
+	}
+	completa := ordem.Entao(PorNome, PorTexto(func(l LinhaSalario) string { return l.Cargo }), PorSalario,
+		func(a, b LinhaSalario) int { return strings.Compare(a.Nome, b.Nome) })
+	slices.SortFunc(ordenadas, completa)
+	return ordenadas
+}
+
+// CompararPtBR compara textos como um dicionário em português: primeiro
+// ignorando acentos e caixa ("Érica" fica entre "Eduardo" e "Fábio"); se
+// empatar, a forma sem acento vem antes ("Joao" < "João"); depois a
+// minúscula vem antes ("ana" < "Ana"). Letras decompostas (NFD, como "e"
+// seguido de U+0301) comparam igual às acentuadas.
+func CompararPtBR(a, b string) int {
+	if c := compararRunas(a, b, func(r rune) rune { return semAcento(unicode.ToLower(r)) }); c != 0 {
+		return c
+	}
+	if c := compararRunas(a, b, unicode.ToLower); c != 0 {
+		return c
+	}
+	return compararRunas(a, b, func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return 1
+		}
+		return 0
+	})
+}
+
+func compararRunas(a, b string, chave func(rune) rune) int {
+	ra, rb := runas(a), runas(b)
+	for i := range min(len(ra), len(rb)) {
+		ca, cb := chave(ra[i]), chave(rb[i])
+		if ca != cb {
+			// no nível de acento, a letra sem acento vem antes da acentuada
+			if semAcento(ca) == semAcento(cb) {
+				if ca == semAcento(ca) {
+					return -1
+				}
+				if cb == semAcento(cb) {
+					return 1
+				}
+			}
+			return cmp.Compare(ca, cb)
+		}
+	}
+	return cmp.Compare(len(ra), len(rb))
+}
+
+var acentos = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'ç': 'c', 'ñ': 'n',
+}
+
+func semAcento(r rune) rune {
+	if base, ok := acentos[r]; ok {
+		return base
+	}
+	return r
+}
+
+// combinantes diz que letras acentuadas cada acento combinante forma.
+var combinantes = map[rune]string{
+	'\u0300': "àèìòù",
+	'\u0301': "áéíóú",
+	'\u0302': "âêîôû",
+	'\u0303': "ãõñ",
+	'\u0308': "äëïöü",
+	'\u0327': "ç",
+}
+
+// compostas leva letra sem acento + acento combinante para a letra acentuada.
+var compostas = map[[2]rune]rune{}
+
+func init() {
+	for acento, letras := range combinantes {
+		for _, letra := range letras {
+			compostas[[2]rune{semAcento(letra), acento}] = letra
+		}
+	}
+}
+
+// runas devolve as runas do texto com as letras decompostas recompostas
+// ("e" + U+0301 vira "é"), sem depender de golang.org/x/text. Os demais
+// acentos combinantes (U+0300 a U+036F) são descartados.
+func runas(texto string) []rune {
+	var rs []rune
+	for _, r := range texto {
+		if r < '\u0300' || r > '\u036f' {
+			rs = append(rs, r)
+			continue
+		}
+		if len(rs) == 0 {
+			continue
+		}
+		base := rs[len(rs)-1]
+		if composta, ok := compostas[[2]rune{unicode.ToLower(base), r}]; ok {
+			if unicode.IsUpper(base) {
+				composta = unicode.ToUpper(composta)
+			}
+			rs[len(rs)-1] = composta
+		}
+	}
+	return rs
+}
+
+// Relatorio é uma tabela de salários com total e média, pronta para ser
+// escrita em texto, Markdown ou CSV. As linhas são ordenadas ao criar o
+// relatório, então as três saídas saem sempre na mesma ordem.
+type Relatorio struct {
+	Titulo string
+	Linhas []LinhaSalario
+}
+
+func NovoRelatorio(titulo string, linhas []LinhaSalario, ordem Ordenacao) Relatorio {
+	return Relatorio{Titulo: titulo, Linhas: Ordenar(linhas, ordem)}
+}
+
+func (r Relatorio) Total() int {
+	total := 0
+	for _, l := range r.Linhas {
+		total += l.Salario
+	}
+	return total
+}
+
+// Media arredonda meio centavo para cima; zero se não houver linhas.
+func (r Relatorio) Media() int {
+	if len(r.Linhas) == 0 {
+		return 0
+	}
+	return (2*r.Total() + len(r.Linhas)) / (2 * len(r.Linhas))
+}
+
+func (r Relatorio) temCargo() bool {
+	return slices.ContainsFunc(r.Linhas, func(l LinhaSalario) bool { return l.Cargo != "" })
+}
+
+// celulas monta cabeçalho, linhas e rodapé como texto; as três saídas só
+// mudam a forma de escrever.
+func (r Relatorio) celulas(formatar func(int) string) (cabecalho []string, corpo [][]string, rodape [][]string) {
+	cargo := r.temCargo()
+	cabecalho = []string{"Nome"}
+	if cargo {
+		cabecalho = append(cabecalho, "Cargo")
+	}
+	cabecalho = append(cabecalho, "Salário")
+
+	linha := func(nome, cargoLinha, valor string) []string {
+		if cargo {
+			return []string{nome, cargoLinha, valor}
+		}
+		return []string{nome, valor}
+	}
+	for _, l := range r.Linhas {
+		corpo = append(corpo, linha(l.Nome, l.Cargo, formatar(l.Salario)))
+	}
+	rodape = [][]string{
+		linha("Total", "", formatar(r.Total())),
+		linha("Média", "", formatar(r.Media())),
+	}
+	return cabecalho, corpo, rodape
+}
+
+// Texto escreve uma tabela alinhada: texto à esquerda, valores à direita.
+func (r Relatorio) Texto(w io.Writer) error {
+	cabecalho, corpo, rodape := r.celulas(formatarReais)
+	larguras := make([]int, len(cabecalho))
+	for _, linha := range slices.Concat([][]string{cabecalho}, corpo, rodape) {
+		for i, c := range linha {
+			larguras[i] = max(larguras[i], len(runas(c)))
+		}
+	}
+
+	var b strings.Builder
+	escrever := func(linha []string) {
+		for i, c := range linha {
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			espaco := strings.Repeat(" ", larguras[i]-len(runas(c)))
+			if i == len(linha)-1 {
+				b.WriteString(espaco + c)
+			} else {
+				b.WriteString(c + espaco)
+			}
+		}
+		b.WriteString("\n")
+	}
+	separador := func() {
+		total := len(larguras)*2 - 2
+		for _, l := range larguras {
+			total += l
+		}
+		b.WriteString(strings.Repeat("-", total) + "\n")
+	}
+
+	if r.Titulo != "" {
+		b.WriteString(r.Titulo + "\n")
+	}
+	escrever(cabecalho)
+	separador()
+	for _, linha := range corpo {
+		escrever(linha)
+	}
+	separador()
+	for _, linha := range rodape {
+		escrever(linha)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Markdown escreve uma tabela no formato do GitHub, com a coluna de valores
+// alinhada à direita e total e média em negrito.
+func (r Relatorio) Markdown(w io.Writer) error {
+	cabecalho, corpo, rodape := r.celulas(func(c int) string { return "R$ " + formatarReais(c) })
+
+	var b strings.Builder
+	if r.Titulo != "" {
+		fmt.Fprintf(&b, "### %s\n\n", r.Titulo)
+	}
+	alinhamento := make([]string, len(cabecalho))
+	for i := range alinhamento {
+		alinhamento[i] = "---"
+	}
+	alinhamento[len(alinhamento)-1] = "---:"
+
+	escrever := func(linha []string) {
+		celulas := make([]string, len(linha))
+		for i, c := range linha {
+			celulas[i] = strings.ReplaceAll(c, "|", `\|`)
+		}
+		b.WriteString("| " + strings.Join(celulas, " | ") + " |\n")
+	}
+	escrever(cabecalho)
+	b.WriteString("| " + strings.Join(alinhamento, " | ") + " |\n")
+	for _, linha := range corpo {
+		escrever(linha)
+	}
+	for _, linha := range rodape {
+		negrito := make([]string, len(linha))
+		for i, c := range linha {
+			if c != "" {
+				c = "**" + c + "**"
+			}
+			negrito[i] = c
+		}
+		escrever(negrito)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// CSV escreve os valores com ponto decimal e sem separador de milhar, para
+// que planilhas e scripts leiam o número sem depender de localidade. Total
+// e média vão nas duas últimas linhas.
+func (r Relatorio) CSV(w io.Writer) error {
+	cabecalho, corpo, rodape := r.celulas(formatarDecimal)
+	return csv.NewWriter(w).WriteAll(slices.Concat([][]string{cabecalho}, corpo, rodape))
+}
+
+// formatarDecimal escreve centavos como "1234.56".
+func formatarDecimal(centavos int) string {
+	sinal := ""
+	if centavos < 0 {
+		sinal = "-"
+		centavos = -centavos
+	}
+	return fmt.Sprintf("%s%d.%02d", sinal, centavos/100, centavos%100)
+}
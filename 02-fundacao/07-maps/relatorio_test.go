@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// go test -run Relatorio -atualizar regrava os arquivos de testdata depois de
+// uma mudança proposital no formato.
+var atualizar = flag.Bool("atualizar", false, "regrava os arquivos golden de testdata")
+
+func TestCompararPtBR(t *testing.T) {
+	ordem := []string{
+		"Álvaro", "ana", "Ana", "Eduardo", "Érica", "Fábio",
+		"Joao", "joão", "João", "Zé",
+	}
+	for i := range ordem {
+		for j := range ordem {
+			quero := 0
+			switch {
+			case i < j:
+				quero = -1
+			case i > j:
+				quero = 1
+			}
+			if got := CompararPtBR(ordem[i], ordem[j]); got != quero {
+				t.Errorf("CompararPtBR(%q, %q) = %d, quero %d", ordem[i], ordem[j], got, quero)
+			}
+		}
+	}
+}
+
+func TestCompararPtBRDecomposto(t *testing.T) {
+	for _, c := range []struct {
+		a, b  string
+		quero int
+	}{
+		// a mesma letra, composta (NFC) e decomposta (NFD)
+		{"José", "Jose\u0301", 0},
+		{"Érica", "E\u0301rica", 0},
+		{"Conceição", "Conceic\u0327a\u0303o", 0},
+		// decomposta, a letra continua no lugar do dicionário
+		{"E\u0301rica", "Eduardo", 1},
+		{"E\u0301rica", "Fábio", -1},
+		{"Joao", "Joa\u0303o", -1},
+		// acentos que não formam letra do português são ignorados
+		{"Ana", "A\u0336na", 0},
+		{"\u0301Ana", "Ana", 0},
+	} {
+		if got := CompararPtBR(c.a, c.b); got != c.quero {
+			t.Errorf("CompararPtBR(%q, %q) = %d, quero %d", c.a, c.b, got, c.quero)
+		}
+	}
+}
+
+func TestOrdenarNaoDependeDaEntrada(t *testing.T) {
+	linhas := []LinhaSalario{
+		{"Bia", "Dev", 500_000},
+		{"Ana", "Dev", 500_000},
+		{"Ana", "Analista", 500_000},
+		{"Caio", "Dev", 700_000},
+		{"ana", "Dev", 500_000},
+	}
+	ordem := PorSalario.Decrescente()
+	primeira := Ordenar(linhas, ordem)
+	invertidas := slices.Clone(linhas)
+	slices.Reverse(invertidas)
+	if segunda := Ordenar(invertidas, ordem); !slices.Equal(primeira, segunda) {
+		t.Errorf("a ordem de entrada mudou o resultado:\n%v\n%v", primeira, segunda)
+	}
+	quero := []LinhaSalario{
+		{"Caio", "Dev", 700_000},
+		{"ana", "Dev", 500_000},
+		{"Ana", "Analista", 500_000},
+		{"Ana", "Dev", 500_000},
+		{"Bia", "Dev", 500_000},
+	}
+	if !slices.Equal(primeira, quero) {
+		t.Errorf("Ordenar = %v, quero %v", primeira, quero)
+	}
+	if linhas[0].Nome != "Bia" {
+		t.Error("Ordenar alterou a fatia de entrada")
+	}
+}
+
+func TestRelatorioMedia(t *testing.T) {
+	for _, c := range []struct {
+		salarios []int
+		quero    int
+	}{
+		{nil, 0},
+		{[]int{100, 201}, 151}, // 150,5 sobe
+		{[]int{100, 200, 200}, 167},
+		{[]int{100, 100, 101}, 100},
+	} {
+		var linhas []LinhaSalario
+		for _, s := range c.salarios {
+			linhas = append(linhas, LinhaSalario{Nome: "x", Salario: s})
+		}
+		if got := NovoRelatorio("", linhas, nil).Media(); got != c.quero {
+			t.Errorf("Media(%v) = %d, quero %d", c.salarios, got, c.quero)
+		}
+	}
+}
+
+// relatorioDeExemplo tem nomes acentuados (um deles decomposto), cargo com
+// barra vertical para o Markdown e nome com vírgula e aspas para o CSV.
+func relatorioDeExemplo() Relatorio {
+	return NovoRelatorio("Salários em 07/2025", []LinhaSalario{
+		{"Fábio", "Dev | Backend", 1_234_567},
+		{"E\u0301rica", "Analista", 650_050},
+		{"Souza, \"Ana\"", "Estagiária", 180_000},
+		{"Eduardo", "", 99},
+	}, PorNome)
+}
+
+func TestRelatorioGolden(t *testing.T) {
+	for _, caso := range []struct {
+		nome     string
+		arquivo  string
+		escrever func(Relatorio, io.Writer) error
+	}{
+		{"texto", "relatorio.txt", Relatorio.Texto},
+		{"markdown", "relatorio.md", Relatorio.Markdown},
+		{"csv", "relatorio.csv", Relatorio.CSV},
+	} {
+		t.Run(caso.nome, func(t *testing.T) {
+			var saida bytes.Buffer
+			if err := caso.escrever(relatorioDeExemplo(), &saida); err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", caso.arquivo)
+			if *atualizar {
+				if err := os.MkdirAll("testdata", 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, saida.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			esperado, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (rode com -atualizar para criar)", err)
+			}
+			if !bytes.Equal(saida.Bytes(), esperado) {
+				t.Errorf("saída difere de %s:\n--- obtido\n%s\n--- esperado\n%s", golden, saida.Bytes(), esperado)
+			}
+		})
+	}
+}
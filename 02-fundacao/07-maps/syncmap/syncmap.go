@@ -0,0 +1,308 @@
+// Package syncmap tem um mapa genérico seguro para uso concorrente, com
+// validade por entrada (TTL), limite de tamanho com descarte da entrada
+// usada há mais tempo (LRU) e carregamento único por chave.
+package syncmap
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+// ErrCarregamentoInterrompido é o que recebem as goroutines que esperavam
+// um GetOrCompute cujo carregar entrou em pânico.
+var ErrCarregamentoInterrompido = erros.Definir("syncmap.carregamento_interrompido", erros.Interno).Sentinela("syncmap: carregamento interrompido por um pânico")
+
+// Motivo diz por que uma entrada saiu do mapa.
+type Motivo int
+
+const (
+	Removido    Motivo = iota // Delete ou Limpar
+	Expirou                   // passou do TTL
+	Capacidade                // descartada pelo LRU para caber uma nova
+	Substituido               // Set numa chave que já existia
+)
+
+func (m Motivo) String() string {
+	switch m {
+	case Removido:
+		return "removido"
+	case Expirou:
+		return "expirou"
+	case Capacidade:
+		return "capacidade"
+	case Substituido:
+		return "substituído"
+	}
+	return "desconhecido"
+}
+
+// Opcoes configura um SyncMap. O valor zero é um mapa sem TTL e sem limite.
+type Opcoes[K comparable, V any] struct {
+	// TTL padrão das entradas; zero é "não expira". SetComTTL sobrepõe.
+	TTL time.Duration
+	// MaxEntradas limita o tamanho; ao passar, sai a usada há mais tempo.
+	// Zero é sem limite.
+	MaxEntradas int
+	// AoRemover é chamado sempre que uma entrada sai do mapa, fora do lock,
+	// então pode usar o próprio mapa.
+	AoRemover func(chave K, valor V, motivo Motivo)
+	// Agora substitui o relógio, para testes; o padrão é time.Now.
+	Agora func() time.Time
+}
+
+type entrada[K comparable, V any] struct {
+	chave  K
+	valor  V
+	expira time.Time // zero: não expira
+}
+
+type remocao[K comparable, V any] struct {
+	chave  K
+	valor  V
+	motivo Motivo
+}
+
+// chamada é um carregamento em andamento de GetOrCompute. invalidada marca
+// que a chave foi gravada ou removida durante o carregamento.
+type chamada[V any] struct {
+	feito      chan struct{}
+	valor      V
+	err        error
+	invalidada bool
+}
+
+// SyncMap é um mapa K -> V seguro para várias goroutines. As entradas
+// expiradas saem quando alguém tenta lê-las, quando o mapa precisa de
+// espaço ou em RemoverExpirados.
+type SyncMap[K comparable, V any] struct {
+	opcoes Opcoes[K, V]
+
+	mu           sync.Mutex
+	entradas     map[K]*list.Element
+	lru          *list.List // frente: usada mais recentemente
+	carregamento map[K]*chamada[V]
+}
+
+func Novo[K comparable, V any](opcoes Opcoes[K, V]) *SyncMap[K, V] {
+	if opcoes.Agora == nil {
+		opcoes.Agora = time.Now
+	}
+	return &SyncMap[K, V]{
+		opcoes:       opcoes,
+		entradas:     map[K]*list.Element{},
+		lru:          list.New(),
+		carregamento: map[K]*chamada[V]{},
+	}
+}
+
+// Get devolve o valor e marca a entrada como usada agora.
+func (m *SyncMap[K, V]) Get(chave K) (V, bool) {
+	m.mu.Lock()
+	valor, ok, removidas := m.buscar(chave)
+	m.mu.Unlock()
+	m.avisar(removidas)
+	return valor, ok
+}
+
+// Set grava com o TTL padrão.
+func (m *SyncMap[K, V]) Set(chave K, valor V) {
+	m.SetComTTL(chave, valor, m.opcoes.TTL)
+}
+
+// SetComTTL grava com um TTL próprio; zero é "não expira".
+func (m *SyncMap[K, V]) SetComTTL(chave K, valor V, ttl time.Duration) {
+	m.mu.Lock()
+	m.invalidarCarregamento(chave)
+	removidas := m.gravar(chave, valor, ttl)
+	m.mu.Unlock()
+	m.avisar(removidas)
+}
+
+func (m *SyncMap[K, V]) Delete(chave K) {
+	m.mu.Lock()
+	m.invalidarCarregamento(chave)
+	var removidas []remocao[K, V]
+	if elemento, ok := m.entradas[chave]; ok {
+		removidas = append(removidas, m.remover(elemento, Removido))
+	}
+	m.mu.Unlock()
+	m.avisar(removidas)
+}
+
+// Len conta as entradas guardadas, inclusive as expiradas que ainda não
+// foram retiradas.
+func (m *SyncMap[K, V]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entradas)
+}
+
+// RemoverExpirados retira todas as entradas vencidas e devolve quantas eram.
+func (m *SyncMap[K, V]) RemoverExpirados() int {
+	m.mu.Lock()
+	agora := m.opcoes.Agora()
+	var removidas []remocao[K, V]
+	for elemento := m.lru.Front(); elemento != nil; {
+		proximo := elemento.Next()
+		if vencida(elemento.Value.(*entrada[K, V]), agora) {
+			removidas = append(removidas, m.remover(elemento, Expirou))
+		}
+		elemento = proximo
+	}
+	m.mu.Unlock()
+	m.avisar(removidas)
+	return len(removidas)
+}
+
+// Limpar remove tudo, inclusive o que estiver sendo carregado.
+func (m *SyncMap[K, V]) Limpar() {
+	m.mu.Lock()
+	for chave := range m.carregamento {
+		m.invalidarCarregamento(chave)
+	}
+	var removidas []remocao[K, V]
+	for elemento := m.lru.Front(); elemento != nil; {
+		proximo := elemento.Next()
+		removidas = append(removidas, m.remover(elemento, Removido))
+		elemento = proximo
+	}
+	m.mu.Unlock()
+	m.avisar(removidas)
+}
+
+// GetOrCompute devolve o valor da chave ou, se não houver, chama carregar e
+// grava o resultado com o TTL padrão. Com várias goroutines pedindo a mesma
+// chave ao mesmo tempo, carregar roda uma vez só e todas recebem o mesmo
+// resultado. Se carregar falhar, o erro vai para todas as que esperavam e
+// nada é gravado, então a próxima chamada tenta de novo. Se carregar entrar
+// em pânico, as que esperavam recebem ErrCarregamentoInterrompido.
+//
+// Um Set, Delete ou Limpar feito enquanto carregar roda vence o carregamento:
+// quem chamou e quem esperava recebem o valor carregado, mas ele não é
+// gravado por cima do que foi escrito no meio tempo.
+func (m *SyncMap[K, V]) GetOrCompute(chave K, carregar func() (V, error)) (V, error) {
+	m.mu.Lock()
+	valor, ok, removidas := m.buscar(chave)
+	if ok {
+		m.mu.Unlock()
+		m.avisar(removidas)
+		return valor, nil
+	}
+	if c, andamento := m.carregamento[chave]; andamento {
+		m.mu.Unlock()
+		m.avisar(removidas)
+		<-c.feito
+		return c.valor, c.err
+	}
+	c := &chamada[V]{feito: make(chan struct{})}
+	m.carregamento[chave] = c
+	m.mu.Unlock()
+	m.avisar(removidas)
+
+	// o defer garante que quem espera é liberado, com um erro, mesmo se
+	// carregar entrar em pânico; nesse caso o pânico segue para quem chamou
+	defer func() {
+		p := recover()
+		if p != nil {
+			c.err = fmt.Errorf("%w: %v", ErrCarregamentoInterrompido, p)
+		}
+		m.mu.Lock()
+		delete(m.carregamento, chave)
+		var removidas []remocao[K, V]
+		if c.err == nil && !c.invalidada {
+			removidas = m.gravar(chave, c.valor, m.opcoes.TTL)
+		}
+		m.mu.Unlock()
+		close(c.feito)
+		m.avisar(removidas)
+		if p != nil {
+			panic(p)
+		}
+	}()
+
+	c.valor, c.err = carregar()
+	return c.valor, c.err
+}
+
+// invalidarCarregamento impede que um carregamento em andamento grave o
+// resultado. Deve ser chamado com m.mu travado.
+func (m *SyncMap[K, V]) invalidarCarregamento(chave K) {
+	if c, ok := m.carregamento[chave]; ok {
+		c.invalidada = true
+	}
+}
+
+// buscar deve ser chamado com m.mu travado.
+func (m *SyncMap[K, V]) buscar(chave K) (V, bool, []remocao[K, V]) {
+	var zero V
+	elemento, ok := m.entradas[chave]
+	if !ok {
+		return zero, false, nil
+	}
+	e := elemento.Value.(*entrada[K, V])
+	if vencida(e, m.opcoes.Agora()) {
+		return zero, false, []remocao[K, V]{m.remover(elemento, Expirou)}
+	}
+	m.lru.MoveToFront(elemento)
+	return e.valor, true, nil
+}
+
+// gravar deve ser chamado com m.mu travado. Se o mapa passar do limite,
+// primeiro saem as expiradas e, se ainda não couber, as do fim do LRU.
+func (m *SyncMap[K, V]) gravar(chave K, valor V, ttl time.Duration) []remocao[K, V] {
+	agora := m.opcoes.Agora()
+	var expira time.Time
+	if ttl > 0 {
+		expira = agora.Add(ttl)
+	}
+
+	var removidas []remocao[K, V]
+	if elemento, ok := m.entradas[chave]; ok {
+		e := elemento.Value.(*entrada[K, V])
+		removidas = append(removidas, remocao[K, V]{chave: chave, valor: e.valor, motivo: Substituido})
+		e.valor, e.expira = valor, expira
+		m.lru.MoveToFront(elemento)
+		return removidas
+	}
+
+	m.entradas[chave] = m.lru.PushFront(&entrada[K, V]{chave: chave, valor: valor, expira: expira})
+	if m.opcoes.MaxEntradas <= 0 || m.lru.Len() <= m.opcoes.MaxEntradas {
+		return removidas
+	}
+	for elemento := m.lru.Back(); elemento != nil && m.lru.Len() > m.opcoes.MaxEntradas; {
+		anterior := elemento.Prev()
+		if vencida(elemento.Value.(*entrada[K, V]), agora) {
+			removidas = append(removidas, m.remover(elemento, Expirou))
+		}
+		elemento = anterior
+	}
+	for m.lru.Len() > m.opcoes.MaxEntradas {
+		removidas = append(removidas, m.remover(m.lru.Back(), Capacidade))
+	}
+	return removidas
+}
+
+// remover deve ser chamado com m.mu travado.
+func (m *SyncMap[K, V]) remover(elemento *list.Element, motivo Motivo) remocao[K, V] {
+	e := m.lru.Remove(elemento).(*entrada[K, V])
+	delete(m.entradas, e.chave)
+	return remocao[K, V]{chave: e.chave, valor: e.valor, motivo: motivo}
+}
+
+// avisar chama AoRemover fora do lock.
+func (m *SyncMap[K, V]) avisar(removidas []remocao[K, V]) {
+	if m.opcoes.AoRemover == nil {
+		return
+	}
+	for _, r := range removidas {
+		m.opcoes.AoRemover(r.chave, r.valor, r.motivo)
+	}
+}
+
+func vencida[K comparable, V any](e *entrada[K, V], agora time.Time) bool {
+	return !e.expira.IsZero() && !agora.Before(e.expira)
+}
@@ -0,0 +1,459 @@
+package syncmap
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrComputeComPanico(t *testing.T) {
+	m := Novo(Opcoes[string, int]{})
+	comecou, liberar := make(chan struct{}), make(chan struct{})
+
+	panicou := make(chan any)
+	go func() {
+		defer func() { panicou <- recover() }()
+		m.GetOrCompute("a", func() (int, error) {
+			close(comecou)
+			<-liberar
+			panic("falhou")
+		})
+	}()
+	<-comecou
+
+	esperou := make(chan error)
+	go func() {
+		_, err := m.GetOrCompute("a", func() (int, error) {
+			return 0, errors.New("carregar rodou de novo em vez de esperar")
+		})
+		esperou <- err
+	}()
+	// dá tempo para a segunda chamada entrar na espera
+	time.Sleep(20 * time.Millisecond)
+	close(liberar)
+
+	if p := <-panicou; p != "falhou" {
+		t.Fatalf("pânico = %v, quero o original", p)
+	}
+	if err := <-esperou; !errors.Is(err, ErrCarregamentoInterrompido) {
+		t.Fatalf("quem esperava recebeu %v, quero ErrCarregamentoInterrompido", err)
+	}
+	if _, ok := m.Get("a"); ok {
+		t.Fatal("o pânico gravou um valor")
+	}
+
+	// a próxima chamada tenta de novo
+	v, err := m.GetOrCompute("a", func() (int, error) { return 7, nil })
+	if err != nil || v != 7 {
+		t.Fatalf("GetOrCompute depois do pânico = %d, %v; quero 7, nil", v, err)
+	}
+}
+
+// relogioTeste é um relógio que só anda quando o teste manda.
+type relogioTeste struct {
+	mu    sync.Mutex
+	agora time.Time
+}
+
+func (r *relogioTeste) Agora() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.agora
+}
+
+func (r *relogioTeste) Avancar(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.agora = r.agora.Add(d)
+}
+
+// remocoesAnotadas guarda o que AoRemover recebeu, no formato "chave:motivo".
+type remocoesAnotadas struct {
+	mu    sync.Mutex
+	lista []string
+}
+
+func (r *remocoesAnotadas) anotar(chave string, _ int, motivo Motivo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lista = append(r.lista, chave+":"+motivo.String())
+}
+
+// conferir compara com o esperado e zera a lista.
+func (r *remocoesAnotadas) conferir(t *testing.T, esperadas ...string) {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !slices.Equal(r.lista, esperadas) {
+		t.Errorf("AoRemover recebeu %v, quero %v", r.lista, esperadas)
+	}
+	r.lista = nil
+}
+
+func TestTTLComRelogioInjetado(t *testing.T) {
+	relogio := &relogioTeste{agora: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var remocoes remocoesAnotadas
+	m := Novo(Opcoes[string, int]{TTL: 10 * time.Second, Agora: relogio.Agora, AoRemover: remocoes.anotar})
+
+	m.Set("padrao", 1)
+	m.SetComTTL("curto", 2, time.Second)
+	m.SetComTTL("eterno", 3, 0)
+
+	relogio.Avancar(time.Second)
+	if _, ok := m.Get("curto"); ok {
+		t.Error("curto ainda está no mapa no fim do TTL")
+	}
+	remocoes.conferir(t, "curto:expirou")
+
+	relogio.Avancar(8*time.Second + 999*time.Millisecond)
+	if v, ok := m.Get("padrao"); !ok || v != 1 {
+		t.Errorf("padrao antes do TTL = %d, %v", v, ok)
+	}
+	relogio.Avancar(time.Millisecond)
+	if n := m.RemoverExpirados(); n != 1 {
+		t.Errorf("RemoverExpirados = %d, quero 1", n)
+	}
+	remocoes.conferir(t, "padrao:expirou")
+
+	relogio.Avancar(24 * time.Hour)
+	if v, ok := m.Get("eterno"); !ok || v != 3 {
+		t.Errorf("eterno = %d, %v; TTL zero não expira", v, ok)
+	}
+
+	// regravar renova o prazo
+	m.Set("renovado", 4)
+	relogio.Avancar(9 * time.Second)
+	m.Set("renovado", 5)
+	remocoes.conferir(t, "renovado:substituído")
+	relogio.Avancar(9 * time.Second)
+	if v, ok := m.Get("renovado"); !ok || v != 5 {
+		t.Errorf("renovado = %d, %v; o Set devia ter renovado o TTL", v, ok)
+	}
+}
+
+func TestLRUDescartaAUsadaHaMaisTempo(t *testing.T) {
+	var remocoes remocoesAnotadas
+	m := Novo(Opcoes[string, int]{MaxEntradas: 3, AoRemover: remocoes.anotar})
+	m.Set("a", 1)
+	m.Set("b", 2)
+	m.Set("c", 3)
+	m.Get("a") // a passa a ser a mais recente e b vira a mais antiga
+
+	m.Set("d", 4)
+	remocoes.conferir(t, "b:capacidade")
+	m.Set("c", 30) // regravar também conta como uso
+	remocoes.conferir(t, "c:substituído")
+	m.Set("e", 5)
+	remocoes.conferir(t, "a:capacidade")
+	m.Set("f", 6)
+	remocoes.conferir(t, "d:capacidade")
+
+	for _, chave := range []string{"c", "e", "f"} {
+		if _, ok := m.Get(chave); !ok {
+			t.Errorf("%s saiu do mapa", chave)
+		}
+	}
+	if m.Len() != 3 {
+		t.Errorf("Len = %d, quero 3", m.Len())
+	}
+}
+
+func TestLRUDescartaExpiradasAntes(t *testing.T) {
+	relogio := &relogioTeste{agora: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var remocoes remocoesAnotadas
+	m := Novo(Opcoes[string, int]{MaxEntradas: 2, Agora: relogio.Agora, AoRemover: remocoes.anotar})
+	m.Set("antiga", 1)
+	m.SetComTTL("vence", 2, time.Minute)
+	relogio.Avancar(time.Minute)
+
+	// a mais antiga no LRU é "antiga", mas "vence" já expirou e sai primeiro
+	m.Set("nova", 3)
+	remocoes.conferir(t, "vence:expirou")
+	if _, ok := m.Get("antiga"); !ok {
+		t.Error("o LRU descartou uma entrada válida com uma expirada disponível")
+	}
+}
+
+func TestAoRemoverEmCadaCaminho(t *testing.T) {
+	relogio := &relogioTeste{agora: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var remocoes remocoesAnotadas
+	m := Novo(Opcoes[string, int]{MaxEntradas: 2, Agora: relogio.Agora, AoRemover: remocoes.anotar})
+
+	m.Set("a", 1)
+	m.Delete("a")
+	m.Delete("a") // chave ausente não avisa
+	remocoes.conferir(t, "a:removido")
+
+	m.Set("a", 1)
+	m.Set("a", 2)
+	remocoes.conferir(t, "a:substituído")
+
+	m.Set("b", 1)
+	m.Set("c", 1)
+	remocoes.conferir(t, "a:capacidade")
+
+	m.SetComTTL("b", 1, time.Second)
+	remocoes.conferir(t, "b:substituído")
+	relogio.Avancar(time.Second)
+	v, err := m.GetOrCompute("b", func() (int, error) { return 9, nil })
+	if err != nil || v != 9 {
+		t.Fatalf("GetOrCompute de chave expirada = %d, %v", v, err)
+	}
+	remocoes.conferir(t, "b:expirou")
+
+	m.Limpar()
+	remocoes.mu.Lock()
+	slices.Sort(remocoes.lista)
+	remocoes.mu.Unlock()
+	remocoes.conferir(t, "b:removido", "c:removido")
+}
+
+func TestAoRemoverPodeUsarOMapa(t *testing.T) {
+	var m *SyncMap[string, int]
+	m = Novo(Opcoes[string, int]{AoRemover: func(chave string, valor int, motivo Motivo) {
+		if motivo == Removido {
+			m.Set("removidos", valor)
+		}
+	}})
+	m.Set("a", 7)
+	m.Delete("a")
+	if v, _ := m.Get("removidos"); v != 7 {
+		t.Errorf("removidos = %d, quero 7", v)
+	}
+}
+
+func TestGetOrComputeCarregaUmaVez(t *testing.T) {
+	m := Novo(Opcoes[string, int]{})
+	var cargas atomic.Int32
+	liberar := make(chan struct{})
+
+	const goroutines = 50
+	var grupo sync.WaitGroup
+	resultados := make(chan int, goroutines)
+	for range goroutines {
+		grupo.Add(1)
+		go func() {
+			defer grupo.Done()
+			v, err := m.GetOrCompute("chave", func() (int, error) {
+				cargas.Add(1)
+				<-liberar
+				return 42, nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+			resultados <- v
+		}()
+	}
+	// dá tempo para as goroutines ficarem esperando o carregamento
+	time.Sleep(20 * time.Millisecond)
+	close(liberar)
+	grupo.Wait()
+	close(resultados)
+
+	if n := cargas.Load(); n != 1 {
+		t.Errorf("carregar rodou %d vezes, quero 1", n)
+	}
+	for v := range resultados {
+		if v != 42 {
+			t.Errorf("resultado %d, quero 42", v)
+		}
+	}
+}
+
+func TestGetOrComputeComErroNaoGrava(t *testing.T) {
+	m := Novo(Opcoes[string, int]{})
+	falha := errors.New("fora do ar")
+	if _, err := m.GetOrCompute("a", func() (int, error) { return 1, falha }); !errors.Is(err, falha) {
+		t.Fatalf("erro = %v, quero o de carregar", err)
+	}
+	if _, ok := m.Get("a"); ok {
+		t.Fatal("o erro gravou um valor")
+	}
+	if v, err := m.GetOrCompute("a", func() (int, error) { return 2, nil }); err != nil || v != 2 {
+		t.Errorf("nova tentativa = %d, %v; quero 2", v, err)
+	}
+}
+
+func TestSetDuranteCarregamentoVence(t *testing.T) {
+	for _, escrita := range []string{"Set", "Delete", "Limpar"} {
+		t.Run(escrita, func(t *testing.T) {
+			m := Novo(Opcoes[string, int]{})
+			comecou, liberar := make(chan struct{}), make(chan struct{})
+			carregado := make(chan int)
+			go func() {
+				v, _ := m.GetOrCompute("a", func() (int, error) {
+					close(comecou)
+					<-liberar
+					return 1, nil
+				})
+				carregado <- v
+			}()
+			<-comecou
+
+			switch escrita {
+			case "Set":
+				m.Set("a", 99)
+			case "Delete":
+				m.Delete("a")
+			case "Limpar":
+				m.Limpar()
+			}
+			close(liberar)
+
+			if v := <-carregado; v != 1 {
+				t.Errorf("GetOrCompute devolveu %d, quero o valor carregado 1", v)
+			}
+			v, ok := m.Get("a")
+			if escrita == "Set" && (!ok || v != 99) {
+				t.Errorf("Get = %d, %v; quero 99, o Set feito durante o carregamento", v, ok)
+			}
+			if escrita != "Set" && ok {
+				t.Errorf("Get = %d depois do %s; o carregamento não devia gravar", v, escrita)
+			}
+		})
+	}
+}
+
+// mapaConcorrente é o mínimo que os mapas comparados oferecem.
+type mapaConcorrente interface {
+	Get(string) (int, bool)
+	Set(string, int)
+}
+
+// mapaComMutex é a solução mais simples: um map comum atrás de um RWMutex.
+type mapaComMutex struct {
+	mu    sync.RWMutex
+	dados map[string]int
+}
+
+func (m *mapaComMutex) Get(chave string) (int, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.dados[chave]
+	return v, ok
+}
+
+func (m *mapaComMutex) Set(chave string, valor int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dados[chave] = valor
+}
+
+type mapaSync struct{ dados sync.Map }
+
+func (m *mapaSync) Get(chave string) (int, bool) {
+	v, ok := m.dados.Load(chave)
+	if !ok {
+		return 0, false
+	}
+	return v.(int), true
+}
+
+func (m *mapaSync) Set(chave string, valor int) {
+	m.dados.Store(chave, valor)
+}
+
+const chavesBench = 1024
+
+func nomesBench() []string {
+	nomes := make([]string, chavesBench)
+	for i := range nomes {
+		nomes[i] = fmt.Sprintf("funcionario-%04d", i)
+	}
+	return nomes
+}
+
+// BenchmarkMapasConcorrentes compara leituras e escritas em paralelo no map
+// com RWMutex, no sync.Map e no SyncMap (sem limite e com LRU), em duas
+// misturas de carga.
+func BenchmarkMapasConcorrentes(b *testing.B) {
+	nomes := nomesBench()
+	candidatos := []struct {
+		nome string
+		novo func() mapaConcorrente
+	}{
+		{"RWMutex", func() mapaConcorrente { return &mapaComMutex{dados: map[string]int{}} }},
+		{"sync.Map", func() mapaConcorrente { return &mapaSync{} }},
+		{"SyncMap", func() mapaConcorrente { return Novo(Opcoes[string, int]{}) }},
+		{"SyncMapLRU", func() mapaConcorrente { return Novo(Opcoes[string, int]{MaxEntradas: chavesBench / 2}) }},
+	}
+	cargas := []struct {
+		nome     string
+		escritas int // em cada 100 operações
+	}{
+		{"leitura90", 10},
+		{"leitura50", 50},
+	}
+	for _, carga := range cargas {
+		for _, candidato := range candidatos {
+			b.Run(carga.nome+"/"+candidato.nome, func(b *testing.B) {
+				mapa := candidato.novo()
+				for i, nome := range nomes {
+					mapa.Set(nome, i)
+				}
+				var semente atomic.Int64
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					aleatorio := rand.New(rand.NewSource(semente.Add(1)))
+					for pb.Next() {
+						nome := nomes[aleatorio.Intn(chavesBench)]
+						if aleatorio.Intn(100) < carga.escritas {
+							mapa.Set(nome, 1)
+						} else {
+							mapa.Get(nome)
+						}
+					}
+				})
+			})
+		}
+	}
+}
+
+// BenchmarkGetOrCompute mede a carga única com o LRU cheio: só metade das
+// chaves cabe no mapa, então boa parte das chamadas carrega, e as que pedem
+// a mesma chave ao mesmo tempo dividem o carregamento. cargas/op conta as
+// vezes em que carregar rodou.
+func BenchmarkGetOrCompute(b *testing.B) {
+	nomes := nomesBench()
+	m := Novo(Opcoes[string, int]{MaxEntradas: chavesBench / 2})
+	var semente, cargas atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		aleatorio := rand.New(rand.NewSource(semente.Add(1)))
+		for pb.Next() {
+			m.GetOrCompute(nomes[aleatorio.Intn(chavesBench)], func() (int, error) {
+				return int(cargas.Add(1)), nil
+			})
+		}
+	})
+	b.ReportMetric(float64(cargas.Load())/float64(b.N), "cargas/op")
+}
+
+// BenchmarkTTL mede leituras e escritas com entradas vencendo o tempo todo.
+// O relógio anda 1ns a cada leitura, então com TTL de 512ns uma entrada dura
+// algumas centenas de operações.
+func BenchmarkTTL(b *testing.B) {
+	nomes := nomesBench()
+	var relogio atomic.Int64
+	m := Novo(Opcoes[string, int]{
+		TTL:   512 * time.Nanosecond,
+		Agora: func() time.Time { return time.Unix(0, relogio.Add(1)) },
+	})
+	var semente, vencidas atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		aleatorio := rand.New(rand.NewSource(semente.Add(1)))
+		for pb.Next() {
+			nome := nomes[aleatorio.Intn(chavesBench)]
+			if _, ok := m.Get(nome); !ok {
+				vencidas.Add(1)
+				m.Set(nome, 1)
+			}
+		}
+	})
+	b.ReportMetric(float64(vencidas.Load())/float64(b.N), "faltas/op")
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"time"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+// As tabelas de cada ano ficam em tabelas/<ano>.json. As que acompanham o
+// programa são embutidas no binário; CarregarTabelas aceita qualquer fs.FS,
+// então dá para apontar para um diretório com tabelas novas sem recompilar.
+//
+//go:embed tabelas/*.json
+var tabelasEmbutidas embed.FS
+
+var ErrTabelaNaoEncontrada = erros.Definir("tabelas.nao_encontrada", erros.NaoEncontrado).Sentinela("tabela não encontrada para a competência")
+
+// Faixa é um degrau de uma tabela progressiva. Valores em centavos e
+// alíquota em pontos-base (7,5% = 750). Ate zero significa "sem limite".
+type Faixa struct {
+	Ate      int
+	Aliquota int
+	Deducao  int
+}
+
+// TabelaIRRF é a tabela mensal do imposto de renda a partir de uma vigência.
+type TabelaIRRF struct {
+	Vigencia             time.Time
+	Faixas               []Faixa
+	DeducaoDependente    int
+	DescontoSimplificado int
+}
+
+// TabelaAno reúne as tabelas de um ano. O INSS muda uma vez por ano, junto
+// com o salário mínimo; o IRRF pode mudar no meio do ano, por isso há uma
+// lista de vigências.
+type TabelaAno struct {
+	Ano   int
+	INSS  []Faixa
+	IRRF  []TabelaIRRF
+	FGTS  int
+	Fonte string
+}
+
+// Tabelas indexa as tabelas carregadas por ano.
+type Tabelas map[int]*TabelaAno
+
+// arquivoTabela é o formato do JSON: valores em reais e alíquotas em
+// percentual, como aparecem nas portarias, para facilitar a conferência.
+type arquivoTabela struct {
+	Ano  int            `json:"ano"`
+	FGTS float64        `json:"fgts"`
+	INSS []arquivoFaixa `json:"inss"`
+	IRRF []struct {
+		Vigencia             string         `json:"vigencia"`
+		DeducaoDependente    float64        `json:"deducao_dependente"`
+		DescontoSimplificado float64        `json:"desconto_simplificado"`
+		Faixas               []arquivoFaixa `json:"faixas"`
+	} `json:"irrf"`
+}
+
+type arquivoFaixa struct {
+	Ate      float64 `json:"ate"`
+	Aliquota float64 `json:"aliquota"`
+	Deducao  float64 `json:"deducao"`
+}
+
+// TabelasPadrao carrega as tabelas embutidas no programa.
+func TabelasPadrao() (Tabelas, error) {
+	sub, err := fs.Sub(tabelasEmbutidas, "tabelas")
+	if err != nil {
+		return nil, err
+	}
+	return CarregarTabelas(sub)
+}
+
+// CarregarTabelas lê todos os *.json da raiz de fsys.
+func CarregarTabelas(fsys fs.FS) (Tabelas, error) {
+	arquivos, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+	if len(arquivos) == 0 {
+		return nil, errors.New("nenhuma tabela encontrada")
+	}
+
+	tabelas := Tabelas{}
+	for _, nome := range arquivos {
+		conteudo, err := fs.ReadFile(fsys, nome)
+		if err != nil {
+			return nil, err
+		}
+		tabela, err := lerTabela(conteudo)
+		if err != nil {
+			return nil, fmt.Errorf("tabela %s: %w", nome, err)
+		}
+		if _, repetido := tabelas[tabela.Ano]; repetido {
+			return nil, fmt.Errorf("tabela %s: ano %d repetido", nome, tabela.Ano)
+		}
+		tabela.Fonte = path.Base(nome)
+		tabelas[tabela.Ano] = tabela
+	}
+	return tabelas, nil
+}
+
+func lerTabela(conteudo []byte) (*TabelaAno, error) {
+	var arquivo arquivoTabela
+	if err := json.Unmarshal(conteudo, &arquivo); err != nil {
+		return nil, err
+	}
+	if arquivo.Ano == 0 {
+		return nil, errors.New("ano não informado")
+	}
+
+	tabela := &TabelaAno{Ano: arquivo.Ano, FGTS: pontosBase(arquivo.FGTS)}
+	var err error
+	if tabela.INSS, err = converterFaixas(arquivo.INSS, true); err != nil {
+		return nil, fmt.Errorf("inss: %w", err)
+	}
+	for _, irrf := range arquivo.IRRF {
+		vigencia, err := time.Parse("2006-01", irrf.Vigencia)
+		if err != nil {
+			return nil, fmt.Errorf("irrf: vigência %q: %w", irrf.Vigencia, err)
+		}
+		if vigencia.Year() != arquivo.Ano {
+			return nil, fmt.Errorf("irrf: vigência %s fora do ano %d", irrf.Vigencia, arquivo.Ano)
+		}
+		faixas, err := converterFaixas(irrf.Faixas, false)
+		if err != nil {
+			return nil, fmt.Errorf("irrf %s: %w", irrf.Vigencia, err)
+		}
+		tabela.IRRF = append(tabela.IRRF, TabelaIRRF{
+			Vigencia:             vigencia,
+			Faixas:               faixas,
+			DeducaoDependente:    centavos(irrf.DeducaoDependente),
+			DescontoSimplificado: centavos(irrf.DescontoSimplificado),
+		})
+	}
+	if len(tabela.IRRF) == 0 {
+		return nil, errors.New("irrf: nenhuma vigência")
+	}
+	sort.Slice(tabela.IRRF, func(i, j int) bool { return tabela.IRRF[i].Vigencia.Before(tabela.IRRF[j].Vigencia) })
+	return tabela, nil
+}
+
+// converterFaixas valida que os limites crescem. Na tabela do INSS todas as
+// faixas têm limite (o último é o teto); na do IRRF só a última pode não ter.
+func converterFaixas(arquivo []arquivoFaixa, comTeto bool) ([]Faixa, error) {
+	if len(arquivo) == 0 {
+		return nil, errors.New("nenhuma faixa")
+	}
+	faixas := make([]Faixa, len(arquivo))
+	anterior := 0
+	for i, f := range arquivo {
+		faixas[i] = Faixa{Ate: centavos(f.Ate), Aliquota: pontosBase(f.Aliquota), Deducao: centavos(f.Deducao)}
+		ultima := i == len(arquivo)-1
+		switch {
+		case faixas[i].Ate == 0 && (comTeto || !ultima):
+			return nil, fmt.Errorf("faixa %d sem limite", i+1)
+		case faixas[i].Ate != 0 && faixas[i].Ate <= anterior:
+			return nil, fmt.Errorf("faixa %d com limite menor que a anterior", i+1)
+		}
+		anterior = faixas[i].Ate
+	}
+	return faixas, nil
+}
+
+// Para devolve as tabelas do INSS e do IRRF que valem na competência
+// (o mês de referência da folha).
+func (t Tabelas) Para(competencia time.Time) (*TabelaAno, TabelaIRRF, error) {
+	ano, ok := t[competencia.Year()]
+	if !ok {
+		return nil, TabelaIRRF{}, fmt.Errorf("%w %s", ErrTabelaNaoEncontrada, competencia.Format("01/2006"))
+	}
+	mes := time.Date(competencia.Year(), competencia.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for i := len(ano.IRRF) - 1; i >= 0; i-- {
+		if !ano.IRRF[i].Vigencia.After(mes) {
+			return ano, ano.IRRF[i], nil
+		}
+	}
+	return nil, TabelaIRRF{}, fmt.Errorf("%w %s (IRRF)", ErrTabelaNaoEncontrada, competencia.Format("01/2006"))
+}
+
+func centavos(reais float64) int {
+	return int(math.Round(reais * 100))
+}
+
+func pontosBase(percentual float64) int {
+	return int(math.Round(percentual * 100))
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// tabelaJSON monta um arquivo de tabela trocando trechos da tabela válida.
+func tabelaJSON(trocas ...string) *fstest.MapFile {
+	conteudo := `{
+  "ano": 2026, "fgts": 8,
+  "inss": [{"ate": 1000, "aliquota": 7.5}, {"ate": 2000, "aliquota": 9}],
+  "irrf": [
+    {"vigencia": "2026-03", "deducao_dependente": 200, "desconto_simplificado": 600,
+     "faixas": [{"ate": 2500, "aliquota": 0}, {"aliquota": 27.5, "deducao": 900}]},
+    {"vigencia": "2026-01", "deducao_dependente": 190, "desconto_simplificado": 560,
+     "faixas": [{"ate": 2300, "aliquota": 0}, {"aliquota": 27.5, "deducao": 880}]}
+  ]
+}`
+	conteudo = strings.NewReplacer(trocas...).Replace(conteudo)
+	return &fstest.MapFile{Data: []byte(conteudo)}
+}
+
+func TestCarregarTabelas(t *testing.T) {
+	tabelas, err := CarregarTabelas(fstest.MapFS{"2026.json": tabelaJSON(), "leia-me.txt": {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ano := tabelas[2026]
+	if ano == nil || ano.Fonte != "2026.json" || ano.FGTS != 800 {
+		t.Fatalf("tabela carregada = %+v", ano)
+	}
+	if ano.INSS[0] != (Faixa{Ate: 100000, Aliquota: 750}) {
+		t.Errorf("primeira faixa do INSS = %+v", ano.INSS[0])
+	}
+
+	// as vigências são ordenadas, e cada mês usa a mais recente até ele
+	for mes, desconto := range map[time.Month]int{time.January: 56000, time.February: 56000, time.March: 60000, time.December: 60000} {
+		_, irrf, err := tabelas.Para(competencia(2026, mes))
+		if err != nil || irrf.DescontoSimplificado != desconto {
+			t.Errorf("%s: desconto simplificado %d, %v; quero %d", mes, irrf.DescontoSimplificado, err, desconto)
+		}
+	}
+}
+
+func TestCarregarTabelasValida(t *testing.T) {
+	casos := []struct {
+		nome  string
+		fsys  fstest.MapFS
+		texto string
+	}{
+		{"sem arquivos", fstest.MapFS{}, "nenhuma tabela"},
+		{"json inválido", fstest.MapFS{"x.json": {Data: []byte("{")}}, "x.json"},
+		{"sem ano", fstest.MapFS{"x.json": tabelaJSON(`"ano": 2026,`, "")}, "ano não informado"},
+		{"ano repetido", fstest.MapFS{"a.json": tabelaJSON(), "b.json": tabelaJSON()}, "ano 2026 repetido"},
+		{"inss sem faixas", fstest.MapFS{"x.json": tabelaJSON(`{"ate": 1000, "aliquota": 7.5}, {"ate": 2000, "aliquota": 9}`, "")}, "inss: nenhuma faixa"},
+		{"inss sem teto", fstest.MapFS{"x.json": tabelaJSON(`{"ate": 2000, "aliquota": 9}`, `{"aliquota": 9}`)}, "inss: faixa 2 sem limite"},
+		{"inss fora de ordem", fstest.MapFS{"x.json": tabelaJSON(`"ate": 2000`, `"ate": 900`)}, "inss: faixa 2 com limite menor"},
+		{"irrf sem limite no meio", fstest.MapFS{"x.json": tabelaJSON(`{"ate": 2500, "aliquota": 0}`, `{"aliquota": 0}`)}, "faixa 1 sem limite"},
+		{"vigência inválida", fstest.MapFS{"x.json": tabelaJSON(`"2026-03"`, `"março"`)}, "vigência"},
+		{"vigência de outro ano", fstest.MapFS{"x.json": tabelaJSON(`"2026-03"`, `"2025-03"`)}, "fora do ano 2026"},
+		{"sem irrf", fstest.MapFS{"x.json": &fstest.MapFile{Data: []byte(`{"ano": 2026, "inss": [{"ate": 1000, "aliquota": 7.5}]}`)}}, "irrf: nenhuma vigência"},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			_, err := CarregarTabelas(c.fsys)
+			if err == nil || !strings.Contains(err.Error(), c.texto) {
+				t.Errorf("erro = %v, quero um que cite %q", err, c.texto)
+			}
+		})
+	}
+}
+
+func TestParaAntesDaPrimeiraVigencia(t *testing.T) {
+	tabelas, err := CarregarTabelas(fstest.MapFS{"2026.json": tabelaJSON(`"2026-01"`, `"2026-02"`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := tabelas.Para(competencia(2026, time.January)); !errors.Is(err, ErrTabelaNaoEncontrada) {
+		t.Errorf("janeiro sem vigência de IRRF: erro = %v, quero ErrTabelaNaoEncontrada", err)
+	}
+	if _, _, err := tabelas.Para(competencia(2026, time.February)); err != nil {
+		t.Errorf("fevereiro: %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+// Package erros organiza os erros do projeto em códigos e categorias.
+//
+// Cada erro de domínio tem um Codigo estável (por exemplo
+// "soma.acima_do_limite") e uma Categoria (validação, não encontrado,
+// conflito, interno). A categoria decide o status HTTP e o código de saída;
+// o código decide a mensagem mostrada ao usuário, que vem de catálogos por
+// idioma separados do código-fonte (veja mensagens.go). Error() continua
+// em texto técnico, para logs.
+//
+// Compilando com -tags debug, cada erro guarda a pilha de onde foi criado.
+package erros
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Categoria agrupa códigos que o chamador trata do mesmo jeito.
+type Categoria int
+
+const (
+	Interno Categoria = iota
+	Validacao
+	NaoEncontrado
+	Conflito
+)
+
+func (c Categoria) String() string {
+	switch c {
+	case Validacao:
+		return "validacao"
+	case NaoEncontrado:
+		return "nao_encontrado"
+	case Conflito:
+		return "conflito"
+	}
+	return "interno"
+}
+
+// StatusHTTP é o status de resposta de cada categoria.
+func (c Categoria) StatusHTTP() int {
+	switch c {
+	case Validacao:
+		return http.StatusUnprocessableEntity
+	case NaoEncontrado:
+		return http.StatusNotFound
+	case Conflito:
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// CodigoSaida segue o sysexits.h do BSD, que é o que scripts de shell
+// costumam esperar: 65 dado inválido, 66 entrada não encontrada, 75 falha
+// temporária (tente de novo) e 70 erro interno.
+func (c Categoria) CodigoSaida() int {
+	switch c {
+	case Validacao:
+		return 65
+	case NaoEncontrado:
+		return 66
+	case Conflito:
+		return 75
+	}
+	return 70
+}
+
+// Codigo identifica um tipo de erro. Defina os códigos uma vez, como
+// variáveis do pacote que os usa:
+//
+//	var ErrSomaAcimaDoLimite = erros.Definir("soma.acima_do_limite", erros.Validacao)
+type Codigo struct {
+	ID        string
+	Categoria Categoria
+}
+
+// Definir cria um código. O ID deve ser estável: ele é a chave das
+// mensagens nos catálogos e pode aparecer em respostas de API.
+func Definir(id string, categoria Categoria) Codigo {
+	return Codigo{ID: id, Categoria: categoria}
+}
+
+// Params são os valores que a mensagem localizada pode citar, como
+// {limite} ou {valor}.
+type Params map[string]any
+
+// Erro é um erro com código. Use errors.As para chegar nele ou as funções
+// CodigoDe, CategoriaDe, StatusHTTP e CodigoSaida, que já fazem isso.
+type Erro struct {
+	Codigo Codigo
+	Params Params
+	causa  error
+	pilha  []uintptr
+}
+
+// Sentinela cria um erro do código com um texto técnico fixo, para ser uma
+// variável de pacote no lugar de errors.New. errors.Is continua valendo
+// para o valor e para qualquer outro erro do mesmo código:
+//
+//	var ErrContaNaoEncontrada = erros.Definir("conta.nao_encontrada", erros.NaoEncontrado).Sentinela("conta não encontrada")
+//
+// Sentinelas não guardam pilha: seriam criadas na inicialização do pacote,
+// longe de onde o erro acontece.
+func (c Codigo) Sentinela(texto string) *Erro {
+	return &Erro{Codigo: c, causa: errors.New(texto)}
+}
+
+// Novo cria um erro do código, sem causa.
+func (c Codigo) Novo(params Params) *Erro {
+	return &Erro{Codigo: c, Params: params, pilha: capturarPilha()}
+}
+
+// Envolver cria um erro do código com causa. errors.Is e errors.As
+// continuam enxergando a causa.
+func (c Codigo) Envolver(causa error, params Params) *Erro {
+	return &Erro{Codigo: c, Params: params, causa: causa, pilha: capturarPilha()}
+}
+
+// Error é a forma técnica, para logs: o ID do código seguido da causa.
+func (e *Erro) Error() string {
+	if e.causa == nil {
+		return e.Codigo.ID
+	}
+	return e.Codigo.ID + ": " + e.causa.Error()
+}
+
+func (e *Erro) Unwrap() error {
+	return e.causa
+}
+
+// Is faz errors.Is(err, codigo.Novo(nil)) valer para qualquer erro do
+// mesmo código, quaisquer que sejam os parâmetros.
+func (e *Erro) Is(alvo error) bool {
+	outro, ok := alvo.(*Erro)
+	return ok && outro.Codigo == e.Codigo
+}
+
+// Pilha devolve a pilha de onde o erro foi criado, uma chamada por linha.
+// Fora de builds com -tags debug, é sempre vazia.
+func (e *Erro) Pilha() string {
+	return formatarPilha(e.pilha)
+}
+
+// CodigoDe devolve o código do primeiro *Erro na cadeia. Erros sem código
+// contam como internos.
+func CodigoDe(err error) (Codigo, bool) {
+	var e *Erro
+	if errors.As(err, &e) {
+		return e.Codigo, true
+	}
+	return Codigo{ID: "interno", Categoria: Interno}, false
+}
+
+// TemCodigo diz se algum *Erro na cadeia tem o código.
+func TemCodigo(err error, codigo Codigo) bool {
+	return errors.Is(err, &Erro{Codigo: codigo})
+}
+
+func CategoriaDe(err error) Categoria {
+	codigo, _ := CodigoDe(err)
+	return codigo.Categoria
+}
+
+// StatusHTTP devolve 200 para nil e o status da categoria nos demais casos.
+func StatusHTTP(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return CategoriaDe(err).StatusHTTP()
+}
+
+// CodigoSaida devolve 0 para nil e o código de saída da categoria nos
+// demais casos.
+func CodigoSaida(err error) int {
+	if err == nil {
+		return 0
+	}
+	return CategoriaDe(err).CodigoSaida()
+}
+
+// formatarParams substitui {nome} pelos valores de params.
+func formatarParams(modelo string, params Params) string {
+	if len(params) == 0 {
+		return modelo
+	}
+	pares := make([]string, 0, 2*len(params))
+	for nome, valor := range params {
+		pares = append(pares, "{"+nome+"}", fmt.Sprint(valor))
+	}
+	return strings.NewReplacer(pares...).Replace(modelo)
+}
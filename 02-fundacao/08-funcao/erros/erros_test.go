@@ -0,0 +1,132 @@
+package erros
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"testing/fstest"
+)
+
+var (
+	errTesteValidacao = Definir("teste.valor_invalido", Validacao)
+	errTesteAusente   = Definir("teste.ausente", NaoEncontrado).Sentinela("registro ausente")
+)
+
+func TestIsComparaPeloCodigo(t *testing.T) {
+	causa := errors.New("disco cheio")
+	err := fmt.Errorf("salvar: %w", errTesteValidacao.Envolver(causa, Params{"valor": 3}))
+
+	if !errors.Is(err, errTesteValidacao.Novo(nil)) {
+		t.Error("errors.Is não achou o código através do fmt.Errorf")
+	}
+	if !TemCodigo(err, errTesteValidacao) {
+		t.Error("TemCodigo = false")
+	}
+	if !errors.Is(err, causa) {
+		t.Error("errors.Is não achou a causa dentro do *Erro")
+	}
+	if errors.Is(err, errTesteAusente) || TemCodigo(err, errTesteAusente.Codigo) {
+		t.Error("erro de um código casou com outro código")
+	}
+
+	// a sentinela casa com qualquer erro do mesmo código, com ou sem causa
+	outro := fmt.Errorf("buscar: %w", errTesteAusente.Codigo.Novo(Params{"id": 7}))
+	if !errors.Is(outro, errTesteAusente) {
+		t.Error("errors.Is não casou a sentinela com um erro novo do mesmo código")
+	}
+
+	var e *Erro
+	if !errors.As(err, &e) || e.Params["valor"] != 3 {
+		t.Errorf("errors.As = %+v", e)
+	}
+	if got := err.Error(); got != "salvar: teste.valor_invalido: disco cheio" {
+		t.Errorf("Error() = %q", got)
+	}
+	if got := errTesteValidacao.Novo(nil).Error(); got != "teste.valor_invalido" {
+		t.Errorf("Error() sem causa = %q", got)
+	}
+}
+
+func TestCodigoDe(t *testing.T) {
+	codigo, ok := CodigoDe(fmt.Errorf("x: %w", errTesteAusente))
+	if !ok || codigo != errTesteAusente.Codigo {
+		t.Errorf("CodigoDe = %v, %v", codigo, ok)
+	}
+	codigo, ok = CodigoDe(errors.New("sem código"))
+	if ok || codigo.ID != "interno" || codigo.Categoria != Interno {
+		t.Errorf("CodigoDe de erro comum = %v, %v; quero interno", codigo, ok)
+	}
+}
+
+func TestStatusECodigoDeSaidaPorCategoria(t *testing.T) {
+	casos := []struct {
+		err           error
+		categoria     Categoria
+		status, saida int
+	}{
+		{nil, Interno, http.StatusOK, 0},
+		{errTesteValidacao.Novo(nil), Validacao, http.StatusUnprocessableEntity, 65},
+		{errTesteAusente, NaoEncontrado, http.StatusNotFound, 66},
+		{Definir("teste.conflito", Conflito).Novo(nil), Conflito, http.StatusConflict, 75},
+		{Definir("teste.interno", Interno).Novo(nil), Interno, http.StatusInternalServerError, 70},
+		{errors.New("sem código"), Interno, http.StatusInternalServerError, 70},
+		{fmt.Errorf("envolvido: %w", errTesteAusente), NaoEncontrado, http.StatusNotFound, 66},
+	}
+	for _, c := range casos {
+		if c.err != nil && CategoriaDe(c.err) != c.categoria {
+			t.Errorf("CategoriaDe(%v) = %v, quero %v", c.err, CategoriaDe(c.err), c.categoria)
+		}
+		if got := StatusHTTP(c.err); got != c.status {
+			t.Errorf("StatusHTTP(%v) = %d, quero %d", c.err, got, c.status)
+		}
+		if got := CodigoSaida(c.err); got != c.saida {
+			t.Errorf("CodigoSaida(%v) = %d, quero %d", c.err, got, c.saida)
+		}
+	}
+}
+
+func TestMensagem(t *testing.T) {
+	err := RegistrarMensagens(fstest.MapFS{
+		"pt-BR.json": {Data: []byte(`{"teste.valor_invalido": "O valor {valor} passa de {limite}."}`)},
+		"en.json":    {Data: []byte(`{"teste.valor_invalido": "Value {valor} is over {limite}."}`)},
+		"pt-PT.json": {Data: []byte(`{"teste.ausente": "Registo ausente."}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	comParams := fmt.Errorf("x: %w", errTesteValidacao.Novo(Params{"valor": 60, "limite": 49}))
+
+	casos := []struct {
+		nome   string
+		err    error
+		idioma string
+		quero  string
+	}{
+		{"idioma exato", comParams, "pt-BR", "O valor 60 passa de 49."},
+		{"idioma base", comParams, "en-US", "Value 60 is over 49."},
+		{"idioma desconhecido cai no padrão", comParams, "fr", "O valor 60 passa de 49."},
+		{"variante regional", errTesteAusente, "pt-PT", "Registo ausente."},
+		{"sem mensagem do código usa a da categoria", errTesteAusente, "pt-BR", "O recurso pedido não foi encontrado."},
+		{"categoria em inglês", errTesteAusente, "en", "The requested resource was not found."},
+		{"erro sem código não vaza o texto técnico", errors.New("senha do banco: hunter2"), "pt-BR", "Ocorreu um erro inesperado. Tente novamente mais tarde."},
+		{"nil", nil, "pt-BR", ""},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			if got := Mensagem(c.err, c.idioma); got != c.quero {
+				t.Errorf("Mensagem = %q, quero %q", got, c.quero)
+			}
+		})
+	}
+
+	if err := RegistrarMensagens(fstest.MapFS{"xx.json": {Data: []byte("{")}}); err == nil {
+		t.Error("RegistrarMensagens aceitou JSON inválido")
+	}
+}
+
+func TestSentinelaNaoGuardaPilha(t *testing.T) {
+	if p := errTesteAusente.Pilha(); p != "" {
+		t.Errorf("sentinela com pilha:\n%s", p)
+	}
+}
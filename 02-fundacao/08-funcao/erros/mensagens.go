@@ -0,0 +1,103 @@
+package erros
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// IdiomaPadrao é usado quando não há mensagem no idioma pedido.
+const IdiomaPadrao = "pt-BR"
+
+// As mensagens das categorias vêm embutidas; cada pacote que define códigos
+// registra as suas com RegistrarMensagens.
+//
+//go:embed mensagens/*.json
+var mensagensEmbutidas embed.FS
+
+var (
+	muCatalogo sync.RWMutex
+	// catalogo[idioma][id do código] = modelo da mensagem
+	catalogo = map[string]map[string]string{}
+)
+
+func init() {
+	sub, err := fs.Sub(mensagensEmbutidas, "mensagens")
+	if err == nil {
+		err = RegistrarMensagens(sub)
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+// RegistrarMensagens lê os arquivos <idioma>.json da raiz de fsys, cada um
+// um objeto {"id.do.codigo": "modelo com {parametros}"}, e os acrescenta ao
+// catálogo. Um id já registrado no mesmo idioma é substituído.
+func RegistrarMensagens(fsys fs.FS) error {
+	arquivos, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return err
+	}
+	for _, nome := range arquivos {
+		conteudo, err := fs.ReadFile(fsys, nome)
+		if err != nil {
+			return err
+		}
+		var mensagens map[string]string
+		if err := json.Unmarshal(conteudo, &mensagens); err != nil {
+			return fmt.Errorf("mensagens %s: %w", nome, err)
+		}
+		idioma := strings.TrimSuffix(path.Base(nome), ".json")
+
+		muCatalogo.Lock()
+		if catalogo[idioma] == nil {
+			catalogo[idioma] = map[string]string{}
+		}
+		for id, modelo := range mensagens {
+			catalogo[idioma][id] = modelo
+		}
+		muCatalogo.Unlock()
+	}
+	return nil
+}
+
+// Mensagem é o texto para mostrar ao usuário. Procura o código no idioma
+// pedido ("pt-BR"), depois no idioma base ("pt"), depois no IdiomaPadrao;
+// sem mensagem para o código, usa a da categoria. Erros sem código recebem
+// a mensagem genérica de erro interno, para não vazar detalhes técnicos.
+func Mensagem(err error, idioma string) string {
+	if err == nil {
+		return ""
+	}
+	codigo, _ := CodigoDe(err)
+	var params Params
+	var e *Erro
+	if errors.As(err, &e) {
+		params = e.Params
+	}
+
+	muCatalogo.RLock()
+	defer muCatalogo.RUnlock()
+	for _, id := range []string{codigo.ID, codigo.Categoria.String()} {
+		for _, candidato := range idiomas(idioma) {
+			if modelo, ok := catalogo[candidato][id]; ok {
+				return formatarParams(modelo, params)
+			}
+		}
+	}
+	return codigo.ID
+}
+
+func idiomas(idioma string) []string {
+	candidatos := []string{idioma}
+	if base, _, ok := strings.Cut(idioma, "-"); ok {
+		candidatos = append(candidatos, base)
+	}
+	return append(candidatos, IdiomaPadrao)
+}
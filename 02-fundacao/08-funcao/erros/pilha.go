@@ -0,0 +1,10 @@
+//go:build !debug
+
+package erros
+
+// Sem a tag debug, capturar a pilha custaria caro em todo erro criado e o
+// resultado quase nunca seria olhado; estas versões não fazem nada.
+
+func capturarPilha() []uintptr { return nil }
+
+func formatarPilha([]uintptr) string { return "" }
@@ -0,0 +1,33 @@
+//go:build debug
+
+package erros
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// capturarPilha guarda quem chamou Novo ou Envolver (pulando as próprias
+// funções do pacote).
+func capturarPilha() []uintptr {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	return pcs[:n]
+}
+
+func formatarPilha(pcs []uintptr) string {
+	if len(pcs) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	quadros := runtime.CallersFrames(pcs)
+	for {
+		quadro, mais := quadros.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", quadro.Function, quadro.File, quadro.Line)
+		if !mais {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,32 @@
+//go:build debug
+
+package erros
+
+import (
+	"strings"
+	"testing"
+)
+
+func criarErroParaPilha() *Erro {
+	return errTesteValidacao.Novo(nil)
+}
+
+func TestPilhaComTagDebug(t *testing.T) {
+	pilha := criarErroParaPilha().Pilha()
+	linhas := strings.Split(strings.TrimSpace(pilha), "\n")
+	if len(linhas) < 2 {
+		t.Fatalf("pilha curta demais:\n%s", pilha)
+	}
+	// o primeiro quadro é quem chamou Novo, não o próprio pacote
+	if !strings.HasSuffix(linhas[0], ".criarErroParaPilha") || !strings.Contains(linhas[1], "pilha_debug_test.go:") {
+		t.Errorf("primeiro quadro:\n%s\n%s\nquero criarErroParaPilha em pilha_debug_test.go", linhas[0], linhas[1])
+	}
+	if !strings.Contains(pilha, "TestPilhaComTagDebug") {
+		t.Errorf("a pilha não chega ao teste:\n%s", pilha)
+	}
+
+	envolvido := errTesteValidacao.Envolver(nil, nil)
+	if !strings.Contains(envolvido.Pilha(), "TestPilhaComTagDebug") {
+		t.Errorf("Envolver não capturou a pilha:\n%s", envolvido.Pilha())
+	}
+}
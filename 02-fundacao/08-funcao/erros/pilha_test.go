@@ -0,0 +1,11 @@
+//go:build !debug
+
+package erros
+
+import "testing"
+
+func TestPilhaVaziaSemTagDebug(t *testing.T) {
+	if p := errTesteValidacao.Novo(nil).Pilha(); p != "" {
+		t.Errorf("Pilha sem -tags debug = %q, quero vazia", p)
+	}
+}
@@ -1,11 +1,69 @@
 package main
 
 import (
-	"errors" // Import the 'errors' package to create custom error messages.
-	"fmt"    // Import the 'fmt' package for formatted I/O (like printing to the console).
+	"embed"
+	"errors" // Import the 'errors' package to inspect errors with errors.As.
+	"flag"
+	"fmt" // Import the 'fmt' package for formatted I/O (like printing to the console).
+	"io/fs"
+	"os"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+	"02-fundacao/02-fundacao/08-funcao/validacao"
+)
+
+// Error codes for this program. The code decides the user-facing message
+// (see mensagens/*.json) and the category decides the HTTP status and the
+// exit code.
+var (
+	ErrSomaForaDoLimite = erros.Definir("soma.fora_do_limite", erros.Validacao)
+	ErrLimitesAusentes  = erros.Definir("limites.arquivo_ausente", erros.NaoEncontrado)
+	ErrLimitesInvalidos = erros.Definir("limites.arquivo_invalido", erros.Validacao)
 )
 
+// The messages live in JSON files, one per language, apart from the code.
+//
+//go:embed mensagens/*.json
+var mensagens embed.FS
+
+func init() {
+	sub, _ := fs.Sub(mensagens, "mensagens")
+	if err := erros.RegistrarMensagens(sub); err != nil {
+		panic(err)
+	}
+}
+
+// limites holds the validation limits used by 'sum'. They used to be
+// compiled into the function ("a+b >= 50 is an error"); now they are data
+// that can be replaced with -limites arquivo.json. For integers, "max 49"
+// is the same rule as the old "a+b >= 50 is an error".
+var limites = validacao.Limites{
+	"soma": {Max: ptr(49.0)},
+}
+
 func main() {
+	arquivo := flag.String("limites", "", "JSON file with validation limits, e.g. {\"soma\": {\"max\": 100}}")
+	flag.Parse()
+	if *arquivo != "" {
+		carregados, err := validacao.CarregarLimites(*arquivo)
+		if err == nil {
+			// 'sum' works with int: a limit like 49.5 is rejected here,
+			// instead of being silently truncated later.
+			_, err = validacao.RegrasDe[int](carregados, "soma")
+		}
+		if err != nil {
+			codigo := ErrLimitesInvalidos
+			if errors.Is(err, fs.ErrNotExist) {
+				codigo = ErrLimitesAusentes
+			}
+			err = codigo.Envolver(err, erros.Params{"arquivo": *arquivo})
+			fmt.Fprintln(os.Stderr, erros.Mensagem(err, "pt-BR"))
+			fmt.Fprintln(os.Stderr, "detail:", err)
+			os.Exit(erros.CodigoSaida(err))
+		}
+		limites = carregados
+	}
+
 	// Call the 'sum' function and assign its two return values to 'valor' and 'err'.
 	// In Go, it's a common pattern for functions that might fail to return a second value of type 'error'.
 	valor, err := sum(50, 10) // Example 1: sum will be 60, triggering an error.
@@ -13,13 +71,29 @@ func main() {
 	// Check if an error occurred.
 	// If 'err' is not 'nil' (which means an error was returned), print the error message.
 	if err != nil {
-		fmt.Println("Error:", err) // Output: Error: A soma é maior que 50
-		return                   // It's good practice to exit the function or handle the error appropriately
-		// to prevent further execution with potentially invalid data.
-	}
+		fmt.Println("Error:", err) // Output: Error: soma.fora_do_limite: soma: 60 acima do máximo 49
+
+		// The technical text above is for logs. Users get a message from
+		// the catalog in their language, and the category tells an HTTP
+		// handler or a CLI how to report it.
+		fmt.Println("  pt-BR:", erros.Mensagem(err, "pt-BR"))
+		fmt.Println("  en-US:", erros.Mensagem(err, "en-US"))
+		fmt.Printf("  category=%s http=%d exit=%d\n", erros.CategoriaDe(err), erros.StatusHTTP(err), erros.CodigoSaida(err))
+		var comCodigo *erros.Erro
+		if errors.As(err, &comCodigo) && comCodigo.Pilha() != "" { // only with -tags debug
+			fmt.Print("  created at:\n", comCodigo.Pilha())
+		}
 
-	// If no error occurred, print the calculated 'valor'.
-	fmt.Println("Result (no error):", valor) // This line will only execute if 'err' is nil.
+		// The error is typed, so the caller can find out which field failed,
+		// against which limit and with which value, instead of parsing a string.
+		var limite *validacao.ErrLimite[int]
+		if errors.As(err, &limite) {
+			fmt.Printf("  field=%s rule=%s limit=%d value=%d\n", limite.Campo, limite.Regra, limite.Limite, limite.Valor)
+		}
+	} else {
+		// If no error occurred, print the calculated 'valor'.
+		fmt.Println("Result (no error):", valor)
+	}
 
 	// Let's try another call where the sum is less than 50.
 	valor2, err2 := sum(10, 20) // Example 2: sum will be 30, no error.
@@ -28,23 +102,57 @@ func main() {
 		return
 	}
 	fmt.Println("Result (no error):", valor2) // Output: Result (no error): 30
+
+	// --- Several Violations at Once ---
+
+	// A Validador keeps checking after the first failure and returns every
+	// violation together. Rules compose: Intervalo is Min and Max, and
+	// Predicado turns any func into a rule.
+	var v validacao.Validador
+	par := validacao.Predicado("par", func(n int) bool { return n%2 == 0 })
+	validacao.Checar(&v, "a", -3, validacao.Intervalo(0, 100), par)
+	validacao.Checar(&v, "b", 7, validacao.Max(5))
+	validacao.Checar(&v, "nome", "", validacao.Predicado("não vazio", func(s string) bool { return s != "" }))
+	if err := v.Err(); err != nil {
+		var violacoes validacao.Violacoes
+		errors.As(err, &violacoes)
+		fmt.Printf("\n%d violations:\n", len(violacoes))
+		for _, violacao := range violacoes {
+			fmt.Println(" -", violacao)
+		}
+	}
 }
 
 // sum is a function that takes two integers (a and b) and returns an integer
 // and an error. This is a common Go idiom for functions that might fail.
 func sum(a, b int) (int, error) {
-	// Check if the sum of 'a' and 'b' is greater than or equal to 50.
-	if a+b >= 50 {
-		// If the condition is met, return 0 (or any appropriate zero value for the primary result)
-		// and a new error created using 'errors.New()'.
-		return 0, errors.New("A soma é maior que 50")
+	// Check the result against the limits configured for "soma". When a rule
+	// fails, return 0 (the zero value for the primary result) and the error.
+	regras, err := validacao.RegrasDe[int](limites, "soma")
+	if err != nil {
+		return 0, err
+	}
+	var v validacao.Validador
+	validacao.Checar(&v, "soma", a+b, regras...)
+	if err := v.Err(); err != nil {
+		// Wrapping keeps the validation error reachable with errors.As.
+		params := erros.Params{"valor": a + b}
+		var limite *validacao.ErrLimite[int]
+		if errors.As(err, &limite) {
+			params["limite"] = limite.Limite
+		}
+		return 0, ErrSomaForaDoLimite.Envolver(err, params)
 	}
 
-	// If the condition is not met (i.e., the sum is less than 50),
-	// return the actual sum and 'nil' for the error, indicating no error occurred.
+	// Otherwise return the actual sum and 'nil' for the error,
+	// indicating no error occurred.
 	return a + b, nil
 }
 
+func ptr[T any](v T) *T {
+	return &v
+}
+
 /*
 --- Old Examples (commented out for clarity, but explained below) ---
 
@@ -0,0 +1,105 @@
+package validacao
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+)
+
+// Numero são os tipos que os limites configuráveis sabem converter.
+type Numero interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// LimitesCampo são os limites de um campo; um ponteiro nil é "sem limite".
+type LimitesCampo struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// Limites guarda os limites por nome de campo, para que possam vir de um
+// arquivo em vez de ficarem fixos no código:
+//
+//	{"soma": {"max": 49}, "a": {"min": 0}}
+type Limites map[string]LimitesCampo
+
+// CarregarLimites lê os limites de um arquivo JSON.
+func CarregarLimites(caminho string) (Limites, error) {
+	conteudo, err := os.ReadFile(caminho)
+	if err != nil {
+		return nil, err
+	}
+	var limites Limites
+	if err := json.Unmarshal(conteudo, &limites); err != nil {
+		return nil, fmt.Errorf("limites %s: %w", caminho, err)
+	}
+	for campo, l := range limites {
+		if l.Min != nil && l.Max != nil && *l.Min > *l.Max {
+			return nil, fmt.Errorf("limites %s: campo %q com min %v maior que max %v", caminho, campo, *l.Min, *l.Max)
+		}
+	}
+	return limites, nil
+}
+
+// RegrasDe monta as regras Min e Max configuradas para o campo. Um campo
+// sem configuração não tem regras.
+//
+// Os limites vêm do JSON como float64. Para um T inteiro, um limite com
+// casas decimais, negativo num T sem sinal ou fora da faixa de T é um erro,
+// em vez de virar outro número na conversão: "max": 49.5 para int não é
+// truncado para 49. Para float32 o limite é arredondado para o float32 mais
+// próximo, mas um valor que não cabe em float32 também é um erro.
+func RegrasDe[T Numero](limites Limites, campo string) ([]Regra[T], error) {
+	l, ok := limites[campo]
+	if !ok {
+		return nil, nil
+	}
+	var regras []Regra[T]
+	if l.Min != nil {
+		minimo, err := converterLimite[T](campo, "min", *l.Min)
+		if err != nil {
+			return nil, err
+		}
+		regras = append(regras, Min(minimo))
+	}
+	if l.Max != nil {
+		maximo, err := converterLimite[T](campo, "max", *l.Max)
+		if err != nil {
+			return nil, err
+		}
+		regras = append(regras, Max(maximo))
+	}
+	return regras, nil
+}
+
+// converterLimite converte o limite para T. A conversão de float para
+// inteiro fora da faixa não entra em pânico, mas o resultado depende da
+// plataforma, então a faixa é conferida antes.
+func converterLimite[T Numero](campo, nome string, limite float64) (T, error) {
+	tipo := reflect.TypeFor[T]()
+	bits := tipo.Bits()
+	var minimo, maximo float64 // maximo é exclusivo
+	switch tipo.Kind() {
+	case reflect.Float32, reflect.Float64:
+		convertido := T(limite)
+		if math.IsInf(float64(convertido), 0) && !math.IsInf(limite, 0) {
+			return 0, fmt.Errorf("limites: campo %q: %s %v fora da faixa de %s", campo, nome, limite, tipo)
+		}
+		return convertido, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		minimo, maximo = 0, math.Ldexp(1, bits)
+	default:
+		minimo, maximo = -math.Ldexp(1, bits-1), math.Ldexp(1, bits-1)
+	}
+	if limite != math.Trunc(limite) {
+		return 0, fmt.Errorf("limites: campo %q: %s %v não é inteiro, como %s exige", campo, nome, limite, tipo)
+	}
+	if limite < minimo || limite >= maximo {
+		return 0, fmt.Errorf("limites: campo %q: %s %v fora da faixa de %s", campo, nome, limite, tipo)
+	}
+	return T(limite), nil
+}
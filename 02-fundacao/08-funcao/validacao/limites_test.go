@@ -0,0 +1,63 @@
+package validacao
+
+import (
+	"math"
+	"testing"
+)
+
+func limitesDe(minimo, maximo float64) Limites {
+	return Limites{"campo": {Min: &minimo, Max: &maximo}}
+}
+
+func TestRegrasDeLimitesExatos(t *testing.T) {
+	regras, err := RegrasDe[int](limitesDe(-10, 49), "campo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Todas(regras...)("campo", 49); err != nil {
+		t.Errorf("49 com max 49: %v", err)
+	}
+	if err := Todas(regras...)("campo", 50); err == nil {
+		t.Error("50 com max 49 passou")
+	}
+
+	if _, err := RegrasDe[uint8](limitesDe(0, 255), "campo"); err != nil {
+		t.Errorf("uint8 de 0 a 255: %v", err)
+	}
+	if _, err := RegrasDe[int8](limitesDe(-128, 127), "campo"); err != nil {
+		t.Errorf("int8 de -128 a 127: %v", err)
+	}
+	if regras, err := RegrasDe[int](Limites{}, "campo"); err != nil || regras != nil {
+		t.Errorf("campo sem limites = %v, %v; quero nil, nil", regras, err)
+	}
+}
+
+func TestRegrasDeRejeitaConversaoInexata(t *testing.T) {
+	casos := []struct {
+		nome   string
+		regras func() error
+	}{
+		{"fração em int", func() error { _, err := RegrasDe[int](limitesDe(0, 49.5), "campo"); return err }},
+		{"negativo em uint", func() error { _, err := RegrasDe[uint](limitesDe(-1, 10), "campo"); return err }},
+		{"acima de uint8", func() error { _, err := RegrasDe[uint8](limitesDe(0, 256), "campo"); return err }},
+		{"abaixo de int8", func() error { _, err := RegrasDe[int8](limitesDe(-129, 0), "campo"); return err }},
+		{"2^63 em int64", func() error { _, err := RegrasDe[int64](limitesDe(0, math.Ldexp(1, 63)), "campo"); return err }},
+		{"2^64 em uint64", func() error { _, err := RegrasDe[uint64](limitesDe(0, math.Ldexp(1, 64)), "campo"); return err }},
+		{"acima de float32", func() error { _, err := RegrasDe[float32](limitesDe(0, 1e39), "campo"); return err }},
+	}
+	for _, c := range casos {
+		if err := c.regras(); err == nil {
+			t.Errorf("%s: RegrasDe aceitou", c.nome)
+		}
+	}
+}
+
+func TestRegrasDeFloat32Arredonda(t *testing.T) {
+	regras, err := RegrasDe[float32](limitesDe(0, 0.1), "campo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Todas(regras...)("campo", float32(0.1)); err != nil {
+		t.Errorf("0.1 com max 0.1: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+// Package validacao tem regras de validação que se combinam (Max, Min,
+// Intervalo, predicados próprios), erros tipados que dizem qual campo falhou,
+// contra qual limite e com qual valor, e um Validador que junta todas as
+// violações em vez de parar na primeira.
+package validacao
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Regra confere um valor de um campo e devolve nil ou o erro da violação.
+type Regra[T any] func(campo string, valor T) error
+
+// ErrLimite é a violação de um limite numérico. Use errors.As com o mesmo T
+// do valor validado:
+//
+//	var limite *validacao.ErrLimite[int]
+//	if errors.As(err, &limite) { ... limite.Campo, limite.Limite, limite.Valor ... }
+type ErrLimite[T cmp.Ordered] struct {
+	Campo  string
+	Regra  string // "max" ou "min"
+	Limite T
+	Valor  T
+}
+
+func (e *ErrLimite[T]) Error() string {
+	if e.Regra == "min" {
+		return fmt.Sprintf("%s: %v abaixo do mínimo %v", e.Campo, e.Valor, e.Limite)
+	}
+	return fmt.Sprintf("%s: %v acima do máximo %v", e.Campo, e.Valor, e.Limite)
+}
+
+// ErrPredicado é a violação de uma regra feita com Predicado.
+type ErrPredicado struct {
+	Campo     string
+	Descricao string
+	Valor     any
+}
+
+func (e *ErrPredicado) Error() string {
+	return fmt.Sprintf("%s: %v não satisfaz %q", e.Campo, e.Valor, e.Descricao)
+}
+
+// Max aceita valores menores ou iguais ao limite.
+func Max[T cmp.Ordered](limite T) Regra[T] {
+	return func(campo string, valor T) error {
+		if valor > limite {
+			return &ErrLimite[T]{Campo: campo, Regra: "max", Limite: limite, Valor: valor}
+		}
+		return nil
+	}
+}
+
+// Min aceita valores maiores ou iguais ao limite.
+func Min[T cmp.Ordered](limite T) Regra[T] {
+	return func(campo string, valor T) error {
+		if valor < limite {
+			return &ErrLimite[T]{Campo: campo, Regra: "min", Limite: limite, Valor: valor}
+		}
+		return nil
+	}
+}
+
+// Intervalo aceita valores em [minimo, maximo].
+func Intervalo[T cmp.Ordered](minimo, maximo T) Regra[T] {
+	return Todas(Min(minimo), Max(maximo))
+}
+
+// Predicado transforma uma função qualquer em regra. A descrição aparece na
+// mensagem de erro e deve dizer o que se espera ("par", "não vazio").
+func Predicado[T any](descricao string, ok func(T) bool) Regra[T] {
+	return func(campo string, valor T) error {
+		if !ok(valor) {
+			return &ErrPredicado{Campo: campo, Descricao: descricao, Valor: valor}
+		}
+		return nil
+	}
+}
+
+// Todas aplica todas as regras e junta as violações.
+func Todas[T any](regras ...Regra[T]) Regra[T] {
+	return func(campo string, valor T) error {
+		var violacoes Violacoes
+		for _, r := range regras {
+			violacoes = violacoes.adicionar(r(campo, valor))
+		}
+		return violacoes.Err()
+	}
+}
+
+// Violacoes junta vários erros de validação. Unwrap devolve todos, então
+// errors.Is e errors.As encontram qualquer um deles.
+type Violacoes []error
+
+func (v Violacoes) Error() string {
+	mensagens := make([]string, len(v))
+	for i, err := range v {
+		mensagens[i] = err.Error()
+	}
+	return strings.Join(mensagens, "; ")
+}
+
+func (v Violacoes) Unwrap() []error {
+	return v
+}
+
+// Err devolve nil se não houver violações; senão, as próprias Violacoes.
+func (v Violacoes) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+// adicionar achata Violacoes aninhadas, para que o resultado de Todas dentro
+// de outra Todas continue sendo uma lista só.
+func (v Violacoes) adicionar(err error) Violacoes {
+	if err == nil {
+		return v
+	}
+	var aninhadas Violacoes
+	if errors.As(err, &aninhadas) {
+		return append(v, aninhadas...)
+	}
+	return append(v, err)
+}
+
+// Validador acumula as violações de vários campos.
+//
+//	var v validacao.Validador
+//	validacao.Checar(&v, "idade", idade, validacao.Min(0))
+//	validacao.Checar(&v, "nome", nome, validacao.Predicado("não vazio", func(s string) bool { return s != "" }))
+//	return v.Err()
+type Validador struct {
+	violacoes Violacoes
+}
+
+// Checar aplica as regras ao valor do campo. É uma função, e não um método,
+// porque métodos em Go não podem ter parâmetros de tipo.
+func Checar[T any](v *Validador, campo string, valor T, regras ...Regra[T]) {
+	for _, r := range regras {
+		v.violacoes = v.violacoes.adicionar(r(campo, valor))
+	}
+}
+
+// Err devolve as violações acumuladas, ou nil.
+func (v *Validador) Err() error {
+	return v.violacoes.Err()
+}
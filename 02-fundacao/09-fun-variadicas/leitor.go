@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Formato é como os números estão escritos na entrada.
+type Formato int
+
+const (
+	// FormatoAuto olha o primeiro caractere: '[' é JSON; senão, uma vírgula
+	// na primeira linha indica CSV; senão, um número por linha.
+	FormatoAuto Formato = iota
+	FormatoLinhas
+	FormatoCSV
+	FormatoJSON
+)
+
+func (f Formato) String() string {
+	switch f {
+	case FormatoLinhas:
+		return "linhas"
+	case FormatoCSV:
+		return "csv"
+	case FormatoJSON:
+		return "json"
+	}
+	return "auto"
+}
+
+// ParseFormato aceita os nomes devolvidos por String.
+func ParseFormato(nome string) (Formato, error) {
+	for _, f := range []Formato{FormatoAuto, FormatoLinhas, FormatoCSV, FormatoJSON} {
+		if f.String() == nome {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("formato desconhecido %q (use auto, linhas, csv ou json)", nome)
+}
+
+const (
+	// maxMalformadasGuardadas limita quantas linhas ruins ficam na memória;
+	// as demais são só contadas, para a memória não crescer com a entrada.
+	maxMalformadasGuardadas = 20
+	// maxTamanhoLinha é o maior trecho de uma linha guardado para parsear.
+	// Linhas maiores são lidas até o fim e descartadas como malformadas.
+	maxTamanhoLinha = 4096
+	// maxTamanhoRegistro limita quanto se lê de um registro CSV. O
+	// csv.Reader monta o registro inteiro na memória antes de devolvê-lo, e
+	// um campo entre aspas pode atravessar quantas linhas quiser; passado o
+	// limite, a leitura para com errRegistroLongo.
+	maxTamanhoRegistro = 1 << 20
+)
+
+// ErrLinha é um valor que não pôde ser lido como inteiro. A leitura segue
+// depois dele.
+type ErrLinha struct {
+	Linha  int
+	Coluna int // campo (CSV), a partir de 1; zero quando não se aplica
+	Texto  string
+	Err    error
+}
+
+func (e *ErrLinha) Error() string {
+	if e.Coluna > 0 {
+		return fmt.Sprintf("linha %d, coluna %d: %q: %v", e.Linha, e.Coluna, e.Texto, e.Err)
+	}
+	return fmt.Sprintf("linha %d: %q: %v", e.Linha, e.Texto, e.Err)
+}
+
+func (e *ErrLinha) Unwrap() error {
+	return e.Err
+}
+
+var (
+	errNaoInteiro    = errors.New("não é um número inteiro")
+	errLinhaLonga    = errors.New("linha longa demais")
+	errForaDoLimite  = errors.New("fora do intervalo de int")
+	errRegistroLongo = errors.New("registro CSV maior que 1 MiB")
+)
+
+// Leitor lê números de um io.Reader sem carregar a entrada inteira: a
+// memória usada não depende do tamanho do arquivo.
+//
+//	leitor := NovoLeitor(arquivo, FormatoAuto)
+//	for n := range leitor.Numeros() { ... }
+//	if err := leitor.Err(); err != nil { ... }
+//	for _, ruim := range leitor.Malformadas() { ... }
+type Leitor struct {
+	r       *bufio.Reader
+	formato Formato
+
+	malformadas      []*ErrLinha
+	totalMalformadas int
+	err              error
+}
+
+func NovoLeitor(r io.Reader, formato Formato) *Leitor {
+	return &Leitor{r: bufio.NewReaderSize(r, 64*1024), formato: formato}
+}
+
+// Numeros percorre os inteiros válidos da entrada, pulando (e anotando) os
+// malformados. Só pode ser percorrido uma vez. Ao final, confira Err.
+func (l *Leitor) Numeros() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		formato := l.formato
+		if formato == FormatoAuto {
+			formato = l.detectarFormato()
+		}
+		switch formato {
+		case FormatoJSON:
+			l.err = l.lerJSON(yield)
+		case FormatoCSV:
+			l.err = l.lerCSV(yield)
+		default:
+			l.err = l.lerLinhas(yield)
+		}
+	}
+}
+
+// Err é o erro que interrompeu a leitura (falha de E/S, JSON com sintaxe
+// quebrada ou registro CSV acima de 1 MiB); valores malformados não contam
+// aqui.
+func (l *Leitor) Err() error {
+	return l.err
+}
+
+// Malformadas devolve as primeiras linhas ruins encontradas.
+func (l *Leitor) Malformadas() []*ErrLinha {
+	return l.malformadas
+}
+
+// TotalMalformadas conta todas as linhas ruins, inclusive as não guardadas.
+func (l *Leitor) TotalMalformadas() int {
+	return l.totalMalformadas
+}
+
+func (l *Leitor) anotar(e *ErrLinha) {
+	l.totalMalformadas++
+	if len(l.malformadas) < maxMalformadasGuardadas {
+		l.malformadas = append(l.malformadas, e)
+	}
+}
+
+func (l *Leitor) detectarFormato() Formato {
+	// Peek não consome nada; 4 KiB bastam para ver a primeira linha na
+	// prática, e se não bastarem, "linhas" é um palpite seguro.
+	inicio, _ := l.r.Peek(4096)
+	texto := bytes.TrimLeft(inicio, " \t\r\n\ufeff")
+	if len(texto) > 0 && texto[0] == '[' {
+		return FormatoJSON
+	}
+	primeira, _, _ := bytes.Cut(texto, []byte("\n"))
+	if bytes.ContainsRune(primeira, ',') {
+		return FormatoCSV
+	}
+	return FormatoLinhas
+}
+
+func (l *Leitor) lerLinhas(yield func(int) bool) error {
+	for linha := 1; ; linha++ {
+		texto, longa, err := l.lerLinha()
+		if err == io.EOF && texto == nil {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		campo := strings.TrimSpace(string(texto))
+		switch {
+		case longa:
+			l.anotar(&ErrLinha{Linha: linha, Texto: campo[:min(len(campo), 40)] + "...", Err: errLinhaLonga})
+		case campo == "":
+			// linhas em branco são ignoradas
+		default:
+			n, erro := converter(campo)
+			if erro != nil {
+				l.anotar(&ErrLinha{Linha: linha, Texto: campo, Err: erro})
+			} else if !yield(n) {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// lerLinha devolve a próxima linha sem o \n. De linhas maiores que
+// maxTamanhoLinha, só o começo é devolvido, com longa = true; o resto é
+// lido e jogado fora.
+func (l *Leitor) lerLinha() (linha []byte, longa bool, err error) {
+	for {
+		pedaco, err := l.r.ReadSlice('\n')
+		if len(linha)+len(pedaco) > maxTamanhoLinha {
+			longa = true
+		}
+		linha = append(linha, pedaco[:min(len(pedaco), maxTamanhoLinha-len(linha))]...)
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF && len(linha) == 0 && !longa:
+			return nil, false, io.EOF
+		case err != nil && err != io.EOF:
+			return nil, false, err
+		}
+		return bytes.TrimSuffix(linha, []byte("\n")), longa, err
+	}
+}
+
+func (l *Leitor) lerCSV(yield func(int) bool) error {
+	limitador := &limitadorRegistro{r: l.r}
+	leitor := csv.NewReader(limitador)
+	leitor.FieldsPerRecord = -1
+	leitor.ReuseRecord = true
+	leitor.TrimLeadingSpace = true
+	for {
+		limitador.inicio = leitor.InputOffset()
+		registro, err := leitor.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var erroCSV *csv.ParseError
+		if errors.As(err, &erroCSV) {
+			l.anotar(&ErrLinha{Linha: erroCSV.Line, Coluna: erroCSV.Column, Err: erroCSV.Err})
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("csv: %w", err)
+		}
+		for i, campo := range registro {
+			campo = strings.TrimSpace(campo)
+			if campo == "" {
+				continue
+			}
+			n, erro := converter(campo)
+			if erro != nil {
+				linha, _ := leitor.FieldPos(i)
+				l.anotar(&ErrLinha{Linha: linha, Coluna: i + 1, Texto: campo, Err: erro})
+				continue
+			}
+			if !yield(n) {
+				return nil
+			}
+		}
+	}
+}
+
+// limitadorRegistro fica entre o csv.Reader e a entrada e recusa ler mais
+// de maxTamanhoRegistro bytes a partir do início do registro atual. A
+// contagem inclui o que o csv.Reader já leu adiantado para o buffer dele, de
+// modo que o limite efetivo de um registro fica até 4 KiB abaixo disso.
+type limitadorRegistro struct {
+	r      io.Reader
+	lidos  int64
+	inicio int64
+}
+
+func (l *limitadorRegistro) Read(p []byte) (int, error) {
+	resta := l.inicio + maxTamanhoRegistro - l.lidos
+	if resta <= 0 {
+		return 0, errRegistroLongo
+	}
+	n, err := l.r.Read(p[:min(int64(len(p)), resta)])
+	l.lidos += int64(n)
+	return n, err
+}
+
+// lerJSON percorre um array JSON elemento a elemento com json.Decoder, sem
+// decodificar o array inteiro.
+func (l *Leitor) lerJSON(yield func(int) bool) error {
+	linhas := &contadorLinhas{r: l.r}
+	decodificador := json.NewDecoder(linhas)
+	decodificador.UseNumber()
+
+	abertura, err := decodificador.Token()
+	if err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+	if abertura != json.Delim('[') {
+		return fmt.Errorf("json: esperado um array, encontrado %v", abertura)
+	}
+	for decodificador.More() {
+		var bruto json.RawMessage
+		if err := decodificador.Decode(&bruto); err != nil {
+			return fmt.Errorf("json, linha %d: %w", linhas.linhaEm(decodificador.InputOffset()), err)
+		}
+		fim := decodificador.InputOffset()
+		n, erro := converter(string(bruto))
+		if erro != nil {
+			inicio := fim - int64(len(bruto))
+			texto := string(bruto)
+			if len(texto) > 40 {
+				texto = texto[:40] + "..."
+			}
+			l.anotar(&ErrLinha{Linha: linhas.linhaEm(inicio), Texto: texto, Err: erro})
+		}
+		// As quebras antes do fim do elemento já não serão consultadas; sem
+		// descartá-las aqui, a fila guardaria todas as do arquivo.
+		linhas.descartarAte(fim)
+		if erro == nil && !yield(n) {
+			return nil
+		}
+	}
+	if _, err := decodificador.Token(); err != nil {
+		return fmt.Errorf("json, linha %d: %w", linhas.linhaEm(decodificador.InputOffset()), err)
+	}
+	return nil
+}
+
+// contadorLinhas anota onde estão as quebras de linha lidas pelo
+// json.Decoder para traduzir posições em números de linha. Como o
+// decodificador só olha para a frente, as quebras já ultrapassadas viram
+// só um contador (veja descartarAte) e a fila fica do tamanho do buffer do
+// decodificador.
+type contadorLinhas struct {
+	r       io.Reader
+	lidos   int64
+	quebras []int64
+	linha   int
+}
+
+func (c *contadorLinhas) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	for i, b := range p[:n] {
+		if b == '\n' {
+			c.quebras = append(c.quebras, c.lidos+int64(i))
+		}
+	}
+	c.lidos += int64(n)
+	return n, err
+}
+
+// linhaEm devolve a linha (a partir de 1) da posição. As posições pedidas
+// nunca diminuem.
+func (c *contadorLinhas) linhaEm(posicao int64) int {
+	c.descartarAte(posicao)
+	return c.linha + 1
+}
+
+// descartarAte troca as quebras anteriores à posição pela contagem delas.
+func (c *contadorLinhas) descartarAte(posicao int64) {
+	i := 0
+	for i < len(c.quebras) && c.quebras[i] < posicao {
+		i++
+	}
+	c.linha += i
+	c.quebras = c.quebras[i:]
+}
+
+// converter aceita só inteiros decimais que cabem em int.
+func converter(texto string) (int, error) {
+	n, err := strconv.ParseInt(texto, 10, strconv.IntSize)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, errForaDoLimite
+		}
+		return 0, errNaoInteiro
+	}
+	return int(n), nil
+}
+
+// ErrEstouro diz que a soma passou do maior (ou menor) int.
+type ErrEstouro struct {
+	Parcial int // soma até antes do valor que estourou
+	Valor   int
+	Posicao int // quantos valores já tinham sido somados
+}
+
+func (e *ErrEstouro) Error() string {
+	return fmt.Sprintf("a soma estoura int: %d + %d (depois de %d valores)", e.Parcial, e.Valor, e.Posicao)
+}
+
+// SomarSeq soma a sequência e para no primeiro estouro.
+func SomarSeq(numeros iter.Seq[int]) (soma, quantidade int, err error) {
+	for n := range numeros {
+		if (n > 0 && soma > math.MaxInt-n) || (n < 0 && soma < math.MinInt-n) {
+			return soma, quantidade, &ErrEstouro{Parcial: soma, Valor: n, Posicao: quantidade}
+		}
+		soma += n
+		quantidade++
+	}
+	return soma, quantidade, nil
+}
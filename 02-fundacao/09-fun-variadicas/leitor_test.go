@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDetectarFormato(t *testing.T) {
+	for _, c := range []struct {
+		entrada string
+		quero   Formato
+	}{
+		{"[1, 2, 3]", FormatoJSON},
+		{" \n\t[1]", FormatoJSON},
+		{"\ufeff[1]", FormatoJSON},
+		{"1,2,3\n4", FormatoCSV},
+		{"1\n2,3\n", FormatoLinhas},
+		{"1\n2\n", FormatoLinhas},
+		{"", FormatoLinhas},
+	} {
+		if got := NovoLeitor(strings.NewReader(c.entrada), FormatoAuto).detectarFormato(); got != c.quero {
+			t.Errorf("detectarFormato(%q) = %v, quero %v", c.entrada, got, c.quero)
+		}
+	}
+}
+
+// lerTudo percorre a entrada e devolve os números válidos e as linhas ruins.
+func lerTudo(t *testing.T, entrada string, formato Formato) ([]int, *Leitor) {
+	t.Helper()
+	leitor := NovoLeitor(strings.NewReader(entrada), formato)
+	numeros := slices.Collect(leitor.Numeros())
+	return numeros, leitor
+}
+
+func TestMalformadasComPosicao(t *testing.T) {
+	type ruim struct {
+		linha, coluna int
+		texto         string
+		err           error
+	}
+	for _, c := range []struct {
+		nome    string
+		entrada string
+		formato Formato
+		numeros []int
+		ruins   []ruim
+	}{
+		{
+			nome:    "linhas",
+			entrada: "1\n x \n\n3\n99999999999999999999\n-4",
+			formato: FormatoAuto,
+			numeros: []int{1, 3, -4},
+			ruins: []ruim{
+				{2, 0, "x", errNaoInteiro},
+				{5, 0, "99999999999999999999", errForaDoLimite},
+			},
+		},
+		{
+			nome:    "csv",
+			entrada: "1,2\n3, x ,4\n\"5\n6\",7\n8,2.5\n",
+			formato: FormatoAuto,
+			numeros: []int{1, 2, 3, 4, 7, 8},
+			ruins: []ruim{
+				{2, 2, "x", errNaoInteiro},
+				{3, 1, "5\n6", errNaoInteiro},
+				{5, 2, "2.5", errNaoInteiro},
+			},
+		},
+		{
+			nome:    "csv com aspas quebradas",
+			entrada: "1,2\n3,\"a\"b\n4\n",
+			formato: FormatoCSV,
+			numeros: []int{1, 2, 4},
+			ruins:   []ruim{{2, 5, "", csv.ErrQuote}}, // a aspa depois do "a"
+		},
+		{
+			nome:    "json",
+			entrada: "[1,\n  \"x\",\n  2.5, 3,\n  {\"a\": 1}\n]",
+			formato: FormatoAuto,
+			numeros: []int{1, 3},
+			ruins: []ruim{
+				{2, 0, `"x"`, errNaoInteiro},
+				{3, 0, "2.5", errNaoInteiro},
+				{4, 0, `{"a": 1}`, errNaoInteiro},
+			},
+		},
+	} {
+		t.Run(c.nome, func(t *testing.T) {
+			numeros, leitor := lerTudo(t, c.entrada, c.formato)
+			if err := leitor.Err(); err != nil {
+				t.Fatalf("Err() = %v", err)
+			}
+			if !slices.Equal(numeros, c.numeros) {
+				t.Errorf("números = %v, quero %v", numeros, c.numeros)
+			}
+			malformadas := leitor.Malformadas()
+			if len(malformadas) != len(c.ruins) || leitor.TotalMalformadas() != len(c.ruins) {
+				t.Fatalf("malformadas = %v (total %d), quero %d", malformadas, leitor.TotalMalformadas(), len(c.ruins))
+			}
+			for i, quero := range c.ruins {
+				e := malformadas[i]
+				if e.Linha != quero.linha || e.Coluna != quero.coluna || e.Texto != quero.texto || !errors.Is(e, quero.err) {
+					t.Errorf("malformada %d = %v, quero linha %d coluna %d %q: %v", i, e, quero.linha, quero.coluna, quero.texto, quero.err)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONComSintaxeQuebrada(t *testing.T) {
+	for _, entrada := range []string{"[1, 2", "[1 2]", `{"a": 1}`} {
+		_, leitor := lerTudo(t, entrada, FormatoJSON)
+		if leitor.Err() == nil {
+			t.Errorf("Err() = nil para %q, quero um erro", entrada)
+		}
+	}
+}
+
+func TestLinhaLonga(t *testing.T) {
+	longa := strings.Repeat("9", maxTamanhoLinha+1000)
+	numeros, leitor := lerTudo(t, "1\n"+longa+"\n2\n"+longa, FormatoLinhas)
+	if err := leitor.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(numeros, []int{1, 2}) {
+		t.Errorf("números = %v, quero [1 2]", numeros)
+	}
+	malformadas := leitor.Malformadas()
+	if len(malformadas) != 2 {
+		t.Fatalf("malformadas = %v, quero as linhas 2 e 4", malformadas)
+	}
+	for i, linha := range []int{2, 4} {
+		e := malformadas[i]
+		if e.Linha != linha || !errors.Is(e, errLinhaLonga) || e.Texto != longa[:40]+"..." {
+			t.Errorf("malformada %d = %v, quero linha %d truncada em 40 caracteres", i, e, linha)
+		}
+	}
+}
+
+func TestMalformadasGuardadasComLimite(t *testing.T) {
+	entrada := strings.Repeat("x\n", maxMalformadasGuardadas+5) + "7\n"
+	numeros, leitor := lerTudo(t, entrada, FormatoLinhas)
+	if !slices.Equal(numeros, []int{7}) {
+		t.Errorf("números = %v, quero [7]", numeros)
+	}
+	if got := len(leitor.Malformadas()); got != maxMalformadasGuardadas {
+		t.Errorf("len(Malformadas()) = %d, quero %d", got, maxMalformadasGuardadas)
+	}
+	if got := leitor.TotalMalformadas(); got != maxMalformadasGuardadas+5 {
+		t.Errorf("TotalMalformadas() = %d, quero %d", got, maxMalformadasGuardadas+5)
+	}
+}
+
+func TestRegistroCSVLongoInterrompeLeitura(t *testing.T) {
+	// Um campo entre aspas que não fecha faria o csv.Reader guardar a
+	// entrada inteira na memória.
+	entrada := "1,2\n\"" + strings.Repeat("9\n", maxTamanhoRegistro)
+	numeros, leitor := lerTudo(t, entrada, FormatoCSV)
+	if !errors.Is(leitor.Err(), errRegistroLongo) {
+		t.Fatalf("Err() = %v, quero errRegistroLongo", leitor.Err())
+	}
+	if !slices.Equal(numeros, []int{1, 2}) {
+		t.Errorf("números = %v, quero [1 2] (os de antes do registro longo)", numeros)
+	}
+
+	// Registros grandes, mas dentro do limite, passam.
+	grande := strings.Repeat("1,", maxTamanhoRegistro/4) + "1\n"
+	numeros, leitor = lerTudo(t, grande+grande, FormatoCSV)
+	if err := leitor.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(numeros) != 2*(maxTamanhoRegistro/4+1) {
+		t.Errorf("%d números, quero %d", len(numeros), 2*(maxTamanhoRegistro/4+1))
+	}
+}
+
+func TestPararNoMeio(t *testing.T) {
+	for _, c := range []struct {
+		formato Formato
+		entrada string
+	}{
+		{FormatoLinhas, "1\n2\n3\n4\n"},
+		{FormatoCSV, "1,2\n3,4\n"},
+		{FormatoJSON, "[1, 2, 3, 4]"},
+	} {
+		leitor := NovoLeitor(strings.NewReader(c.entrada), c.formato)
+		var lidos []int
+		// Um yield chamado depois do break faria o range entrar em pânico.
+		for n := range leitor.Numeros() {
+			lidos = append(lidos, n)
+			if len(lidos) == 2 {
+				break
+			}
+		}
+		if !slices.Equal(lidos, []int{1, 2}) {
+			t.Errorf("%v: lidos = %v, quero [1 2]", c.formato, lidos)
+		}
+		if err := leitor.Err(); err != nil {
+			t.Errorf("%v: Err() = %v depois do break", c.formato, err)
+		}
+	}
+}
+
+func TestSomarSeqEstouro(t *testing.T) {
+	for _, c := range []struct {
+		valores []int
+		quero   ErrEstouro
+	}{
+		{[]int{math.MaxInt - 1, 1, 1}, ErrEstouro{Parcial: math.MaxInt, Valor: 1, Posicao: 2}},
+		{[]int{-5, math.MinInt + 5, -1}, ErrEstouro{Parcial: math.MinInt, Valor: -1, Posicao: 2}},
+		{[]int{1, math.MaxInt}, ErrEstouro{Parcial: 1, Valor: math.MaxInt, Posicao: 1}},
+	} {
+		soma, quantidade, err := SomarSeq(slices.Values(c.valores))
+		var estouro *ErrEstouro
+		if !errors.As(err, &estouro) {
+			t.Errorf("SomarSeq(%v) err = %v, quero *ErrEstouro", c.valores, err)
+			continue
+		}
+		if *estouro != c.quero || soma != c.quero.Parcial || quantidade != c.quero.Posicao {
+			t.Errorf("SomarSeq(%v) = %d, %d, %+v; quero %+v", c.valores, soma, quantidade, *estouro, c.quero)
+		}
+	}
+
+	soma, quantidade, err := SomarSeq(slices.Values([]int{math.MaxInt, math.MinInt, -1}))
+	if err != nil || soma != -2 || quantidade != 3 {
+		t.Errorf("SomarSeq sem estouro = %d, %d, %v; quero -2, 3, nil", soma, quantidade, err)
+	}
+}
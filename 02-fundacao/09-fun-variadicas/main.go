@@ -1,11 +1,56 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+var (
+	ErrArquivoAusente     = erros.Definir("soma.arquivo_ausente", erros.NaoEncontrado)
+	ErrEntradaIlegivel    = erros.Definir("soma.entrada_ilegivel", erros.Validacao)
+	ErrValoresMalformados = erros.Definir("soma.valores_malformados", erros.Validacao)
+	ErrSomaEstourou       = erros.Definir("soma.estouro", erros.Validacao)
+	ErrSaida              = erros.Definir("soma.saida", erros.Interno)
 )
 
 func main() {
-	fmt.Println(sum(1, 34, 43, 54))
+	formato := flag.String("formato", "auto", "formato da entrada: auto, linhas, csv ou json")
+	estrito := flag.Bool("estrito", false, "falha (código 65) se houver valores malformados")
+	gerar := flag.Int("gerar", 0, "escreve N números, um por linha, na saída padrão e sai")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "uso: go run . [-formato f] [-estrito] [arquivo|-]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *gerar > 0 {
+		if err := gerarNumeros(os.Stdout, *gerar); err != nil {
+			sair(ErrSaida.Envolver(err, nil))
+		}
+		return
+	}
+	if flag.NArg() == 0 {
+		fmt.Println(sum(1, 34, 43, 54))
+		return
+	}
+
+	f, err := ParseFormato(*formato)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := somarArquivo(flag.Arg(0), f, *estrito); err != nil {
+		sair(err)
+	}
 }
 
 func sum(numeros ...int) int {
@@ -15,3 +60,59 @@ func sum(numeros ...int) int {
 	}
 	return total
 }
+
+// somarArquivo soma o arquivo (ou a entrada padrão, com "-") sem carregá-lo
+// na memória e imprime o resultado e as linhas malformadas.
+func somarArquivo(caminho string, formato Formato, estrito bool) error {
+	var entrada io.Reader = os.Stdin
+	if caminho != "-" {
+		arquivo, err := os.Open(caminho)
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrArquivoAusente.Envolver(err, erros.Params{"arquivo": caminho})
+		}
+		if err != nil {
+			return ErrEntradaIlegivel.Envolver(err, erros.Params{"arquivo": caminho})
+		}
+		defer arquivo.Close()
+		entrada = arquivo
+	}
+
+	leitor := NovoLeitor(entrada, formato)
+	soma, quantidade, err := SomarSeq(leitor.Numeros())
+	for _, ruim := range leitor.Malformadas() {
+		fmt.Fprintln(os.Stderr, "malformado:", ruim)
+	}
+	if resto := leitor.TotalMalformadas() - len(leitor.Malformadas()); resto > 0 {
+		fmt.Fprintf(os.Stderr, "malformado: mais %d valores\n", resto)
+	}
+	if err != nil {
+		return ErrSomaEstourou.Envolver(err, erros.Params{"arquivo": caminho})
+	}
+	if err := leitor.Err(); err != nil {
+		return ErrEntradaIlegivel.Envolver(err, erros.Params{"arquivo": caminho})
+	}
+
+	fmt.Printf("soma=%d valores=%d malformados=%d\n", soma, quantidade, leitor.TotalMalformadas())
+	if estrito && leitor.TotalMalformadas() > 0 {
+		return ErrValoresMalformados.Novo(erros.Params{"arquivo": caminho, "total": leitor.TotalMalformadas()})
+	}
+	return nil
+}
+
+func gerarNumeros(w io.Writer, n int) error {
+	saida := bufio.NewWriter(w)
+	var buf []byte
+	for i := range n {
+		buf = strconv.AppendInt(buf[:0], int64(i%1000), 10)
+		buf = append(buf, '\n')
+		if _, err := saida.Write(buf); err != nil {
+			return err
+		}
+	}
+	return saida.Flush()
+}
+
+func sair(err error) {
+	fmt.Fprintln(os.Stderr, "erro:", err)
+	os.Exit(erros.CodigoSaida(err))
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// OpcoesReduce ajusta Reduce. O valor zero usa os padrões.
+type OpcoesReduce struct {
+	// Trabalhadores é o máximo de goroutines; zero usa GOMAXPROCS.
+	Trabalhadores int
+	// Bloco é quantos elementos cada goroutine pega por vez; zero usa
+	// BlocoPadrao. Entradas que cabem num bloco são reduzidas sem goroutines.
+	Bloco int
+}
+
+// BlocoPadrao é grande o bastante para que o custo de coordenar as
+// goroutines fique pequeno perto do trabalho de cada bloco.
+const BlocoPadrao = 32 * 1024
+
+// Reduce combina os valores com op em paralelo:
+//
+//	soma, err := Reduce(ctx, valores, 0, func(a, b int) int { return a + b }, OpcoesReduce{})
+//
+// op precisa ser associativa e neutro, o elemento neutro dela (0 para soma,
+// 1 para produto): cada bloco começa de neutro e os resultados dos blocos
+// são combinados depois.
+//
+// Os blocos dependem só de len(valores) e de Bloco, não de quantas
+// goroutines rodaram nem da ordem em que terminaram, e os resultados
+// parciais são combinados na ordem dos blocos. Com inteiros o resultado é
+// sempre o mesmo de sum, inclusive quando a soma dá a volta; com float o
+// arredondamento pode diferir do laço sequencial, mas se repete a cada
+// execução com o mesmo Bloco, com qualquer número de Trabalhadores.
+//
+// Com o contexto cancelado, Reduce para de pegar blocos novos e devolve
+// ctx.Err(). Um panic em op é repassado a quem chamou Reduce.
+func Reduce[T any](ctx context.Context, valores []T, neutro T, op func(T, T) T, opcoes OpcoesReduce) (T, error) {
+	reduzirBloco := func(parte []T) T {
+		acumulado := neutro
+		for _, v := range parte {
+			acumulado = op(acumulado, v)
+		}
+		return acumulado
+	}
+	return reduzirEmBlocos(ctx, valores, neutro, op, reduzirBloco, opcoes)
+}
+
+// reduzirEmBlocos faz o trabalho de Reduce, mas recebe à parte a função que
+// reduz um bloco inteiro. Assim SomarParalelo usa um laço com + em vez de
+// pagar uma chamada de função por elemento, e op só é chamada uma vez por
+// bloco, para combinar os parciais.
+func reduzirEmBlocos[T any](ctx context.Context, valores []T, neutro T, op func(T, T) T, reduzirBloco func([]T) T, opcoes OpcoesReduce) (T, error) {
+	bloco := opcoes.Bloco
+	if bloco <= 0 {
+		bloco = BlocoPadrao
+	}
+	blocos := (len(valores) + bloco - 1) / bloco
+	parte := func(i int) []T {
+		return valores[i*bloco : min((i+1)*bloco, len(valores))]
+	}
+	trabalhadores := opcoes.Trabalhadores
+	if trabalhadores <= 0 {
+		trabalhadores = runtime.GOMAXPROCS(0)
+	}
+	trabalhadores = min(trabalhadores, blocos)
+
+	// Sem goroutines: os mesmos blocos, na mesma ordem, conferindo o
+	// contexto entre um bloco e outro.
+	if trabalhadores <= 1 {
+		resultado := neutro
+		for i := range blocos {
+			if err := ctx.Err(); err != nil {
+				return neutro, err
+			}
+			resultado = op(resultado, reduzirBloco(parte(i)))
+		}
+		return resultado, nil
+	}
+
+	var (
+		parciais = make([]T, blocos)
+		proximo  atomic.Int64
+		wg       sync.WaitGroup
+		panicOp  atomic.Pointer[any]
+	)
+	for range trabalhadores {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if p := recover(); p != nil {
+					panicOp.CompareAndSwap(nil, &p)
+				}
+			}()
+			for {
+				i := int(proximo.Add(1) - 1)
+				if i >= blocos || ctx.Err() != nil || panicOp.Load() != nil {
+					return
+				}
+				parciais[i] = reduzirBloco(parte(i))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if p := panicOp.Load(); p != nil {
+		panic(*p)
+	}
+	if err := ctx.Err(); err != nil {
+		return neutro, err
+	}
+	resultado := neutro
+	for _, parcial := range parciais {
+		resultado = op(resultado, parcial)
+	}
+	return resultado, nil
+}
+
+// Inteiro são os tipos em que a soma em paralelo dá exatamente o mesmo
+// resultado que a sequencial.
+type Inteiro interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// SomarParalelo é a versão paralela de sum.
+func SomarParalelo[T Inteiro](ctx context.Context, valores []T, opcoes OpcoesReduce) (T, error) {
+	somarBloco := func(parte []T) T {
+		var soma T
+		for _, v := range parte {
+			soma += v
+		}
+		return soma
+	}
+	return reduzirEmBlocos(ctx, valores, 0, func(a, b T) T { return a + b }, somarBloco, opcoes)
+}
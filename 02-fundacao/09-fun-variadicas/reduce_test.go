@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestReduceIgualASum confere Reduce e SomarParalelo contra sum em tamanhos
+// em volta de um bloco, com vários Trabalhadores e Blocos, inclusive com
+// somas que dão a volta.
+func TestReduceIgualASum(t *testing.T) {
+	aleatorio := rand.New(rand.NewSource(1))
+	pequenos := make([]int, 100_003)
+	for i := range pequenos {
+		pequenos[i] = aleatorio.Intn(2000) - 1000
+	}
+	// perto de MaxInt a soma dá a volta várias vezes
+	grandes := make([]int, 100_003)
+	for i := range grandes {
+		grandes[i] = math.MaxInt - aleatorio.Intn(1000)
+	}
+
+	ctx := context.Background()
+	for _, entrada := range []struct {
+		nome    string
+		valores []int
+	}{{"pequenos", pequenos}, {"grandes", grandes}} {
+		for _, n := range []int{0, 1, 1023, 1024, 1025, BlocoPadrao + 1, len(entrada.valores)} {
+			valores := entrada.valores[:n]
+			esperado := sum(valores...)
+			for _, trabalhadores := range []int{0, 1, 2, 3, 16} {
+				for _, bloco := range []int{0, 1, 7, 1024} {
+					if bloco == 1 && n > 2000 {
+						continue // um bloco por elemento só nos tamanhos pequenos
+					}
+					opcoes := OpcoesReduce{Trabalhadores: trabalhadores, Bloco: bloco}
+					nome := fmt.Sprintf("%s/n=%d/trabalhadores=%d/bloco=%d", entrada.nome, n, trabalhadores, bloco)
+					if got, err := Reduce(ctx, valores, 0, somar, opcoes); err != nil || got != esperado {
+						t.Errorf("%s: Reduce = %d, %v; sum = %d", nome, got, err, esperado)
+					}
+					if got, err := SomarParalelo(ctx, valores, opcoes); err != nil || got != esperado {
+						t.Errorf("%s: SomarParalelo = %d, %v; sum = %d", nome, got, err, esperado)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestSomarParaleloUint8DaAVolta(t *testing.T) {
+	valores := make([]uint8, 5000)
+	var esperado uint8
+	for i := range valores {
+		valores[i] = uint8(i*37 + 11)
+		esperado += valores[i]
+	}
+	for _, trabalhadores := range []int{1, 4} {
+		got, err := SomarParalelo(context.Background(), valores, OpcoesReduce{Trabalhadores: trabalhadores, Bloco: 100})
+		if err != nil || got != esperado {
+			t.Errorf("trabalhadores=%d: SomarParalelo = %d, %v; quero %d", trabalhadores, got, err, esperado)
+		}
+	}
+}
+
+func TestReduceCancelado(t *testing.T) {
+	valores := entradaBench(100_000)
+	for _, trabalhadores := range []int{1, 4} {
+		opcoes := OpcoesReduce{Trabalhadores: trabalhadores, Bloco: 1000}
+
+		// cancelado antes de começar
+		ctx, cancelar := context.WithCancel(context.Background())
+		cancelar()
+		if _, err := Reduce(ctx, valores, 0, somar, opcoes); !errors.Is(err, context.Canceled) {
+			t.Errorf("trabalhadores=%d: Reduce com ctx cancelado = %v, quero context.Canceled", trabalhadores, err)
+		}
+		if _, err := SomarParalelo(ctx, valores, opcoes); !errors.Is(err, context.Canceled) {
+			t.Errorf("trabalhadores=%d: SomarParalelo com ctx cancelado = %v, quero context.Canceled", trabalhadores, err)
+		}
+
+		// cancelado no meio: nenhum bloco novo começa depois
+		ctx, cancelar = context.WithCancel(context.Background())
+		chamadas := 0
+		_, err := Reduce(ctx, valores, 0, func(a, b int) int {
+			if trabalhadores == 1 {
+				chamadas++
+				if chamadas == 1500 {
+					cancelar()
+				}
+			} else {
+				cancelar()
+			}
+			return a + b
+		}, opcoes)
+		cancelar()
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("trabalhadores=%d: Reduce cancelado no meio = %v, quero context.Canceled", trabalhadores, err)
+		}
+		// dois blocos de 1000, mais uma combinação por bloco
+		if trabalhadores == 1 && chamadas != 2002 {
+			t.Errorf("op foi chamada %d vezes, quero 2002: depois de cancelar, só o bloco em andamento termina", chamadas)
+		}
+	}
+}
+
+func TestReduceRepassaPanic(t *testing.T) {
+	defer func() {
+		if p := recover(); p != "op falhou" {
+			t.Fatalf("panic = %v, quero o de op", p)
+		}
+	}()
+	Reduce(context.Background(), entradaBench(100_000), 0, func(a, b int) int {
+		if b == 999 {
+			panic("op falhou")
+		}
+		return a + b
+	}, OpcoesReduce{Trabalhadores: 4, Bloco: 1000})
+	t.Fatal("Reduce não repassou o panic")
+}
+
+// valoresBench é compartilhado pelos benchmarks para não alocar 10⁷
+// inteiros em cada um.
+var valoresBench []int
+
+func entradaBench(n int) []int {
+	if len(valoresBench) < n {
+		valoresBench = make([]int, n)
+		for i := range valoresBench {
+			valoresBench[i] = i % 1000
+		}
+	}
+	return valoresBench[:n]
+}
+
+func somar(a, b int) int { return a + b }
+
+func BenchmarkSoma(b *testing.B) {
+	ctx := context.Background()
+	for _, n := range []int{10_000, 100_000, 1_000_000, 10_000_000} {
+		valores := entradaBench(n)
+		b.Run(fmt.Sprintf("n=%d/sum", n), func(b *testing.B) {
+			for b.Loop() {
+				sum(valores...)
+			}
+		})
+		b.Run(fmt.Sprintf("n=%d/Reduce", n), func(b *testing.B) {
+			for b.Loop() {
+				Reduce(ctx, valores, 0, somar, OpcoesReduce{})
+			}
+		})
+		b.Run(fmt.Sprintf("n=%d/SomarParalelo", n), func(b *testing.B) {
+			for b.Loop() {
+				SomarParalelo(ctx, valores, OpcoesReduce{})
+			}
+		})
+	}
+}
+
+func BenchmarkBloco(b *testing.B) {
+	ctx := context.Background()
+	valores := entradaBench(1_000_000)
+	for _, bloco := range []int{1 << 10, 1 << 13, BlocoPadrao, 1 << 18} {
+		opcoes := OpcoesReduce{Bloco: bloco}
+		b.Run(fmt.Sprintf("bloco=%d/Reduce", bloco), func(b *testing.B) {
+			for b.Loop() {
+				Reduce(ctx, valores, 0, somar, opcoes)
+			}
+		})
+		b.Run(fmt.Sprintf("bloco=%d/SomarParalelo", bloco), func(b *testing.B) {
+			for b.Loop() {
+				SomarParalelo(ctx, valores, opcoes)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+// ErrChaveDesconhecida é retornado quando um registro foi cifrado com uma
+// chave que o provedor não conhece mais.
+var ErrChaveDesconhecida = erros.Definir("chaves.chave_desconhecida", erros.Interno).Sentinela("chave de criptografia desconhecida")
+
+// KeyProvider entrega as chaves usadas para cifrar os dados pessoais.
+//
+// A chave atual é usada para cifrar registros novos; as antigas continuam
+// disponíveis por id para ler o que ainda não foi rotacionado. A chave de
+// índice é usada no HMAC dos índices cegos e não muda na rotação, senão as
+// buscas deixariam de encontrar os registros antigos.
+type KeyProvider interface {
+	ChaveAtual() (id string, chave []byte, err error)
+	Chave(id string) ([]byte, error)
+	ChaveIndice() ([]byte, error)
+}
+
+// arquivoChaves é o formato do arquivo lido pelo ProvedorChavesArquivo.
+// As chaves são de 32 bytes (AES-256) codificadas em base64.
+type arquivoChaves struct {
+	Atual  string            `json:"atual"`
+	Chaves map[string]string `json:"chaves"`
+	Indice string            `json:"indice"`
+}
+
+// ProvedorChavesArquivo lê as chaves de um arquivo JSON local. Serve para
+// desenvolvimento; em produção o KeyProvider seria um KMS.
+type ProvedorChavesArquivo struct {
+	caminho string
+
+	mu     sync.RWMutex
+	atual  string
+	chaves map[string][]byte
+	indice []byte
+}
+
+func NovoProvedorChavesArquivo(caminho string) (*ProvedorChavesArquivo, error) {
+	p := &ProvedorChavesArquivo{caminho: caminho}
+	if err := p.Recarregar(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Recarregar relê o arquivo. É assim que uma chave nova passa a valer:
+// adiciona-se a chave no arquivo, troca-se o campo "atual" e chama-se Recarregar.
+func (p *ProvedorChavesArquivo) Recarregar() error {
+	conteudo, err := os.ReadFile(p.caminho)
+	if err != nil {
+		return err
+	}
+	var arquivo arquivoChaves
+	if err := json.Unmarshal(conteudo, &arquivo); err != nil {
+		return fmt.Errorf("arquivo de chaves %s: %w", p.caminho, err)
+	}
+
+	chaves := map[string][]byte{}
+	for id, codificada := range arquivo.Chaves {
+		chave, err := decodificarChave(codificada)
+		if err != nil {
+			return fmt.Errorf("chave %q: %w", id, err)
+		}
+		chaves[id] = chave
+	}
+	if _, ok := chaves[arquivo.Atual]; !ok {
+		return fmt.Errorf("chave atual %q: %w", arquivo.Atual, ErrChaveDesconhecida)
+	}
+	indice, err := decodificarChave(arquivo.Indice)
+	if err != nil {
+		return fmt.Errorf("chave de índice: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.atual, p.chaves, p.indice = arquivo.Atual, chaves, indice
+	return nil
+}
+
+func (p *ProvedorChavesArquivo) ChaveAtual() (string, []byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.atual, p.chaves[p.atual], nil
+}
+
+func (p *ProvedorChavesArquivo) Chave(id string) ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	chave, ok := p.chaves[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrChaveDesconhecida, id)
+	}
+	return chave, nil
+}
+
+func (p *ProvedorChavesArquivo) ChaveIndice() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.indice, nil
+}
+
+// AdicionarChaveAoArquivo gera uma chave aleatória com o id informado e a
+// torna a chave atual. Se o arquivo não existir, ele é criado junto com a
+// chave de índice.
+func AdicionarChaveAoArquivo(caminho, id string) error {
+	var arquivo arquivoChaves
+	conteudo, err := os.ReadFile(caminho)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		indice, err := chaveAleatoria()
+		if err != nil {
+			return err
+		}
+		arquivo.Indice = indice
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(conteudo, &arquivo); err != nil {
+			return fmt.Errorf("arquivo de chaves %s: %w", caminho, err)
+		}
+	}
+	if arquivo.Chaves == nil {
+		arquivo.Chaves = map[string]string{}
+	}
+
+	chave, err := chaveAleatoria()
+	if err != nil {
+		return err
+	}
+	arquivo.Chaves[id] = chave
+	arquivo.Atual = id
+
+	conteudo, err = json.MarshalIndent(arquivo, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(caminho, conteudo, 0o600)
+}
+
+func chaveAleatoria() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func decodificarChave(codificada string) ([]byte, error) {
+	chave, err := base64.StdEncoding.DecodeString(codificada)
+	if err != nil {
+		return nil, err
+	}
+	if len(chave) != 32 {
+		return nil, fmt.Errorf("chave com %d bytes, esperado 32", len(chave))
+	}
+	return chave, nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+// ErrClienteNaoEncontrado é retornado quando o id não existe no cadastro.
+var ErrClienteNaoEncontrado = erros.Definir("lgpd.cliente_nao_encontrado", erros.NaoEncontrado).Sentinela("cliente não encontrado")
+
+// Evento registra algo que aconteceu com um cliente (cadastro, alteração, anonimização...).
+type Evento struct {
+	Tipo    string    `json:"tipo"`
+	Data    time.Time `json:"data"`
+	Detalhe string    `json:"detalhe,omitempty"`
+}
+
+// RegistroAuditoria guarda quem pediu qual operação da LGPD e quando.
+type RegistroAuditoria struct {
+	Operacao    string    `json:"operacao"`
+	ClienteID   string    `json:"cliente_id"`
+	Solicitante string    `json:"solicitante"`
+	Data        time.Time `json:"data"`
+	Sucesso     bool      `json:"sucesso"`
+}
+
+// Cadastro guarda os clientes em memória junto com o histórico de eventos de
+// cada um e a trilha de auditoria das operações da LGPD.
+type Cadastro struct {
+	mu        sync.Mutex
+	clientes  map[string]Cliente
+	eventos   map[string][]Evento
+	auditoria []RegistroAuditoria
+	agora     func() time.Time
+}
+
+func NovoCadastro() *Cadastro {
+	return &Cadastro{
+		clientes: map[string]Cliente{},
+		eventos:  map[string][]Evento{},
+		agora:    time.Now,
+	}
+}
+
+// Salvar cria ou atualiza o cliente e registra o evento correspondente.
+func (c *Cadastro) Salvar(id string, cliente Cliente) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tipo := "atualizado"
+	if _, ok := c.clientes[id]; !ok {
+		tipo = "cadastrado"
+	}
+	c.clientes[id] = cliente
+	c.eventos[id] = append(c.eventos[id], Evento{Tipo: tipo, Data: c.agora()})
+}
+
+// RegistrarEvento adiciona um evento ao histórico de um cliente existente.
+func (c *Cadastro) RegistrarEvento(id, tipo, detalhe string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.clientes[id]; !ok {
+		return ErrClienteNaoEncontrado
+	}
+	c.eventos[id] = append(c.eventos[id], Evento{Tipo: tipo, Data: c.agora(), Detalhe: detalhe})
+	return nil
+}
+
+func (c *Cadastro) Buscar(id string) (Cliente, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cliente, ok := c.clientes[id]
+	if !ok {
+		return Cliente{}, ErrClienteNaoEncontrado
+	}
+	return cliente, nil
+}
+
+// DadosPessoais é a visão do cliente usada na exportação: todos os campos
+// guardados, com o endereço separado em um objeto próprio.
+type DadosPessoais struct {
+	Nome      string   `json:"nome"`
+	Documento string   `json:"documento"`
+	Idade     int      `json:"idade"`
+	Ativo     bool     `json:"ativo"`
+	Endereco  Endereco `json:"endereco"`
+}
+
+// ExportacaoLGPD é o documento entregue ao titular num pedido de acesso (art. 18 da LGPD).
+type ExportacaoLGPD struct {
+	ClienteID string        `json:"cliente_id"`
+	GeradoEm  time.Time     `json:"gerado_em"`
+	Dados     DadosPessoais `json:"dados"`
+	Eventos   []Evento      `json:"eventos"`
+}
+
+// ExportarDados monta o JSON com tudo o que está guardado sobre o cliente.
+// O pedido fica registrado na auditoria, tenha dado certo ou não.
+func (c *Cadastro) ExportarDados(id, solicitante string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cliente, ok := c.clientes[id]
+	c.auditar("exportacao", id, solicitante, ok)
+	if !ok {
+		return nil, ErrClienteNaoEncontrado
+	}
+
+	exportacao := ExportacaoLGPD{
+		ClienteID: id,
+		GeradoEm:  c.agora(),
+		Dados: DadosPessoais{
+			Nome:      cliente.Nome,
+			Documento: cliente.Documento,
+			Idade:     cliente.Idade,
+			Ativo:     cliente.Ativo,
+			Endereco:  cliente.Endereco,
+		},
+		Eventos: append([]Evento{}, c.eventos[id]...),
+	}
+	return json.MarshalIndent(exportacao, "", "  ")
+}
+
+// Anonimizar troca o nome por um pseudônimo aleatório, sem relação com o
+// original, e apaga documento, logradouro e número, de forma que não há
+// como desfazer. Campos usados em estatística, como Cidade, Estado e Idade,
+// são mantidos. Os detalhes dos eventos também são apagados, já que
+// podem conter dados pessoais.
+func (c *Cadastro) Anonimizar(id, solicitante string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cliente, ok := c.clientes[id]
+	if !ok {
+		c.auditar("anonimizacao", id, solicitante, false)
+		return ErrClienteNaoEncontrado
+	}
+
+	pseudonimo, err := tokenAleatorio()
+	if err != nil {
+		c.auditar("anonimizacao", id, solicitante, false)
+		return err
+	}
+	cliente.Nome = "anonimo-" + pseudonimo
+	cliente.Documento = ""
+	cliente.Logradouro = ""
+	cliente.Numero = 0
+	c.clientes[id] = cliente
+
+	eventos := c.eventos[id]
+	for i := range eventos {
+		eventos[i].Detalhe = ""
+	}
+	c.eventos[id] = append(eventos, Evento{Tipo: "anonimizado", Data: c.agora()})
+
+	c.auditar("anonimizacao", id, solicitante, true)
+	return nil
+}
+
+// Auditoria devolve uma cópia da trilha de auditoria.
+func (c *Cadastro) Auditoria() []RegistroAuditoria {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]RegistroAuditoria{}, c.auditoria...)
+}
+
+// auditar deve ser chamado com c.mu travado.
+func (c *Cadastro) auditar(operacao, id, solicitante string, sucesso bool) {
+	c.auditoria = append(c.auditoria, RegistroAuditoria{
+		Operacao:    operacao,
+		ClienteID:   id,
+		Solicitante: solicitante,
+		Data:        c.agora(),
+		Sucesso:     sucesso,
+	})
+}
+
+func tokenAleatorio() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func cadastroTeste(t *testing.T) (*Cadastro, time.Time) {
+	t.Helper()
+	agora := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	c := NovoCadastro()
+	c.agora = func() time.Time { return agora }
+	c.Salvar("1", Cliente{
+		Nome:      "João",
+		Documento: "529.982.247-25",
+		Idade:     30,
+		Ativo:     true,
+		Endereco:  Endereco{Logradouro: "Rua A", Numero: 10, Cidade: "Recife", Estado: "PE"},
+	})
+	if err := c.RegistrarEvento("1", "contato", "ligou pedindo 2ª via, tel. 81 99999-0000"); err != nil {
+		t.Fatal(err)
+	}
+	return c, agora
+}
+
+func TestExportarDados(t *testing.T) {
+	c, agora := cadastroTeste(t)
+
+	dados, err := c.ExportarDados("1", "dpo@empresa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exportacao ExportacaoLGPD
+	if err := json.Unmarshal(dados, &exportacao); err != nil {
+		t.Fatalf("JSON inválido: %v\n%s", err, dados)
+	}
+	if exportacao.ClienteID != "1" || !exportacao.GeradoEm.Equal(agora) {
+		t.Errorf("cabeçalho = %q %v, quero \"1\" %v", exportacao.ClienteID, exportacao.GeradoEm, agora)
+	}
+	quero := DadosPessoais{
+		Nome:      "João",
+		Documento: "529.982.247-25",
+		Idade:     30,
+		Ativo:     true,
+		Endereco:  Endereco{Logradouro: "Rua A", Numero: 10, Cidade: "Recife", Estado: "PE"},
+	}
+	if exportacao.Dados != quero {
+		t.Errorf("Dados = %+v, quero %+v", exportacao.Dados, quero)
+	}
+	if len(exportacao.Eventos) != 2 || exportacao.Eventos[0].Tipo != "cadastrado" || exportacao.Eventos[1].Tipo != "contato" {
+		t.Errorf("Eventos = %+v, quero cadastrado e contato", exportacao.Eventos)
+	}
+
+	if _, err := c.ExportarDados("2", "dpo@empresa"); !errors.Is(err, ErrClienteNaoEncontrado) {
+		t.Errorf("ExportarDados(id inexistente) = %v, quero ErrClienteNaoEncontrado", err)
+	}
+}
+
+func TestAnonimizar(t *testing.T) {
+	c, _ := cadastroTeste(t)
+
+	if err := c.Anonimizar("1", "dpo@empresa"); err != nil {
+		t.Fatal(err)
+	}
+	cliente, err := c.Buscar("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(cliente.Nome, "anonimo-") || cliente.Nome == "anonimo-" {
+		t.Errorf("Nome = %q, quero um pseudônimo anonimo-<token>", cliente.Nome)
+	}
+	if cliente.Documento != "" || cliente.Logradouro != "" || cliente.Numero != 0 {
+		t.Errorf("dados pessoais continuam lá: %+v", cliente)
+	}
+	if cliente.Cidade != "Recife" || cliente.Estado != "PE" || cliente.Idade != 30 || !cliente.Ativo {
+		t.Errorf("campos de estatística foram alterados: %+v", cliente)
+	}
+
+	dados, err := c.ExportarDados("1", "dpo@empresa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if texto := string(dados); strings.Contains(texto, "João") || strings.Contains(texto, "99999") || strings.Contains(texto, "529.982") {
+		t.Errorf("exportação depois da anonimização ainda tem dados pessoais:\n%s", texto)
+	}
+
+	// Clientes com o mesmo nome recebem pseudônimos diferentes.
+	c.Salvar("2", Cliente{Nome: "João"})
+	if err := c.Anonimizar("2", "dpo@empresa"); err != nil {
+		t.Fatal(err)
+	}
+	outro, _ := c.Buscar("2")
+	if outro.Nome == cliente.Nome {
+		t.Errorf("pseudônimos iguais: %q", outro.Nome)
+	}
+
+	if err := c.Anonimizar("3", "dpo@empresa"); !errors.Is(err, ErrClienteNaoEncontrado) {
+		t.Errorf("Anonimizar(id inexistente) = %v, quero ErrClienteNaoEncontrado", err)
+	}
+}
+
+func TestAuditoriaRegistraSucessosEFalhas(t *testing.T) {
+	c, agora := cadastroTeste(t)
+
+	c.ExportarDados("1", "titular")
+	c.ExportarDados("9", "titular")
+	c.Anonimizar("9", "dpo@empresa")
+	c.Anonimizar("1", "dpo@empresa")
+
+	quero := []RegistroAuditoria{
+		{Operacao: "exportacao", ClienteID: "1", Solicitante: "titular", Data: agora, Sucesso: true},
+		{Operacao: "exportacao", ClienteID: "9", Solicitante: "titular", Data: agora, Sucesso: false},
+		{Operacao: "anonimizacao", ClienteID: "9", Solicitante: "dpo@empresa", Data: agora, Sucesso: false},
+		{Operacao: "anonimizacao", ClienteID: "1", Solicitante: "dpo@empresa", Data: agora, Sucesso: true},
+	}
+	trilha := c.Auditoria()
+	if len(trilha) != len(quero) {
+		t.Fatalf("Auditoria() tem %d registros, quero %d: %+v", len(trilha), len(quero), trilha)
+	}
+	for i := range quero {
+		if trilha[i] != quero[i] {
+			t.Errorf("registro %d = %+v, quero %+v", i, trilha[i], quero[i])
+		}
+	}
+
+	// A cópia devolvida não altera a trilha guardada.
+	trilha[0].Solicitante = "outro"
+	if got := c.Auditoria()[0].Solicitante; got != "titular" {
+		t.Errorf("Auditoria() devolveu a fatia interna: solicitante virou %q", got)
+	}
+}
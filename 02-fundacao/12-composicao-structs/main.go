@@ -1,6 +1,11 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
 
 type Endereco struct {
 	Logradouro string
@@ -10,9 +15,10 @@ type Endereco struct {
 }
 
 type Cliente struct {
-	Nome   string
-	Idade  int
-	Ativo  bool
+	Nome      string
+	Documento string
+	Idade     int
+	Ativo     bool
 	Endereco
 	//ou
 	//Adress Endereco
@@ -40,4 +46,76 @@ func main() {
 	//ou
 	//joao.Endereco.Cidade = "Brasília"
 	fmt.Println(joao.Nome)
+
+	// LGPD: exportação dos dados do titular e anonimização (ver lgpd.go)
+	cadastro := NovoCadastro()
+	joao.Documento = "123.456.789-09"
+	joao.Logradouro = "Rua das Flores"
+	joao.Estado = "DF"
+	cadastro.Salvar("c1", joao)
+
+	exportacao, err := cadastro.ExportarDados("c1", "dpo@empresa.com.br")
+	if err != nil {
+		fmt.Println("Erro:", err)
+		return
+	}
+	fmt.Println(string(exportacao))
+
+	if err := cadastro.Anonimizar("c1", "dpo@empresa.com.br"); err != nil {
+		fmt.Println("Erro:", err)
+		return
+	}
+	anonimo, _ := cadastro.Buscar("c1")
+	fmt.Println(anonimo.Nome, anonimo.Estado)
+
+	// Persistência com os dados pessoais cifrados (ver persistencia.go)
+	dir, err := os.MkdirTemp("", "clientes")
+	if err != nil {
+		fmt.Println("Erro:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	arquivoChaves := filepath.Join(dir, "chaves.json")
+	if err := AdicionarChaveAoArquivo(arquivoChaves, "k1"); err != nil {
+		fmt.Println("Erro:", err)
+		return
+	}
+	chaves, err := NovoProvedorChavesArquivo(arquivoChaves)
+	if err != nil {
+		fmt.Println("Erro:", err)
+		return
+	}
+	armazem, err := AbrirArmazemCifrado(filepath.Join(dir, "clientes.json"), chaves)
+	if err != nil {
+		fmt.Println("Erro:", err)
+		return
+	}
+	maria := Cliente{Nome: "Maria", Documento: "987.654.321-00", Idade: 30, Ativo: true}
+	maria.Logradouro = "Av. Paulista"
+	maria.Estado = "SP"
+	if err := armazem.Salvar("c2", maria); err != nil {
+		fmt.Println("Erro:", err)
+		return
+	}
+
+	// nova chave: os registros antigos são recifrados em segundo plano
+	if err := AdicionarChaveAoArquivo(arquivoChaves, "k2"); err != nil {
+		fmt.Println("Erro:", err)
+		return
+	}
+	if err := chaves.Recarregar(); err != nil {
+		fmt.Println("Erro:", err)
+		return
+	}
+	if err := <-armazem.RotacionarChaves(context.Background()); err != nil {
+		fmt.Println("Erro:", err)
+		return
+	}
+
+	ids, _ := armazem.BuscarPorDocumento("987.654.321-00")
+	for _, id := range ids {
+		cliente, _ := armazem.Buscar(id)
+		fmt.Println(id, cliente.Nome, cliente.Logradouro)
+	}
 }
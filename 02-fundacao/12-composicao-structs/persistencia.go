@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// registroCifrado é como o cliente fica no disco: nome, documento e endereço
+// cifrados com AES-GCM e os demais campos em claro. Os índices cegos
+// (HMAC-SHA256 do valor normalizado) permitem buscar por igualdade exata sem
+// decifrar nada.
+type registroCifrado struct {
+	ID              string `json:"id"`
+	ChaveID         string `json:"chave_id"`
+	Nome            []byte `json:"nome"`
+	Documento       []byte `json:"documento"`
+	Endereco        []byte `json:"endereco"`
+	Idade           int    `json:"idade"`
+	Ativo           bool   `json:"ativo"`
+	IndiceNome      string `json:"indice_nome"`
+	IndiceDocumento string `json:"indice_documento"`
+}
+
+// ArmazemCifrado persiste clientes num arquivo JSON com os dados pessoais
+// cifrados campo a campo.
+type ArmazemCifrado struct {
+	caminho string
+	chaves  KeyProvider
+
+	mu        sync.Mutex
+	registros map[string]registroCifrado
+}
+
+// AbrirArmazemCifrado carrega o arquivo, se ele existir.
+func AbrirArmazemCifrado(caminho string, chaves KeyProvider) (*ArmazemCifrado, error) {
+	a := &ArmazemCifrado{caminho: caminho, chaves: chaves, registros: map[string]registroCifrado{}}
+
+	conteudo, err := os.ReadFile(caminho)
+	if errors.Is(err, os.ErrNotExist) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var registros []registroCifrado
+	if err := json.Unmarshal(conteudo, &registros); err != nil {
+		return nil, err
+	}
+	for _, r := range registros {
+		a.registros[r.ID] = r
+	}
+	return a, nil
+}
+
+// Salvar cifra com a chave atual e grava. A cifragem acontece com a.mu
+// travado: cifrando antes do lock, uma rotação que começasse no meio podia
+// terminar antes desta gravação, e o registro ficaria com a chave antiga
+// sem que ninguém o recifrasse.
+func (a *ArmazemCifrado) Salvar(id string, cliente Cliente) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	registro, err := a.cifrar(id, cliente)
+	if err != nil {
+		return err
+	}
+	anterior, existia := a.registros[id]
+	a.registros[id] = registro
+	if err := a.gravar(); err != nil {
+		// o disco ficou como estava; a memória volta junto
+		if existia {
+			a.registros[id] = anterior
+		} else {
+			delete(a.registros, id)
+		}
+		return err
+	}
+	return nil
+}
+
+func (a *ArmazemCifrado) Buscar(id string) (Cliente, error) {
+	a.mu.Lock()
+	registro, ok := a.registros[id]
+	a.mu.Unlock()
+	if !ok {
+		return Cliente{}, ErrClienteNaoEncontrado
+	}
+	return a.decifrar(registro)
+}
+
+// BuscarPorDocumento devolve os ids dos clientes com exatamente esse
+// documento. Documento vazio não encontra ninguém: clientes sem documento
+// (como os anonimizados) não entram no índice.
+func (a *ArmazemCifrado) BuscarPorDocumento(documento string) ([]string, error) {
+	return a.buscarPorIndice(documento, func(r registroCifrado) string { return r.IndiceDocumento })
+}
+
+// BuscarPorNome devolve os ids dos clientes com exatamente esse nome
+// (sem diferenciar maiúsculas e minúsculas). Como no documento, nome vazio
+// não encontra ninguém.
+func (a *ArmazemCifrado) BuscarPorNome(nome string) ([]string, error) {
+	return a.buscarPorIndice(nome, func(r registroCifrado) string { return r.IndiceNome })
+}
+
+func (a *ArmazemCifrado) buscarPorIndice(valor string, campo func(registroCifrado) string) ([]string, error) {
+	indice, err := a.indiceCego(valor)
+	if err != nil || indice == "" {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var ids []string
+	for id, r := range a.registros {
+		if hmac.Equal([]byte(campo(r)), []byte(indice)) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// RotacionarChaves recifra, em segundo plano, todo registro que não esteja
+// com a chave atual do KeyProvider. O canal devolvido recebe o resultado
+// (nil em caso de sucesso) e é fechado ao final. Cada registro é trocado sob
+// o lock, então leituras e gravações continuam funcionando durante a rotação.
+// Se a rotação for cancelada no meio, o que já foi recifrado é gravado e uma
+// nova chamada continua de onde ela parou.
+func (a *ArmazemCifrado) RotacionarChaves(ctx context.Context) <-chan error {
+	resultado := make(chan error, 1)
+	go func() {
+		defer close(resultado)
+		resultado <- a.rotacionar(ctx)
+	}()
+	return resultado
+}
+
+func (a *ArmazemCifrado) rotacionar(ctx context.Context) error {
+	atual, _, err := a.chaves.ChaveAtual()
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	var pendentes []string
+	for id, r := range a.registros {
+		if r.ChaveID != atual {
+			pendentes = append(pendentes, id)
+		}
+	}
+	a.mu.Unlock()
+
+	var trocas []troca
+	var errRotacao error
+	for _, id := range pendentes {
+		if errRotacao = ctx.Err(); errRotacao != nil {
+			break
+		}
+		t, err := a.recifrar(id, atual)
+		if err != nil {
+			errRotacao = err
+			break
+		}
+		if t != nil {
+			trocas = append(trocas, *t)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.gravar(); err != nil {
+		a.desfazer(trocas)
+		return err
+	}
+	return errRotacao
+}
+
+// troca é um registro recifrado pela rotação, guardado para ser desfeito se
+// a gravação falhar.
+type troca struct {
+	antigo, novo registroCifrado
+}
+
+func (a *ArmazemCifrado) recifrar(id, chaveAtual string) (*troca, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	registro, ok := a.registros[id]
+	if !ok || registro.ChaveID == chaveAtual {
+		// apagado ou regravado com a chave nova enquanto a rotação rodava
+		return nil, nil
+	}
+	cliente, err := a.decifrar(registro)
+	if err != nil {
+		return nil, err
+	}
+	novo, err := a.cifrar(id, cliente)
+	if err != nil {
+		return nil, err
+	}
+	a.registros[id] = novo
+	return &troca{antigo: registro, novo: novo}, nil
+}
+
+// desfazer volta para a versão antiga os registros recifrados que ainda
+// estão como a rotação os deixou; os regravados depois por Salvar ficam como
+// estão. Deve ser chamado com a.mu travado.
+func (a *ArmazemCifrado) desfazer(trocas []troca) {
+	for _, t := range trocas {
+		// o nonce aleatório faz de cada campo cifrado uma identidade
+		if r, ok := a.registros[t.novo.ID]; ok && bytes.Equal(r.Nome, t.novo.Nome) {
+			a.registros[t.novo.ID] = t.antigo
+		}
+	}
+}
+
+func (a *ArmazemCifrado) cifrar(id string, cliente Cliente) (registroCifrado, error) {
+	chaveID, chave, err := a.chaves.ChaveAtual()
+	if err != nil {
+		return registroCifrado{}, err
+	}
+	endereco, err := json.Marshal(cliente.Endereco)
+	if err != nil {
+		return registroCifrado{}, err
+	}
+	indiceNome, err := a.indiceCego(cliente.Nome)
+	if err != nil {
+		return registroCifrado{}, err
+	}
+	indiceDocumento, err := a.indiceCego(cliente.Documento)
+	if err != nil {
+		return registroCifrado{}, err
+	}
+
+	registro := registroCifrado{
+		ID:              id,
+		ChaveID:         chaveID,
+		Idade:           cliente.Idade,
+		Ativo:           cliente.Ativo,
+		IndiceNome:      indiceNome,
+		IndiceDocumento: indiceDocumento,
+	}
+	campos := []struct {
+		nome    string
+		destino *[]byte
+		valor   []byte
+	}{
+		{"nome", &registro.Nome, []byte(cliente.Nome)},
+		{"documento", &registro.Documento, []byte(cliente.Documento)},
+		{"endereco", &registro.Endereco, endereco},
+	}
+	for _, c := range campos {
+		if *c.destino, err = selar(chave, c.valor, id+"/"+c.nome); err != nil {
+			return registroCifrado{}, err
+		}
+	}
+	return registro, nil
+}
+
+func (a *ArmazemCifrado) decifrar(registro registroCifrado) (Cliente, error) {
+	chave, err := a.chaves.Chave(registro.ChaveID)
+	if err != nil {
+		return Cliente{}, err
+	}
+	nome, err := abrir(chave, registro.Nome, registro.ID+"/nome")
+	if err != nil {
+		return Cliente{}, err
+	}
+	documento, err := abrir(chave, registro.Documento, registro.ID+"/documento")
+	if err != nil {
+		return Cliente{}, err
+	}
+	endereco, err := abrir(chave, registro.Endereco, registro.ID+"/endereco")
+	if err != nil {
+		return Cliente{}, err
+	}
+
+	cliente := Cliente{
+		Nome:      string(nome),
+		Documento: string(documento),
+		Idade:     registro.Idade,
+		Ativo:     registro.Ativo,
+	}
+	if err := json.Unmarshal(endereco, &cliente.Endereco); err != nil {
+		return Cliente{}, err
+	}
+	return cliente, nil
+}
+
+// indiceCego é o HMAC do valor normalizado. Valor vazio dá índice vazio: o
+// HMAC de "" seria o mesmo para todos os clientes sem o campo e uma busca
+// por ele traria todos.
+func (a *ArmazemCifrado) indiceCego(valor string) (string, error) {
+	normalizado := strings.ToLower(strings.TrimSpace(valor))
+	if normalizado == "" {
+		return "", nil
+	}
+	chave, err := a.chaves.ChaveIndice()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, chave)
+	mac.Write([]byte(normalizado))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// gravar escreve num arquivo temporário e renomeia, para não deixar o
+// arquivo pela metade se o processo cair. Deve ser chamado com a.mu travado.
+func (a *ArmazemCifrado) gravar() error {
+	registros := make([]registroCifrado, 0, len(a.registros))
+	for _, r := range a.registros {
+		registros = append(registros, r)
+	}
+	sort.Slice(registros, func(i, j int) bool { return registros[i].ID < registros[j].ID })
+
+	conteudo, err := json.MarshalIndent(registros, "", "  ")
+	if err != nil {
+		return err
+	}
+	temporario := a.caminho + ".tmp"
+	if err := os.WriteFile(temporario, conteudo, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(temporario, a.caminho)
+}
+
+// selar cifra com AES-GCM e devolve nonce||texto cifrado. O contexto (id do
+// registro e nome do campo) entra como dado autenticado, assim um campo
+// cifrado não pode ser copiado para outro registro sem ser detectado.
+func selar(chave, valor []byte, contexto string) ([]byte, error) {
+	gcm, err := novoGCM(chave)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, valor, []byte(contexto)), nil
+}
+
+func abrir(chave, selado []byte, contexto string) ([]byte, error) {
+	gcm, err := novoGCM(chave)
+	if err != nil {
+		return nil, err
+	}
+	if len(selado) < gcm.NonceSize() {
+		return nil, errors.New("campo cifrado truncado")
+	}
+	nonce, cifrado := selado[:gcm.NonceSize()], selado[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, cifrado, []byte(contexto))
+}
+
+func novoGCM(chave []byte) (cipher.AEAD, error) {
+	bloco, err := aes.NewCipher(chave)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(bloco)
+}
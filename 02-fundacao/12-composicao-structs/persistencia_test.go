@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// chavesMemoria é um KeyProvider em memória. aoLerAtual, se existir, roda
+// uma vez, na primeira chamada de ChaveAtual, depois de lida a chave.
+type chavesMemoria struct {
+	mu         sync.Mutex
+	atual      string
+	chaves     map[string][]byte
+	aoLerAtual func()
+}
+
+func (c *chavesMemoria) ChaveAtual() (string, []byte, error) {
+	c.mu.Lock()
+	id, chave, gancho := c.atual, c.chaves[c.atual], c.aoLerAtual
+	c.aoLerAtual = nil
+	c.mu.Unlock()
+	if gancho != nil {
+		gancho()
+	}
+	return id, chave, nil
+}
+
+func (c *chavesMemoria) Chave(id string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	chave, ok := c.chaves[id]
+	if !ok {
+		return nil, fmt.Errorf("chave %q desconhecida", id)
+	}
+	return chave, nil
+}
+
+func (c *chavesMemoria) ChaveIndice() ([]byte, error) {
+	return bytes.Repeat([]byte{9}, 32), nil
+}
+
+func (c *chavesMemoria) trocar(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.chaves[id] = bytes.Repeat([]byte{byte(len(c.chaves) + 1)}, 32)
+	c.atual = id
+}
+
+func TestSalvarDuranteRotacaoNaoFicaComChaveAntiga(t *testing.T) {
+	chaves := &chavesMemoria{atual: "k1", chaves: map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}}
+	armazem, err := AbrirArmazemCifrado(filepath.Join(t.TempDir(), "clientes.json"), chaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Salvar lê a chave k1; logo em seguida a chave muda para k2 e a
+	// rotação começa, antes de Salvar terminar.
+	var rotacao <-chan error
+	var rotacaoErr error
+	terminou := false
+	chaves.aoLerAtual = func() {
+		chaves.trocar("k2")
+		rotacao = armazem.RotacionarChaves(context.Background())
+		select {
+		case rotacaoErr = <-rotacao:
+			terminou = true
+		case <-time.After(50 * time.Millisecond):
+			// com a cifragem sob o lock, a rotação espera Salvar
+		}
+	}
+	if err := armazem.Salvar("c1", Cliente{Nome: "Ana", Documento: "123"}); err != nil {
+		t.Fatal(err)
+	}
+	if !terminou {
+		rotacaoErr = <-rotacao
+	}
+	if rotacaoErr != nil {
+		t.Fatal(rotacaoErr)
+	}
+
+	armazem.mu.Lock()
+	chaveID := armazem.registros["c1"].ChaveID
+	armazem.mu.Unlock()
+	if chaveID != "k2" {
+		t.Fatalf("registro salvo durante a rotação ficou com a chave %q, quero k2", chaveID)
+	}
+	cliente, err := armazem.Buscar("c1")
+	if err != nil || cliente.Nome != "Ana" {
+		t.Fatalf("Buscar = %+v, %v", cliente, err)
+	}
+}
+
+// armazemComArquivo abre um ArmazemCifrado com um ProvedorChavesArquivo
+// recém-criado, com a chave k1.
+func armazemComArquivo(t *testing.T) (armazem *ArmazemCifrado, chaves *ProvedorChavesArquivo, dir string) {
+	t.Helper()
+	dir = t.TempDir()
+	if err := AdicionarChaveAoArquivo(filepath.Join(dir, "chaves.json"), "k1"); err != nil {
+		t.Fatal(err)
+	}
+	chaves, err := NovoProvedorChavesArquivo(filepath.Join(dir, "chaves.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	armazem, err = AbrirArmazemCifrado(filepath.Join(dir, "clientes.json"), chaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return armazem, chaves, dir
+}
+
+func TestCifrarEDecifrar(t *testing.T) {
+	armazem, chaves, dir := armazemComArquivo(t)
+	ana := Cliente{
+		Nome:      "Ana Souza",
+		Documento: "529.982.247-25",
+		Idade:     41,
+		Ativo:     true,
+		Endereco:  Endereco{Logradouro: "Rua das Flores", Numero: 12, Cidade: "Recife", Estado: "PE"},
+	}
+	if err := armazem.Salvar("c1", ana); err != nil {
+		t.Fatal(err)
+	}
+
+	conteudo, err := os.ReadFile(filepath.Join(dir, "clientes.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, claro := range []string{"Ana", "529.982", "Flores", "Recife"} {
+		if strings.Contains(string(conteudo), claro) {
+			t.Errorf("o arquivo tem %q em claro:\n%s", claro, conteudo)
+		}
+	}
+
+	// Reaberto do disco, o cliente volta igual.
+	reaberto, err := AbrirArmazemCifrado(filepath.Join(dir, "clientes.json"), chaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cliente, err := reaberto.Buscar("c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cliente != ana {
+		t.Errorf("Buscar = %+v, quero %+v", cliente, ana)
+	}
+	if _, err := reaberto.Buscar("c2"); !errors.Is(err, ErrClienteNaoEncontrado) {
+		t.Errorf("Buscar(c2) = %v, quero ErrClienteNaoEncontrado", err)
+	}
+
+	// Um campo cifrado copiado para outro registro não decifra.
+	reaberto.mu.Lock()
+	outro := reaberto.registros["c1"]
+	outro.ID = "c2"
+	reaberto.registros["c2"] = outro
+	reaberto.mu.Unlock()
+	if _, err := reaberto.Buscar("c2"); err == nil {
+		t.Error("registro copiado para outro id foi decifrado")
+	}
+}
+
+func TestBuscarPorIndiceCego(t *testing.T) {
+	armazem, _, _ := armazemComArquivo(t)
+	for id, cliente := range map[string]Cliente{
+		"c1": {Nome: "Ana Souza", Documento: "111"},
+		"c2": {Nome: "ana souza", Documento: "222"},
+		"c3": {Nome: "Bia", Documento: "111"},
+		"c4": {Nome: "Caio"},
+		"c5": {Nome: "Duda"},
+	} {
+		if err := armazem.Salvar(id, cliente); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, c := range []struct {
+		busca func(string) ([]string, error)
+		valor string
+		quero []string
+	}{
+		{armazem.BuscarPorNome, "  ANA SOUZA ", []string{"c1", "c2"}},
+		{armazem.BuscarPorNome, "Ana", nil},
+		{armazem.BuscarPorDocumento, "111", []string{"c1", "c3"}},
+		{armazem.BuscarPorDocumento, "333", nil},
+		// c4 e c5 não têm documento, mas isso não faz deles o mesmo cliente
+		{armazem.BuscarPorDocumento, "", nil},
+		{armazem.BuscarPorDocumento, "  ", nil},
+	} {
+		ids, err := c.busca(c.valor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(ids, c.quero) {
+			t.Errorf("busca por %q = %v, quero %v", c.valor, ids, c.quero)
+		}
+	}
+
+	armazem.mu.Lock()
+	indice := armazem.registros["c4"].IndiceDocumento
+	armazem.mu.Unlock()
+	if indice != "" {
+		t.Errorf("documento vazio gerou o índice %q, quero vazio", indice)
+	}
+}
+
+func TestRotacionarComProvedorArquivo(t *testing.T) {
+	armazem, chaves, dir := armazemComArquivo(t)
+	clientes := map[string]Cliente{
+		"c1": {Nome: "Ana", Documento: "111", Idade: 30},
+		"c2": {Nome: "Bia", Documento: "222", Idade: 25},
+	}
+	for id, cliente := range clientes {
+		if err := armazem.Salvar(id, cliente); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	caminhoChaves := filepath.Join(dir, "chaves.json")
+	if err := AdicionarChaveAoArquivo(caminhoChaves, "k2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := chaves.Recarregar(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-armazem.RotacionarChaves(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tirando a k1 do arquivo, só o que foi recifrado com a k2 ainda abre.
+	conteudo, err := os.ReadFile(caminhoChaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var arquivo arquivoChaves
+	if err := json.Unmarshal(conteudo, &arquivo); err != nil {
+		t.Fatal(err)
+	}
+	delete(arquivo.Chaves, "k1")
+	conteudo, _ = json.Marshal(arquivo)
+	if err := os.WriteFile(caminhoChaves, conteudo, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	soK2, err := NovoProvedorChavesArquivo(caminhoChaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reaberto, err := AbrirArmazemCifrado(filepath.Join(dir, "clientes.json"), soK2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, quero := range clientes {
+		cliente, err := reaberto.Buscar(id)
+		if err != nil {
+			t.Fatalf("Buscar(%s) depois da rotação: %v", id, err)
+		}
+		if cliente != quero {
+			t.Errorf("Buscar(%s) = %+v, quero %+v", id, cliente, quero)
+		}
+	}
+	// A chave de índice não muda na rotação: as buscas continuam achando.
+	if ids, err := reaberto.BuscarPorDocumento("222"); err != nil || !slices.Equal(ids, []string{"c2"}) {
+		t.Errorf("BuscarPorDocumento(222) = %v, %v; quero [c2]", ids, err)
+	}
+}
+
+// quebrarGravacao faz a próxima gravação do armazém falhar, ocupando o
+// caminho do arquivo temporário com um diretório.
+func quebrarGravacao(t *testing.T, armazem *ArmazemCifrado) (consertar func()) {
+	t.Helper()
+	temporario := armazem.caminho + ".tmp"
+	if err := os.Mkdir(temporario, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return func() { os.Remove(temporario) }
+}
+
+func TestSalvarQueFalhaNaoAlteraAMemoria(t *testing.T) {
+	armazem, _, _ := armazemComArquivo(t)
+	if err := armazem.Salvar("c1", Cliente{Nome: "Ana", Documento: "111"}); err != nil {
+		t.Fatal(err)
+	}
+	consertar := quebrarGravacao(t, armazem)
+
+	if err := armazem.Salvar("c1", Cliente{Nome: "Ana Maria", Documento: "111"}); err == nil {
+		t.Fatal("Salvar gravou mesmo com o temporário ocupado")
+	}
+	if err := armazem.Salvar("c2", Cliente{Nome: "Bia"}); err == nil {
+		t.Fatal("Salvar gravou mesmo com o temporário ocupado")
+	}
+	if cliente, err := armazem.Buscar("c1"); err != nil || cliente.Nome != "Ana" {
+		t.Errorf("Buscar(c1) = %+v, %v; quero o cliente como estava no disco", cliente, err)
+	}
+	if _, err := armazem.Buscar("c2"); !errors.Is(err, ErrClienteNaoEncontrado) {
+		t.Errorf("Buscar(c2) = %v, quero ErrClienteNaoEncontrado", err)
+	}
+
+	consertar()
+	if err := armazem.Salvar("c2", Cliente{Nome: "Bia"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRotacaoQueFalhaVoltaAChaveAntiga(t *testing.T) {
+	chaves := &chavesMemoria{atual: "k1", chaves: map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}}
+	armazem, err := AbrirArmazemCifrado(filepath.Join(t.TempDir(), "clientes.json"), chaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"c1", "c2"} {
+		if err := armazem.Salvar(id, Cliente{Nome: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chaves.trocar("k2")
+	consertar := quebrarGravacao(t, armazem)
+
+	if err := <-armazem.RotacionarChaves(context.Background()); err == nil {
+		t.Fatal("a rotação terminou sem erro com o temporário ocupado")
+	}
+	chaveDe := func(id string) string {
+		armazem.mu.Lock()
+		defer armazem.mu.Unlock()
+		return armazem.registros[id].ChaveID
+	}
+	for _, id := range []string{"c1", "c2"} {
+		if got := chaveDe(id); got != "k1" {
+			t.Errorf("%s ficou com a chave %q depois da falha, quero k1 como no disco", id, got)
+		}
+	}
+
+	consertar()
+	if err := <-armazem.RotacionarChaves(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"c1", "c2"} {
+		if got := chaveDe(id); got != "k2" {
+			t.Errorf("%s ficou com a chave %q, quero k2", id, got)
+		}
+	}
+}
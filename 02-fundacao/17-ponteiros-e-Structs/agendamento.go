@@ -0,0 +1,313 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+// Frequencia define de quanto em quanto tempo um agendamento se repete.
+type Frequencia int
+
+const (
+	Unica Frequencia = iota
+	Diaria
+	Semanal
+	// Mensal repete no DiaDoMes do agendamento. Em meses mais curtos (dia 31
+	// em abril, dia 30 em fevereiro) a transferência sai no último dia do mês.
+	Mensal
+)
+
+var (
+	ErrAgendamentoNaoEncontrado = erros.Definir("agendamento.nao_encontrado", erros.NaoEncontrado).Sentinela("agendamento não encontrado")
+	ErrAgendamentoSemContas     = erros.Definir("agendamento.sem_contas", erros.Validacao).Sentinela("agendamento sem origem ou destino")
+	ErrDiaDoMesInvalido         = erros.Definir("agendamento.dia_do_mes_invalido", erros.Validacao).Sentinela("dia do mês inválido")
+)
+
+// Agendamento é uma transferência futura, única ou recorrente. Fim zero
+// significa que a recorrência não tem data para acabar.
+type Agendamento struct {
+	ID         string
+	Origem     *Conta
+	Destino    *Conta
+	Valor      int
+	Frequencia Frequencia
+	Inicio     time.Time
+	DiaDoMes   int
+	Fim        time.Time
+}
+
+// Backoff controla as novas tentativas quando falta saldo: a primeira espera
+// Inicial, e cada uma seguinte espera o dobro, até Maximo. Depois de
+// MaxTentativas a ocorrência é abandonada e o agendamento segue para a próxima.
+type Backoff struct {
+	Inicial       time.Duration
+	Maximo        time.Duration
+	MaxTentativas int
+}
+
+// StatusExecucao é o resultado de uma tentativa.
+type StatusExecucao string
+
+const (
+	ExecucaoSucesso    StatusExecucao = "sucesso"
+	ExecucaoReagendada StatusExecucao = "reagendada"
+	ExecucaoFalha      StatusExecucao = "falha"
+)
+
+// Execucao é uma entrada do histórico do agendador.
+type Execucao struct {
+	AgendamentoID string
+	Prevista      time.Time
+	Executada     time.Time
+	Tentativa     int
+	Status        StatusExecucao
+	Erro          error
+}
+
+type estadoAgendamento struct {
+	Agendamento
+	ocorrencia int
+	prevista   time.Time
+	tentarEm   time.Time
+	tentativas int
+	ativo      bool
+}
+
+// Agendador executa os agendamentos vencidos segundo o relógio informado.
+// Com um RelogioFake, basta avançar o relógio e chamar Executar.
+type Agendador struct {
+	relogio Relogio
+	backoff Backoff
+
+	mu           sync.Mutex
+	ultimoID     int
+	agendamentos map[string]*estadoAgendamento
+	historico    []Execucao
+}
+
+func NovoAgendador(relogio Relogio, backoff Backoff) *Agendador {
+	if relogio == nil {
+		relogio = relogioSistema{}
+	}
+	if backoff.MaxTentativas < 1 {
+		backoff.MaxTentativas = 1
+	}
+	return &Agendador{relogio: relogio, backoff: backoff, agendamentos: map[string]*estadoAgendamento{}}
+}
+
+// Agendar registra o agendamento e devolve o id.
+func (a *Agendador) Agendar(ag Agendamento) (string, error) {
+	if ag.Valor <= 0 {
+		return "", ErrValorInvalido
+	}
+	if ag.Origem == nil || ag.Destino == nil {
+		return "", ErrAgendamentoSemContas
+	}
+	if ag.Frequencia == Mensal && (ag.DiaDoMes < 1 || ag.DiaDoMes > 31) {
+		return "", fmt.Errorf("%w: %d", ErrDiaDoMesInvalido, ag.DiaDoMes)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.ultimoID++
+	ag.ID = fmt.Sprintf("agendamento-%d", a.ultimoID)
+	estado := &estadoAgendamento{Agendamento: ag, ativo: true}
+	estado.prevista = estado.calcularOcorrencia(0)
+	if ag.Frequencia == Mensal && estado.prevista.Before(ag.Inicio) {
+		// o dia do mês já passou no mês de início: começa no mês seguinte
+		estado.ocorrencia = 1
+		estado.prevista = estado.calcularOcorrencia(1)
+	}
+	estado.tentarEm = estado.prevista
+	if !ag.Fim.IsZero() && estado.prevista.After(ag.Fim) {
+		estado.ativo = false
+	}
+	a.agendamentos[ag.ID] = estado
+	return ag.ID, nil
+}
+
+func (a *Agendador) Cancelar(id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	estado, ok := a.agendamentos[id]
+	if !ok {
+		return ErrAgendamentoNaoEncontrado
+	}
+	estado.ativo = false
+	return nil
+}
+
+// ProximaExecucao informa quando o agendamento será tentado de novo.
+func (a *Agendador) ProximaExecucao(id string) (time.Time, bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	estado, ok := a.agendamentos[id]
+	if !ok {
+		return time.Time{}, false, ErrAgendamentoNaoEncontrado
+	}
+	return estado.tentarEm, estado.ativo, nil
+}
+
+// Executar roda tudo o que venceu até agora, em ordem de horário, e devolve
+// as execuções feitas. Se o relógio pulou várias ocorrências, todas saem,
+// intercaladas com as dos outros agendamentos: os saldos são compartilhados,
+// então uma ocorrência de 01/02 tem de sair antes de uma de 03/02, seja de
+// qual agendamento for. Uma ocorrência reagendada só volta na próxima
+// chamada.
+func (a *Agendador) Executar() []Execucao {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	agora := a.relogio.Agora()
+	var fila filaAgendamentos
+	for _, estado := range a.agendamentos {
+		if estado.vencido(agora) {
+			fila = append(fila, estado)
+		}
+	}
+	heap.Init(&fila)
+
+	var execucoes []Execucao
+	for fila.Len() > 0 {
+		estado := heap.Pop(&fila).(*estadoAgendamento)
+		execucao := a.tentar(estado, agora)
+		execucoes = append(execucoes, execucao)
+		if execucao.Status != ExecucaoReagendada && estado.vencido(agora) {
+			heap.Push(&fila, estado)
+		}
+	}
+	a.historico = append(a.historico, execucoes...)
+	return execucoes
+}
+
+func (e *estadoAgendamento) vencido(agora time.Time) bool {
+	return e.ativo && !e.tentarEm.After(agora)
+}
+
+// filaAgendamentos é um heap pelo horário da próxima tentativa; no mesmo
+// horário, pelo id, para que a ordem não dependa do mapa.
+type filaAgendamentos []*estadoAgendamento
+
+func (f filaAgendamentos) Len() int { return len(f) }
+
+func (f filaAgendamentos) Less(i, j int) bool {
+	if !f[i].tentarEm.Equal(f[j].tentarEm) {
+		return f[i].tentarEm.Before(f[j].tentarEm)
+	}
+	return f[i].ID < f[j].ID
+}
+
+func (f filaAgendamentos) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+
+func (f *filaAgendamentos) Push(x any) { *f = append(*f, x.(*estadoAgendamento)) }
+
+func (f *filaAgendamentos) Pop() any {
+	antigo := *f
+	ultimo := antigo[len(antigo)-1]
+	*f = antigo[:len(antigo)-1]
+	return ultimo
+}
+
+// Iniciar chama Executar a cada intervalo até o contexto ser cancelado.
+func (a *Agendador) Iniciar(ctx context.Context, intervalo time.Duration) {
+	ticker := time.NewTicker(intervalo)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.Executar()
+		}
+	}
+}
+
+// Historico devolve uma cópia de todas as execuções.
+func (a *Agendador) Historico() []Execucao {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]Execucao{}, a.historico...)
+}
+
+// tentar faz uma tentativa da ocorrência atual. Deve ser chamado com a.mu travado.
+func (a *Agendador) tentar(estado *estadoAgendamento, agora time.Time) Execucao {
+	estado.tentativas++
+	execucao := Execucao{
+		AgendamentoID: estado.ID,
+		Prevista:      estado.prevista,
+		Executada:     agora,
+		Tentativa:     estado.tentativas,
+	}
+
+	err := Transferir(estado.Origem, estado.Destino, estado.Valor)
+	var semSaldo *ErrSaldoInsuficiente
+	switch {
+	case err == nil:
+		execucao.Status = ExecucaoSucesso
+	case errors.As(err, &semSaldo) && estado.tentativas < a.backoff.MaxTentativas:
+		execucao.Status, execucao.Erro = ExecucaoReagendada, err
+		estado.tentarEm = agora.Add(a.espera(estado.tentativas))
+		return execucao
+	default:
+		execucao.Status, execucao.Erro = ExecucaoFalha, err
+	}
+
+	estado.avancar()
+	return execucao
+}
+
+func (a *Agendador) espera(tentativas int) time.Duration {
+	espera := a.backoff.Inicial
+	for i := 1; i < tentativas; i++ {
+		espera *= 2
+		if a.backoff.Maximo > 0 && espera >= a.backoff.Maximo {
+			return a.backoff.Maximo
+		}
+	}
+	return espera
+}
+
+// avancar passa para a próxima ocorrência ou desativa o agendamento.
+func (e *estadoAgendamento) avancar() {
+	e.tentativas = 0
+	if e.Frequencia == Unica {
+		e.ativo = false
+		return
+	}
+	e.ocorrencia++
+	e.prevista = e.calcularOcorrencia(e.ocorrencia)
+	e.tentarEm = e.prevista
+	if !e.Fim.IsZero() && e.prevista.After(e.Fim) {
+		e.ativo = false
+	}
+}
+
+// calcularOcorrencia devolve a data da n-ésima ocorrência, sempre a partir
+// do início, para que um mês curto não empurre os meses seguintes.
+func (e *estadoAgendamento) calcularOcorrencia(n int) time.Time {
+	switch e.Frequencia {
+	case Diaria:
+		return e.Inicio.AddDate(0, 0, n)
+	case Semanal:
+		return e.Inicio.AddDate(0, 0, 7*n)
+	case Mensal:
+		ano, mes := e.Inicio.Year(), e.Inicio.Month()+time.Month(n)
+		dia := min(e.DiaDoMes, ultimoDiaDoMes(ano, mes, e.Inicio.Location()))
+		return time.Date(ano, mes, dia, e.Inicio.Hour(), e.Inicio.Minute(), e.Inicio.Second(), 0, e.Inicio.Location())
+	}
+	return e.Inicio
+}
+
+func ultimoDiaDoMes(ano int, mes time.Month, local *time.Location) int {
+	// o dia 0 do mês seguinte é o último dia deste
+	return time.Date(ano, mes+1, 0, 0, 0, 0, 0, local).Day()
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var inicioAgendamentos = time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+
+// contasAgendamento abre duas contas num razão novo.
+func contasAgendamento(t *testing.T, saldoA, saldoB int) (*Conta, *Conta) {
+	t.Helper()
+	razao := NovoRazao(nil)
+	a, err := NovaContaNoRazao(razao, "Ana", saldoA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NovaContaNoRazao(razao, "Bia", saldoB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, b
+}
+
+func agendar(t *testing.T, agendador *Agendador, ag Agendamento) string {
+	t.Helper()
+	id, err := agendador.Agendar(ag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// rodarDias chama Executar uma vez por dia, como um cron diário.
+func rodarDias(agendador *Agendador, relogio *RelogioFake, dias int) {
+	for range dias {
+		agendador.Executar()
+		relogio.Avancar(24 * time.Hour)
+	}
+}
+
+func TestAgendadorExecutaOcorrenciasEmOrdemDeHorario(t *testing.T) {
+	relogio := NovoRelogioFake(inicioAgendamentos)
+	agendador := NovoAgendador(relogio, Backoff{MaxTentativas: 1})
+	a, b := contasAgendamento(t, 0, 100)
+	diario := agendar(t, agendador, Agendamento{Origem: a, Destino: b, Valor: 100, Frequencia: Diaria, Inicio: inicioAgendamentos})
+	unico := agendar(t, agendador, Agendamento{Origem: b, Destino: a, Valor: 100, Frequencia: Unica, Inicio: inicioAgendamentos.Add(23 * time.Hour)})
+
+	// o relógio pula três dias de uma vez
+	relogio.Avancar(72 * time.Hour)
+	execucoes := agendador.Executar()
+
+	esperadas := []struct {
+		id     string
+		dia    int
+		status StatusExecucao
+	}{
+		{diario, 1, ExecucaoFalha},   // Ana ainda sem saldo
+		{unico, 2, ExecucaoSucesso},  // 02/01 às 8h: Bia manda 100 para Ana
+		{diario, 2, ExecucaoSucesso}, // 02/01 às 9h: Ana já tem saldo
+		{diario, 3, ExecucaoFalha},
+		{diario, 4, ExecucaoFalha},
+	}
+	if len(execucoes) != len(esperadas) {
+		t.Fatalf("%d execuções, quero %d: %+v", len(execucoes), len(esperadas), execucoes)
+	}
+	for i, e := range esperadas {
+		got := execucoes[i]
+		if got.AgendamentoID != e.id || got.Prevista.Day() != e.dia || got.Status != e.status {
+			t.Errorf("execução %d = %s dia %d %s, quero %s dia %d %s",
+				i, got.AgendamentoID, got.Prevista.Day(), got.Status, e.id, e.dia, e.status)
+		}
+	}
+	if a.Saldo() != 0 || b.Saldo() != 100 {
+		t.Errorf("saldos = %d e %d, quero 0 e 100", a.Saldo(), b.Saldo())
+	}
+}
+
+func TestAgendadorMensalNoDia31(t *testing.T) {
+	inicio := time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC)
+	relogio := NovoRelogioFake(inicio)
+	agendador := NovoAgendador(relogio, Backoff{})
+	a, b := contasAgendamento(t, 100_000, 0)
+	agendar(t, agendador, Agendamento{Origem: a, Destino: b, Valor: 1000, Frequencia: Mensal, DiaDoMes: 31, Inicio: inicio})
+
+	rodarDias(agendador, relogio, 121)
+
+	var datas []string
+	for _, e := range agendador.Historico() {
+		datas = append(datas, e.Prevista.Format("2006-01-02"))
+	}
+	esperadas := []string{"2026-01-31", "2026-02-28", "2026-03-31", "2026-04-30", "2026-05-31"}
+	if len(datas) != len(esperadas) {
+		t.Fatalf("ocorrências = %v, quero %v", datas, esperadas)
+	}
+	for i := range esperadas {
+		if datas[i] != esperadas[i] {
+			t.Errorf("ocorrência %d = %s, quero %s", i, datas[i], esperadas[i])
+		}
+	}
+}
+
+func TestAgendadorBackoffDobraAteOMaximo(t *testing.T) {
+	relogio := NovoRelogioFake(inicioAgendamentos)
+	agendador := NovoAgendador(relogio, Backoff{Inicial: time.Hour, Maximo: 3 * time.Hour, MaxTentativas: 4})
+	a, b := contasAgendamento(t, 0, 0)
+	id := agendar(t, agendador, Agendamento{Origem: a, Destino: b, Valor: 100, Frequencia: Unica, Inicio: inicioAgendamentos})
+
+	// esperas de 1h, 2h e 3h (4h limitada ao máximo)
+	for tentativa, espera := range []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour} {
+		execucoes := agendador.Executar()
+		if len(execucoes) != 1 || execucoes[0].Status != ExecucaoReagendada || execucoes[0].Tentativa != tentativa+1 {
+			t.Fatalf("tentativa %d: %+v", tentativa+1, execucoes)
+		}
+		var semSaldo *ErrSaldoInsuficiente
+		if !errors.As(execucoes[0].Erro, &semSaldo) {
+			t.Errorf("tentativa %d: erro = %v, quero saldo insuficiente", tentativa+1, execucoes[0].Erro)
+		}
+		proxima, ativo, err := agendador.ProximaExecucao(id)
+		if err != nil || !ativo || !proxima.Equal(relogio.Agora().Add(espera)) {
+			t.Fatalf("tentativa %d: próxima = %v, %v, %v; quero daqui a %v", tentativa+1, proxima, ativo, err, espera)
+		}
+		// antes da hora, nada sai
+		relogio.Avancar(espera - time.Minute)
+		if execucoes := agendador.Executar(); len(execucoes) != 0 {
+			t.Fatalf("tentativa %d saiu antes da espera: %+v", tentativa+1, execucoes)
+		}
+		relogio.Avancar(time.Minute)
+	}
+
+	execucoes := agendador.Executar()
+	if len(execucoes) != 1 || execucoes[0].Status != ExecucaoFalha || execucoes[0].Tentativa != 4 {
+		t.Fatalf("última tentativa: %+v", execucoes)
+	}
+	if _, ativo, _ := agendador.ProximaExecucao(id); ativo {
+		t.Error("agendamento único continua ativo depois de desistir")
+	}
+}
+
+func TestAgendadorDesisteESegueParaAProximaOcorrencia(t *testing.T) {
+	relogio := NovoRelogioFake(inicioAgendamentos)
+	agendador := NovoAgendador(relogio, Backoff{Inicial: time.Hour, MaxTentativas: 2})
+	a, b := contasAgendamento(t, 0, 0)
+	id := agendar(t, agendador, Agendamento{Origem: a, Destino: b, Valor: 100, Frequencia: Diaria, Inicio: inicioAgendamentos})
+
+	agendador.Executar()
+	relogio.Avancar(time.Hour)
+	execucoes := agendador.Executar()
+	if len(execucoes) != 1 || execucoes[0].Status != ExecucaoFalha || execucoes[0].Tentativa != 2 {
+		t.Fatalf("segunda tentativa: %+v", execucoes)
+	}
+	proxima, ativo, _ := agendador.ProximaExecucao(id)
+	if !ativo || !proxima.Equal(inicioAgendamentos.AddDate(0, 0, 1)) {
+		t.Fatalf("próxima = %v (ativo %v), quero a ocorrência do dia seguinte", proxima, ativo)
+	}
+
+	// a ocorrência seguinte começa a contagem de tentativas do zero
+	if err := a.Depositar(100); err != nil {
+		t.Fatal(err)
+	}
+	relogio.Avancar(23 * time.Hour)
+	execucoes = agendador.Executar()
+	if len(execucoes) != 1 || execucoes[0].Status != ExecucaoSucesso || execucoes[0].Tentativa != 1 {
+		t.Fatalf("ocorrência seguinte: %+v", execucoes)
+	}
+}
+
+func TestAgendadorFimECancelar(t *testing.T) {
+	relogio := NovoRelogioFake(inicioAgendamentos)
+	agendador := NovoAgendador(relogio, Backoff{})
+	a, b := contasAgendamento(t, 100_000, 0)
+	comFim := agendar(t, agendador, Agendamento{Origem: a, Destino: b, Valor: 100, Frequencia: Diaria,
+		Inicio: inicioAgendamentos, Fim: inicioAgendamentos.AddDate(0, 0, 2)})
+	cancelado := agendar(t, agendador, Agendamento{Origem: a, Destino: b, Valor: 1, Frequencia: Semanal, Inicio: inicioAgendamentos})
+
+	agendador.Executar()
+	if err := agendador.Cancelar(cancelado); err != nil {
+		t.Fatal(err)
+	}
+	relogio.Avancar(24 * time.Hour)
+	rodarDias(agendador, relogio, 14)
+
+	porID := map[string]int{}
+	for _, e := range agendador.Historico() {
+		porID[e.AgendamentoID]++
+	}
+	if porID[comFim] != 3 {
+		t.Errorf("agendamento com fim rodou %d vezes, quero 3 (o fim é inclusivo)", porID[comFim])
+	}
+	if porID[cancelado] != 1 {
+		t.Errorf("agendamento cancelado rodou %d vezes, quero 1", porID[cancelado])
+	}
+	if _, ativo, _ := agendador.ProximaExecucao(comFim); ativo {
+		t.Error("agendamento continua ativo depois do fim")
+	}
+	if err := agendador.Cancelar("agendamento-999"); !errors.Is(err, ErrAgendamentoNaoEncontrado) {
+		t.Errorf("Cancelar de id desconhecido = %v, quero ErrAgendamentoNaoEncontrado", err)
+	}
+}
+
+func TestAgendadorHistorico(t *testing.T) {
+	relogio := NovoRelogioFake(inicioAgendamentos)
+	agendador := NovoAgendador(relogio, Backoff{})
+	a, b := contasAgendamento(t, 1_000, 0)
+	agendar(t, agendador, Agendamento{Origem: a, Destino: b, Valor: 100, Frequencia: Diaria, Inicio: inicioAgendamentos})
+
+	rodarDias(agendador, relogio, 3)
+	historico := agendador.Historico()
+	if len(historico) != 3 {
+		t.Fatalf("histórico com %d execuções, quero 3", len(historico))
+	}
+	for i, e := range historico {
+		if prevista := inicioAgendamentos.AddDate(0, 0, i); !e.Prevista.Equal(prevista) || !e.Executada.Equal(prevista) {
+			t.Errorf("execução %d: prevista %v, executada %v; quero %v", i, e.Prevista, e.Executada, prevista)
+		}
+	}
+
+	// Historico devolve uma cópia
+	historico[0].Status = ExecucaoFalha
+	if agendador.Historico()[0].Status != ExecucaoSucesso {
+		t.Error("alterar o histórico devolvido alterou o do agendador")
+	}
+}
+
+func TestAgendarValida(t *testing.T) {
+	agendador := NovoAgendador(NovoRelogioFake(inicioAgendamentos), Backoff{})
+	a, b := contasAgendamento(t, 0, 0)
+	casos := []struct {
+		nome string
+		ag   Agendamento
+		erro error
+	}{
+		{"valor zero", Agendamento{Origem: a, Destino: b}, ErrValorInvalido},
+		{"sem destino", Agendamento{Origem: a, Valor: 1}, ErrAgendamentoSemContas},
+		{"dia 0", Agendamento{Origem: a, Destino: b, Valor: 1, Frequencia: Mensal}, ErrDiaDoMesInvalido},
+		{"dia 32", Agendamento{Origem: a, Destino: b, Valor: 1, Frequencia: Mensal, DiaDoMes: 32}, ErrDiaDoMesInvalido},
+	}
+	for _, c := range casos {
+		if _, err := agendador.Agendar(c.ag); !errors.Is(err, c.erro) {
+			t.Errorf("%s: erro = %v, quero %v", c.nome, err, c.erro)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+// Corpos das requisições e respostas da API. Valores em centavos (ou na
+// menor unidade da moeda da conta).
+type (
+	AbrirContaRequisicao struct {
+		Titular      string `json:"titular"`
+		SaldoInicial int    `json:"saldo_inicial"`
+		Moeda        string `json:"moeda,omitempty"`
+	}
+	ValorRequisicao struct {
+		Valor int `json:"valor"`
+	}
+	TransferenciaRequisicao struct {
+		Origem  string `json:"origem"`
+		Destino string `json:"destino"`
+		Valor   int    `json:"valor"`
+	}
+	ContaResposta struct {
+		ID      string `json:"id"`
+		Titular string `json:"titular"`
+		Moeda   string `json:"moeda"`
+		Saldo   int    `json:"saldo"`
+	}
+	TransferenciaResposta struct {
+		Origem     string `json:"origem"`
+		Destino    string `json:"destino"`
+		Valor      int    `json:"valor"`
+		SaldoFinal int    `json:"saldo_origem"`
+	}
+	ErroResposta struct {
+		Codigo string `json:"codigo"`
+		Erro   string `json:"erro"`
+	}
+)
+
+// rota descreve um endpoint. A mesma tabela registra os handlers e gera o
+// documento OpenAPI, então a documentação não tem como ficar para trás.
+type rota struct {
+	metodo     string
+	caminho    string
+	resumo     string
+	requisicao any
+	resposta   any
+	status     int
+	parametros []parametroConsulta
+	// conteudos são os tipos de conteúdo da resposta além de JSON, quando
+	// a rota sabe gerar outros formatos.
+	conteudos []string
+	handler   http.HandlerFunc
+}
+
+type parametroConsulta struct {
+	nome      string
+	descricao string
+}
+
+// Servidor expõe as contas de um razão por HTTP. As operações que alteram
+// saldo aceitam o cabeçalho Idempotency-Key.
+type Servidor struct {
+	razao        *Razao
+	idempotencia *Idempotencia
+	rotas        []rota
+	mux          *http.ServeMux
+
+	mu     sync.RWMutex
+	contas map[string]*Conta
+}
+
+func NovoServidor(razao *Razao, idempotencia *Idempotencia) *Servidor {
+	s := &Servidor{
+		razao:        razao,
+		idempotencia: idempotencia,
+		contas:       map[string]*Conta{},
+		mux:          http.NewServeMux(),
+	}
+	s.rotas = []rota{
+		{metodo: "POST", caminho: "/contas", resumo: "Abre uma conta",
+			requisicao: AbrirContaRequisicao{}, resposta: ContaResposta{}, status: http.StatusCreated, handler: s.abrirConta},
+		{metodo: "GET", caminho: "/contas/{id}", resumo: "Consulta o saldo de uma conta",
+			resposta: ContaResposta{}, status: http.StatusOK, handler: s.consultarConta},
+		{metodo: "POST", caminho: "/contas/{id}/depositos", resumo: "Deposita na conta",
+			requisicao: ValorRequisicao{}, resposta: ContaResposta{}, status: http.StatusOK, handler: s.depositar},
+		{metodo: "POST", caminho: "/contas/{id}/saques", resumo: "Saca da conta",
+			requisicao: ValorRequisicao{}, resposta: ContaResposta{}, status: http.StatusOK, handler: s.sacar},
+		{metodo: "POST", caminho: "/transferencias", resumo: "Transfere entre duas contas",
+			requisicao: TransferenciaRequisicao{}, resposta: TransferenciaResposta{}, status: http.StatusOK, handler: s.transferir},
+		{metodo: "GET", caminho: "/contas/{id}/extrato", resumo: "Extrato da conta no período",
+			resposta: Extrato{}, status: http.StatusOK, handler: s.extrato,
+			parametros: []parametroConsulta{
+				{"inicio", "data inicial (AAAA-MM-DD)"},
+				{"fim", "data final, inclusive (AAAA-MM-DD)"},
+				{"formato", "json (padrão), texto, csv ou ofx"},
+			},
+			conteudos: []string{tiposExtrato[ExtratoTexto], tiposExtrato[ExtratoCSV], tiposExtrato[ExtratoOFX]}},
+	}
+	for _, r := range s.rotas {
+		s.mux.HandleFunc(r.metodo+" "+r.caminho, r.handler)
+	}
+	s.mux.HandleFunc("GET /openapi.json", s.openAPI)
+	return s
+}
+
+func (s *Servidor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Servidor) abrirConta(w http.ResponseWriter, r *http.Request) {
+	var req AbrirContaRequisicao
+	if !lerJSON(w, r, &req) {
+		return
+	}
+	moeda := Moeda(req.Moeda)
+	if moeda == "" {
+		moeda = BRL
+	}
+
+	resultado, err := s.idempotente(r, fmt.Sprintf("abrir|%s|%s|%d", req.Titular, moeda, req.SaldoInicial), func() (ResultadoOperacao, error) {
+		conta, err := NovaContaEmMoeda(s.razao, req.Titular, moeda, req.SaldoInicial)
+		if err != nil {
+			return ResultadoOperacao{}, err
+		}
+		s.mu.Lock()
+		s.contas[conta.ID()] = conta
+		s.mu.Unlock()
+		return ResultadoOperacao{Saldo: conta.Saldo(), Referencia: conta.ID()}, nil
+	})
+	if err != nil {
+		escreverErro(w, err)
+		return
+	}
+	conta, _ := s.conta(resultado.Referencia)
+	escreverJSON(w, http.StatusCreated, respostaConta(conta, resultado.Saldo))
+}
+
+func (s *Servidor) consultarConta(w http.ResponseWriter, r *http.Request) {
+	conta, err := s.conta(r.PathValue("id"))
+	if err != nil {
+		escreverErro(w, err)
+		return
+	}
+	escreverJSON(w, http.StatusOK, respostaConta(conta, conta.Saldo()))
+}
+
+func (s *Servidor) depositar(w http.ResponseWriter, r *http.Request) {
+	s.movimentar(w, r, "depositar", (*Conta).depositar)
+}
+
+func (s *Servidor) sacar(w http.ResponseWriter, r *http.Request) {
+	s.movimentar(w, r, "sacar", (*Conta).sacar)
+}
+
+func (s *Servidor) movimentar(w http.ResponseWriter, r *http.Request, nome string, operacao func(*Conta, int) (int, error)) {
+	conta, err := s.conta(r.PathValue("id"))
+	if err != nil {
+		escreverErro(w, err)
+		return
+	}
+	var req ValorRequisicao
+	if !lerJSON(w, r, &req) {
+		return
+	}
+
+	resultado, err := s.idempotente(r, fmt.Sprintf("%s|%s|%d", nome, conta.ID(), req.Valor), func() (ResultadoOperacao, error) {
+		saldo, err := operacao(conta, req.Valor)
+		return ResultadoOperacao{Saldo: saldo}, err
+	})
+	if err != nil {
+		escreverErro(w, err)
+		return
+	}
+	escreverJSON(w, http.StatusOK, respostaConta(conta, resultado.Saldo))
+}
+
+func (s *Servidor) transferir(w http.ResponseWriter, r *http.Request) {
+	var req TransferenciaRequisicao
+	if !lerJSON(w, r, &req) {
+		return
+	}
+	origem, err := s.conta(req.Origem)
+	if err != nil {
+		escreverErro(w, err)
+		return
+	}
+	destino, err := s.conta(req.Destino)
+	if err != nil {
+		escreverErro(w, err)
+		return
+	}
+
+	resultado, err := s.idempotente(r, fmt.Sprintf("transferir|%s|%s|%d", origem.ID(), destino.ID(), req.Valor), func() (ResultadoOperacao, error) {
+		saldo, err := transferir(origem, destino, req.Valor, OperacaoTransferencia, "transferência")
+		return ResultadoOperacao{Saldo: saldo}, err
+	})
+	if err != nil {
+		escreverErro(w, err)
+		return
+	}
+	escreverJSON(w, http.StatusOK, TransferenciaResposta{
+		Origem:     origem.ID(),
+		Destino:    destino.ID(),
+		Valor:      req.Valor,
+		SaldoFinal: resultado.Saldo,
+	})
+}
+
+func (s *Servidor) extrato(w http.ResponseWriter, r *http.Request) {
+	conta, err := s.conta(r.PathValue("id"))
+	if err != nil {
+		escreverErro(w, err)
+		return
+	}
+
+	hoje := time.Now()
+	inicio := time.Date(hoje.Year(), hoje.Month(), 1, 0, 0, 0, 0, hoje.Location())
+	fim := hoje
+	consulta := r.URL.Query()
+	if texto := consulta.Get("inicio"); texto != "" {
+		if inicio, err = time.ParseInLocation(time.DateOnly, texto, time.Local); err != nil {
+			escreverErro(w, fmt.Errorf("%w: inicio %q", errRequisicaoInvalida, texto))
+			return
+		}
+	}
+	if texto := consulta.Get("fim"); texto != "" {
+		dia, err := time.ParseInLocation(time.DateOnly, texto, time.Local)
+		if err != nil {
+			escreverErro(w, fmt.Errorf("%w: fim %q", errRequisicaoInvalida, texto))
+			return
+		}
+		fim = dia.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	formato := FormatoExtrato(consulta.Get("formato"))
+	if formato == "" {
+		formato = ExtratoJSON
+	}
+	tipo, ok := tiposExtrato[formato]
+	if !ok {
+		escreverErro(w, fmt.Errorf("%w: formato %q", errRequisicaoInvalida, formato))
+		return
+	}
+	// O extrato é gerado inteiro antes de responder: se a geração falhar,
+	// ainda dá para mandar um erro em vez de um 200 com o corpo pela metade.
+	var corpo bytes.Buffer
+	if err := conta.Extrato(inicio, fim).Escrever(&corpo, formato); err != nil {
+		escreverErro(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", tipo)
+	w.Write(corpo.Bytes())
+}
+
+// tiposExtrato é o Content-Type de cada formato de extrato. O documento
+// OpenAPI usa a mesma tabela.
+var tiposExtrato = map[FormatoExtrato]string{
+	ExtratoJSON:  "application/json",
+	ExtratoTexto: "text/plain; charset=utf-8",
+	ExtratoCSV:   "text/csv; charset=utf-8",
+	ExtratoOFX:   "application/x-ofx",
+}
+
+func (s *Servidor) conta(id string) (*Conta, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	conta, ok := s.contas[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrContaNaoEncontrada, id)
+	}
+	return conta, nil
+}
+
+// idempotente roda a operação através do registro de idempotência quando a
+// requisição traz o cabeçalho Idempotency-Key; sem ele, roda direto.
+func (s *Servidor) idempotente(r *http.Request, impressao string, op func() (ResultadoOperacao, error)) (ResultadoOperacao, error) {
+	chave := r.Header.Get("Idempotency-Key")
+	if chave == "" {
+		return op()
+	}
+	return s.idempotencia.Executar(chave, impressao, op)
+}
+
+func respostaConta(conta *Conta, saldo int) ContaResposta {
+	return ContaResposta{ID: conta.ID(), Titular: conta.Titular(), Moeda: string(conta.Moeda()), Saldo: saldo}
+}
+
+var errRequisicaoInvalida = errors.New("requisição inválida")
+
+func lerJSON(w http.ResponseWriter, r *http.Request, destino any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(destino); err != nil {
+		escreverErro(w, fmt.Errorf("%w: %v", errRequisicaoInvalida, err))
+		return false
+	}
+	return true
+}
+
+func escreverJSON(w http.ResponseWriter, status int, corpo any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(corpo)
+}
+
+// escreverErro traduz os erros do domínio em status HTTP.
+func escreverErro(w http.ResponseWriter, err error) {
+	var (
+		negada    *ErrOperacaoNegada
+		comCodigo *erros.Erro
+		status    int
+		codigo    string
+	)
+	switch {
+	case errors.As(err, &negada):
+		// negar é uma decisão de política, não um dado inválido
+		status, codigo = http.StatusForbidden, "regras.operacao_negada"
+	case errors.Is(err, errRequisicaoInvalida):
+		status, codigo = http.StatusBadRequest, "requisicao_invalida"
+	case errors.As(err, &comCodigo):
+		// os erros do domínio têm código do pacote erros: a categoria dá o
+		// status (a revisão, por exemplo, é Conflito e vira 409)
+		status, codigo = comCodigo.Codigo.Categoria.StatusHTTP(), comCodigo.Codigo.ID
+	default:
+		status, codigo = http.StatusInternalServerError, "erro_interno"
+	}
+	escreverJSON(w, status, ErroResposta{Codigo: codigo, Erro: err.Error()})
+}
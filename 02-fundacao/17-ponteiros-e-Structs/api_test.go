@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIStatusDosErros(t *testing.T) {
+	api := NovoServidor(NovoRazao(nil), NovaIdempotencia(nil, time.Hour))
+	enviar := func(metodo, caminho, corpo string) (int, ErroResposta) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, httptest.NewRequest(metodo, caminho, strings.NewReader(corpo)))
+		var resposta ErroResposta
+		json.Unmarshal(rec.Body.Bytes(), &resposta)
+		return rec.Code, resposta
+	}
+
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, httptest.NewRequest("POST", "/contas", strings.NewReader(`{"titular":"Ana","saldo_inicial":100}`)))
+	var conta ContaResposta
+	if err := json.Unmarshal(rec.Body.Bytes(), &conta); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("abrir conta: status %d, %v", rec.Code, err)
+	}
+
+	casos := []struct {
+		nome, metodo, caminho, corpo string
+		status                       int
+		codigo                       string
+	}{
+		{"conta inexistente", "GET", "/contas/nada", "", http.StatusNotFound, "conta.nao_encontrada"},
+		{"saldo insuficiente", "POST", "/contas/" + conta.ID + "/saques", `{"valor":500}`, http.StatusUnprocessableEntity, "conta.saldo_insuficiente"},
+		{"valor inválido", "POST", "/contas/" + conta.ID + "/depositos", `{"valor":0}`, http.StatusUnprocessableEntity, "conta.valor_invalido"},
+		{"json malformado", "POST", "/contas/" + conta.ID + "/depositos", `{`, http.StatusBadRequest, "requisicao_invalida"},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			status, resposta := enviar(c.metodo, c.caminho, c.corpo)
+			if status != c.status || resposta.Codigo != c.codigo {
+				t.Errorf("status %d, código %q; quero %d, %q (%s)", status, resposta.Codigo, c.status, c.codigo, resposta.Erro)
+			}
+		})
+	}
+}
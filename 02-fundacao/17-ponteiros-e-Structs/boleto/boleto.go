@@ -0,0 +1,261 @@
+// Package boleto gera e valida o código de barras (44 dígitos) e a linha
+// digitável (47 dígitos) de boletos de cobrança no padrão FEBRABAN.
+package boleto
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+var (
+	ErrFormato           = erros.Definir("boleto.formato_invalido", erros.Validacao).Sentinela("boleto: formato inválido")
+	ErrDigitoVerificador = erros.Definir("boleto.digito_verificador", erros.Validacao).Sentinela("boleto: dígito verificador inválido")
+	ErrVencimento        = erros.Definir("boleto.vencimento", erros.Validacao).Sentinela("boleto: vencimento fora da faixa do fator")
+	ErrValor             = erros.Definir("boleto.valor", erros.Validacao).Sentinela("boleto: valor fora da faixa")
+)
+
+var (
+	// dataBase é o dia zero do fator de vencimento.
+	dataBase = time.Date(1997, 10, 7, 0, 0, 0, 0, time.UTC)
+	// reinicioFator é o dia em que o fator chegou a 10000 e voltou a 1000.
+	reinicioFator = time.Date(2025, 2, 22, 0, 0, 0, 0, time.UTC)
+)
+
+// Boleto traz os dados que vão no código de barras. Valor em centavos.
+// Moeda é o dígito da moeda ('9' para real, o padrão quando zero).
+// Vencimento zero gera fator 0000 (boleto sem vencimento). CampoLivre tem
+// 25 dígitos e seu conteúdo é definido por cada banco.
+type Boleto struct {
+	Banco      string
+	Moeda      byte
+	Vencimento time.Time
+	Valor      int64
+	CampoLivre string
+}
+
+// CodigoDeBarras monta os 44 dígitos: banco, moeda, DV geral, fator de
+// vencimento, valor e campo livre.
+func (b Boleto) CodigoDeBarras() (string, error) {
+	if len(b.Banco) != 3 || !digitos(b.Banco) {
+		return "", fmt.Errorf("%w: banco %q", ErrFormato, b.Banco)
+	}
+	if len(b.CampoLivre) != 25 || !digitos(b.CampoLivre) {
+		return "", fmt.Errorf("%w: campo livre precisa de 25 dígitos", ErrFormato)
+	}
+	if b.Valor < 0 || b.Valor > 99_999_999_99 {
+		return "", ErrValor
+	}
+	moeda := b.Moeda
+	if moeda == 0 {
+		moeda = '9'
+	}
+	if moeda < '0' || moeda > '9' {
+		return "", fmt.Errorf("%w: moeda %q", ErrFormato, moeda)
+	}
+	fator := 0
+	if !b.Vencimento.IsZero() {
+		var err error
+		if fator, err = FatorVencimento(b.Vencimento); err != nil {
+			return "", err
+		}
+	}
+
+	semDV := fmt.Sprintf("%s%c%04d%010d%s", b.Banco, moeda, fator, b.Valor, b.CampoLivre)
+	return semDV[:4] + strconv.Itoa(modulo11(semDV)) + semDV[4:], nil
+}
+
+// LinhaDigitavel monta os 47 dígitos da linha digitável, sem pontuação.
+// Use Formatar para a forma impressa no boleto.
+func (b Boleto) LinhaDigitavel() (string, error) {
+	codigo, err := b.CodigoDeBarras()
+	if err != nil {
+		return "", err
+	}
+	return LinhaDoCodigo(codigo)
+}
+
+// LinhaDoCodigo converte um código de barras válido na linha digitável.
+func LinhaDoCodigo(codigo string) (string, error) {
+	if err := ValidarCodigoDeBarras(codigo); err != nil {
+		return "", err
+	}
+	livre := codigo[19:44]
+	campo1 := codigo[0:4] + livre[0:5]
+	campo2 := livre[5:15]
+	campo3 := livre[15:25]
+	return campo1 + strconv.Itoa(modulo10(campo1)) +
+		campo2 + strconv.Itoa(modulo10(campo2)) +
+		campo3 + strconv.Itoa(modulo10(campo3)) +
+		codigo[4:5] + codigo[5:19], nil
+}
+
+// CodigoDaLinha converte a linha digitável (com ou sem pontuação) de volta
+// no código de barras, conferindo os dígitos verificadores de cada campo.
+func CodigoDaLinha(linha string) (string, error) {
+	linha = limpar(linha)
+	if len(linha) != 47 || !digitos(linha) {
+		return "", fmt.Errorf("%w: a linha digitável tem 47 dígitos", ErrFormato)
+	}
+	campos := []struct{ dados, dv string }{
+		{linha[0:9], linha[9:10]},
+		{linha[10:20], linha[20:21]},
+		{linha[21:31], linha[31:32]},
+	}
+	for i, c := range campos {
+		if strconv.Itoa(modulo10(c.dados)) != c.dv {
+			return "", fmt.Errorf("%w: campo %d", ErrDigitoVerificador, i+1)
+		}
+	}
+
+	codigo := linha[0:4] + linha[32:33] + linha[33:47] + linha[4:9] + linha[10:20] + linha[21:31]
+	if err := ValidarCodigoDeBarras(codigo); err != nil {
+		return "", err
+	}
+	return codigo, nil
+}
+
+// ValidarCodigoDeBarras confere o tamanho e o DV geral.
+func ValidarCodigoDeBarras(codigo string) error {
+	if len(codigo) != 44 || !digitos(codigo) {
+		return fmt.Errorf("%w: o código de barras tem 44 dígitos", ErrFormato)
+	}
+	if strconv.Itoa(modulo11(codigo[:4]+codigo[5:])) != codigo[4:5] {
+		return fmt.Errorf("%w: DV geral", ErrDigitoVerificador)
+	}
+	return nil
+}
+
+// LerCodigoDeBarras extrai os dados de um código de barras. Como o fator de
+// vencimento se repete a cada ciclo, referencia (normalmente hoje) escolhe o
+// vencimento mais próximo dela.
+func LerCodigoDeBarras(codigo string, referencia time.Time) (Boleto, error) {
+	if err := ValidarCodigoDeBarras(codigo); err != nil {
+		return Boleto{}, err
+	}
+	fator, _ := strconv.Atoi(codigo[5:9])
+	valor, _ := strconv.ParseInt(codigo[9:19], 10, 64)
+	b := Boleto{
+		Banco:      codigo[0:3],
+		Moeda:      codigo[3],
+		Valor:      valor,
+		CampoLivre: codigo[19:44],
+	}
+	if fator != 0 {
+		b.Vencimento = DataDoFator(fator, referencia)
+	}
+	return b, nil
+}
+
+// LerLinhaDigitavel valida a linha e extrai os dados do boleto.
+func LerLinhaDigitavel(linha string, referencia time.Time) (Boleto, error) {
+	codigo, err := CodigoDaLinha(linha)
+	if err != nil {
+		return Boleto{}, err
+	}
+	return LerCodigoDeBarras(codigo, referencia)
+}
+
+// Formatar pontua a linha digitável como ela aparece impressa:
+// AAAAA.AAAAA BBBBB.BBBBBB CCCCC.CCCCCC D EEEEEEEEEEEEEE.
+func Formatar(linha string) string {
+	linha = limpar(linha)
+	if len(linha) != 47 {
+		return linha
+	}
+	return fmt.Sprintf("%s.%s %s.%s %s.%s %s %s",
+		linha[0:5], linha[5:10], linha[10:15], linha[15:21], linha[21:26], linha[26:32], linha[32:33], linha[33:47])
+}
+
+// FatorVencimento é o número de dias desde 07/10/1997. Ao chegar a 10000,
+// em 22/02/2025, o fator voltou a 1000, então ele só vai de 1000 a 9999.
+func FatorVencimento(vencimento time.Time) (int, error) {
+	dia := time.Date(vencimento.Year(), vencimento.Month(), vencimento.Day(), 0, 0, 0, 0, time.UTC)
+	var fator int
+	if dia.Before(reinicioFator) {
+		fator = dias(dataBase, dia)
+	} else {
+		fator = 1000 + dias(reinicioFator, dia)
+	}
+	if fator < 1000 || fator > 9999 {
+		return 0, fmt.Errorf("%w: %s", ErrVencimento, vencimento.Format(time.DateOnly))
+	}
+	return fator, nil
+}
+
+// DataDoFator devolve, entre os ciclos possíveis, o vencimento com esse
+// fator mais próximo da data de referência.
+func DataDoFator(fator int, referencia time.Time) time.Time {
+	candidatos := []time.Time{
+		dataBase.AddDate(0, 0, fator),
+		reinicioFator.AddDate(0, 0, fator-1000),
+	}
+	for ciclo := 1; candidatos[len(candidatos)-1].Before(referencia); ciclo++ {
+		candidatos = append(candidatos, reinicioFator.AddDate(0, 0, fator-1000+9000*ciclo))
+	}
+
+	melhor := candidatos[0]
+	for _, c := range candidatos[1:] {
+		if distancia(c, referencia) < distancia(melhor, referencia) {
+			melhor = c
+		}
+	}
+	return melhor
+}
+
+// modulo10 calcula o DV dos campos da linha digitável: pesos 2 e 1 da
+// direita para a esquerda, somando os algarismos de cada produto.
+func modulo10(numero string) int {
+	soma, peso := 0, 2
+	for i := len(numero) - 1; i >= 0; i-- {
+		produto := int(numero[i]-'0') * peso
+		soma += produto/10 + produto%10
+		peso = 3 - peso
+	}
+	return (10 - soma%10) % 10
+}
+
+// modulo11 calcula o DV geral do código de barras: pesos de 2 a 9 da
+// direita para a esquerda. Resultados 0, 10 e 11 viram 1.
+func modulo11(numero string) int {
+	soma, peso := 0, 2
+	for i := len(numero) - 1; i >= 0; i-- {
+		soma += int(numero[i]-'0') * peso
+		peso++
+		if peso > 9 {
+			peso = 2
+		}
+	}
+	dv := 11 - soma%11
+	if dv == 0 || dv == 10 || dv == 11 {
+		return 1
+	}
+	return dv
+}
+
+func dias(de, ate time.Time) int {
+	return int(ate.Sub(de).Hours() / 24)
+}
+
+func distancia(a, b time.Time) time.Duration {
+	if a.After(b) {
+		return a.Sub(b)
+	}
+	return b.Sub(a)
+}
+
+func digitos(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func limpar(linha string) string {
+	return strings.NewReplacer(".", "", " ", "", "-", "").Replace(strings.TrimSpace(linha))
+}
@@ -0,0 +1,250 @@
+package boleto
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Linha de um boleto do Banco do Brasil publicada como exemplo: R$ 1,00 com
+// fator 3737 (31/12/2007).
+const (
+	linhaPublicada  = "00190.50095 40144.816069 06809.350314 3 37370000000100"
+	codigoPublicado = "00193373700000001000500940144816060680935031"
+)
+
+func data(ano int, mes time.Month, dia int) time.Time {
+	return time.Date(ano, mes, dia, 0, 0, 0, 0, time.UTC)
+}
+
+func TestLinhaPublicada(t *testing.T) {
+	codigo, err := CodigoDaLinha(linhaPublicada)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codigo != codigoPublicado {
+		t.Fatalf("CodigoDaLinha = %s, quero %s", codigo, codigoPublicado)
+	}
+	linha, err := LinhaDoCodigo(codigo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Formatar(linha) != linhaPublicada {
+		t.Errorf("LinhaDoCodigo = %s, quero %s", Formatar(linha), linhaPublicada)
+	}
+
+	b, err := LerLinhaDigitavel(linhaPublicada, data(2008, 1, 15))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Banco != "001" || b.Moeda != '9' || b.Valor != 100 || !b.Vencimento.Equal(data(2007, 12, 31)) {
+		t.Errorf("LerLinhaDigitavel = %+v", b)
+	}
+	// o mesmo fator, lido perto do ciclo seguinte
+	if b, _ := LerLinhaDigitavel(linhaPublicada, data(2032, 6, 1)); !b.Vencimento.Equal(data(2032, 8, 21)) {
+		t.Errorf("vencimento no segundo ciclo = %s, quero 2032-08-21", b.Vencimento.Format(time.DateOnly))
+	}
+
+	// o Boleto com os mesmos dados gera o mesmo código
+	gerado, err := Boleto{Banco: b.Banco, Vencimento: b.Vencimento, Valor: b.Valor, CampoLivre: b.CampoLivre}.CodigoDeBarras()
+	if err != nil || gerado != codigoPublicado {
+		t.Errorf("CodigoDeBarras = %s, %v; quero %s", gerado, err, codigoPublicado)
+	}
+}
+
+func TestLinhaECodigoIdaEVolta(t *testing.T) {
+	aleatorio := rand.New(rand.NewSource(1))
+	for range 500 {
+		var livre strings.Builder
+		for range 25 {
+			livre.WriteByte(byte('0' + aleatorio.Intn(10)))
+		}
+		b := Boleto{
+			Banco:      fmt.Sprintf("%03d", aleatorio.Intn(1000)),
+			Vencimento: data(2025, 2, 22).AddDate(0, 0, aleatorio.Intn(8999)),
+			Valor:      aleatorio.Int63n(99_999_999_99),
+			CampoLivre: livre.String(),
+		}
+		if aleatorio.Intn(10) == 0 {
+			b.Vencimento = time.Time{}
+		}
+		codigo, err := b.CodigoDeBarras()
+		if err != nil {
+			t.Fatalf("%+v: %v", b, err)
+		}
+		linha, err := LinhaDoCodigo(codigo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		volta, err := CodigoDaLinha(Formatar(linha))
+		if err != nil || volta != codigo {
+			t.Fatalf("CodigoDaLinha(LinhaDoCodigo(%s)) = %s, %v", codigo, volta, err)
+		}
+		lido, err := LerCodigoDeBarras(codigo, b.Vencimento)
+		if err != nil || lido.Valor != b.Valor || !lido.Vencimento.Equal(b.Vencimento) || lido.CampoLivre != b.CampoLivre {
+			t.Fatalf("LerCodigoDeBarras(%s) = %+v, %v; quero %+v", codigo, lido, err, b)
+		}
+	}
+}
+
+func TestDigitoVerificadorErradoEmCadaCampo(t *testing.T) {
+	linha := limpar(linhaPublicada)
+	for _, c := range []struct {
+		nome    string
+		posicao int
+	}{
+		{"campo 1", 9},
+		{"campo 2", 20},
+		{"campo 3", 31},
+		{"DV geral", 32},
+	} {
+		alterada := []byte(linha)
+		alterada[c.posicao] = '0' + (alterada[c.posicao]-'0'+1)%10
+		if _, err := CodigoDaLinha(string(alterada)); !errors.Is(err, ErrDigitoVerificador) {
+			t.Errorf("%s alterado: erro = %v, quero ErrDigitoVerificador", c.nome, err)
+		}
+	}
+
+	alterado := []byte(codigoPublicado)
+	alterado[4] = '9'
+	if err := ValidarCodigoDeBarras(string(alterado)); !errors.Is(err, ErrDigitoVerificador) {
+		t.Errorf("código com DV geral errado: %v", err)
+	}
+	if _, err := CodigoDaLinha(linha[:46]); !errors.Is(err, ErrFormato) {
+		t.Errorf("linha com 46 dígitos: %v", err)
+	}
+}
+
+func TestModulo11(t *testing.T) {
+	casos := []struct {
+		numero string
+		dv     int
+	}{
+		{"1", 9},  // 11 - 2
+		{"0", 1},  // resto 0: 11 vira 1
+		{"6", 1},  // 12 % 11 = 1: 10 vira 1
+		{"11", 6}, // 3 + 2 = 5
+		{codigoPublicado[:4] + codigoPublicado[5:], 3},
+	}
+	for _, c := range casos {
+		if got := modulo11(c.numero); got != c.dv {
+			t.Errorf("modulo11(%s) = %d, quero %d", c.numero, got, c.dv)
+		}
+	}
+}
+
+func TestModulo10(t *testing.T) {
+	// os três campos da linha publicada
+	for _, c := range []struct {
+		numero string
+		dv     int
+	}{{"001905009", 5}, {"4014481606", 9}, {"0680935031", 4}} {
+		if got := modulo10(c.numero); got != c.dv {
+			t.Errorf("modulo10(%s) = %d, quero %d", c.numero, got, c.dv)
+		}
+	}
+}
+
+func TestFatorVencimento(t *testing.T) {
+	casos := []struct {
+		dia   time.Time
+		fator int
+	}{
+		{data(2000, 7, 3), 1000},
+		{data(2007, 12, 31), 3737},
+		{data(2025, 2, 21), 9999},
+		{data(2025, 2, 22), 1000},
+		{data(2049, 10, 13), 9999}, // fim do segundo ciclo
+	}
+	for _, c := range casos {
+		fator, err := FatorVencimento(c.dia)
+		if err != nil || fator != c.fator {
+			t.Errorf("FatorVencimento(%s) = %d, %v; quero %d", c.dia.Format(time.DateOnly), fator, err, c.fator)
+		}
+	}
+	for _, dia := range []time.Time{data(2000, 7, 2), data(2049, 10, 14)} {
+		if _, err := FatorVencimento(dia); !errors.Is(err, ErrVencimento) {
+			t.Errorf("FatorVencimento(%s) = %v, quero ErrVencimento", dia.Format(time.DateOnly), err)
+		}
+	}
+}
+
+func TestDataDoFatorEscolheOCicloMaisProximo(t *testing.T) {
+	casos := []struct {
+		fator      int
+		referencia time.Time
+		quero      time.Time
+	}{
+		{1000, data(2001, 1, 1), data(2000, 7, 3)},
+		{1000, data(2025, 3, 1), data(2025, 2, 22)},
+		{9999, data(2025, 3, 1), data(2025, 2, 21)},
+		{1001, data(2025, 1, 1), data(2025, 2, 23)},
+		{3737, data(2032, 6, 1), data(2032, 8, 21)},
+	}
+	for _, c := range casos {
+		if got := DataDoFator(c.fator, c.referencia); !got.Equal(c.quero) {
+			t.Errorf("DataDoFator(%d, %s) = %s, quero %s", c.fator, c.referencia.Format(time.DateOnly),
+				got.Format(time.DateOnly), c.quero.Format(time.DateOnly))
+		}
+	}
+}
+
+func TestCodigoDeBarrasRejeitaDadosInvalidos(t *testing.T) {
+	valido := Boleto{Banco: "001", Valor: 100, CampoLivre: "0000001234567000000012317"}
+	casos := []struct {
+		nome   string
+		altera func(*Boleto)
+		erro   error
+	}{
+		{"moeda não numérica", func(b *Boleto) { b.Moeda = 'X' }, ErrFormato},
+		{"banco curto", func(b *Boleto) { b.Banco = "01" }, ErrFormato},
+		{"campo livre com letra", func(b *Boleto) { b.CampoLivre = "A" + b.CampoLivre[1:] }, ErrFormato},
+		{"valor negativo", func(b *Boleto) { b.Valor = -1 }, ErrValor},
+		{"valor grande demais", func(b *Boleto) { b.Valor = 100_000_000_00 }, ErrValor},
+		{"vencimento antes do fator 1000", func(b *Boleto) { b.Vencimento = data(2000, 1, 1) }, ErrVencimento},
+	}
+	for _, c := range casos {
+		b := valido
+		c.altera(&b)
+		if _, err := b.CodigoDeBarras(); !errors.Is(err, c.erro) {
+			t.Errorf("%s: erro = %v, quero %v", c.nome, err, c.erro)
+		}
+	}
+	if _, err := valido.CodigoDeBarras(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEscreverSVG(t *testing.T) {
+	var svg bytes.Buffer
+	if err := EscreverSVG(&svg, codigoPublicado); err != nil {
+		t.Fatal(err)
+	}
+	texto := svg.String()
+	// fundo + 2 barras de início + 5 por par de dígitos + 2 de fim
+	if n := strings.Count(texto, "<rect "); n != 1+2+22*5+2 {
+		t.Errorf("%d retângulos, quero 115", n)
+	}
+	// 4 de início, 18 unidades por par (cada dígito tem 2 largas e 3
+	// estreitas), 5 de fim e as margens
+	largura := 4 + 22*18 + 5 + 2*margem
+	if !strings.Contains(texto, fmt.Sprintf(`width="%d"`, largura)) {
+		t.Errorf("SVG sem a largura %d:\n%s", largura, texto[:200])
+	}
+	// o par "00" começa com barras e espaços nnwwn/nnwwn
+	if !strings.Contains(texto, `<rect x="10" y="10" width="1" height="50"/>
+<rect x="12" y="10" width="1" height="50"/>
+<rect x="14" y="10" width="1" height="50"/>
+<rect x="16" y="10" width="1" height="50"/>
+<rect x="18" y="10" width="3" height="50"/>`) {
+		t.Errorf("barras de início e do primeiro par erradas:\n%s", texto)
+	}
+
+	if err := EscreverSVG(&svg, codigoPublicado[:43]); !errors.Is(err, ErrFormato) {
+		t.Errorf("código curto: %v", err)
+	}
+}
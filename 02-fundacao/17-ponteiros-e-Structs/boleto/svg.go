@@ -0,0 +1,64 @@
+package boleto
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// padroesI2de5 são as larguras (n = estreita, w = larga) das cinco barras ou
+// espaços de cada dígito no Intercalado 2 de 5.
+var padroesI2de5 = [10]string{
+	"nnwwn", "wnnnw", "nwnnw", "wwnnn", "nnwnw",
+	"wnwnn", "nwwnn", "nnnww", "wnnwn", "nwnwn",
+}
+
+// Dimensões do SVG, em unidades da barra estreita. O padrão FEBRABAN usa a
+// barra larga com três vezes a largura da estreita.
+const (
+	larguraEstreita = 1
+	larguraLarga    = 3
+	alturaBarras    = 50
+	margem          = 10
+)
+
+// EscreverSVG desenha o código de barras em Intercalado 2 de 5 (ITF). Os
+// dígitos são lidos em pares: o primeiro define as barras e o segundo os
+// espaços entre elas.
+func EscreverSVG(w io.Writer, codigo string) error {
+	if err := ValidarCodigoDeBarras(codigo); err != nil {
+		return err
+	}
+
+	// início: barra, espaço, barra, espaço estreitos; fim: barra larga,
+	// espaço e barra estreitos
+	larguras := "nnnn"
+	for i := 0; i < len(codigo); i += 2 {
+		barras, espacos := padroesI2de5[codigo[i]-'0'], padroesI2de5[codigo[i+1]-'0']
+		for j := 0; j < 5; j++ {
+			larguras += string(barras[j]) + string(espacos[j])
+		}
+	}
+	larguras += "wnn"
+
+	var barras strings.Builder
+	x := margem
+	for i, largura := range larguras {
+		tamanho := larguraEstreita
+		if largura == 'w' {
+			tamanho = larguraLarga
+		}
+		if i%2 == 0 {
+			fmt.Fprintf(&barras, `<rect x="%d" y="%d" width="%d" height="%d"/>`+"\n", x, margem, tamanho, alturaBarras)
+		}
+		x += tamanho
+	}
+
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">
+<rect width="100%%" height="100%%" fill="white"/>
+<g fill="black">
+%s</g>
+</svg>
+`, x+margem, alturaBarras+2*margem, x+margem, alturaBarras+2*margem, barras.String())
+	return err
+}
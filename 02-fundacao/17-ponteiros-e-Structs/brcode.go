@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+// IDs dos campos EMV usados no BR Code do Pix (Manual de Padrões para
+// Iniciação do Pix).
+const (
+	campoFormato         = "00"
+	campoContaRecebedor  = "26"
+	campoCategoria       = "52"
+	campoMoeda           = "53"
+	campoValor           = "54"
+	campoPais            = "58"
+	campoNome            = "59"
+	campoCidade          = "60"
+	campoDadosAdicionais = "62"
+	campoCRC             = "63"
+	subcampoGUI          = "00"
+	subcampoChave        = "01"
+	subcampoDescricao    = "02"
+	subcampoTxID         = "05"
+	guiPix               = "br.gov.bcb.pix"
+)
+
+var (
+	ErrBRCodeInvalido = erros.Definir("brcode.invalido", erros.Validacao).Sentinela("BR Code inválido")
+	ErrBRCodeCRC      = erros.Definir("brcode.crc_invalido", erros.Validacao).Sentinela("BR Code com CRC inválido")
+)
+
+// BRCode é o conteúdo de um Pix "copia e cola" estático. Valor em centavos;
+// zero deixa o valor para quem paga.
+type BRCode struct {
+	Chave     string
+	Descricao string
+	Valor     int
+	Nome      string
+	Cidade    string
+	TxID      string
+}
+
+// Gerar monta o payload EMV (campos TLV) com o CRC16 no final.
+func (b BRCode) Gerar() (string, error) {
+	if b.Chave == "" || b.Nome == "" || b.Cidade == "" {
+		return "", fmt.Errorf("%w: chave, nome e cidade são obrigatórios", ErrBRCodeInvalido)
+	}
+	txid := b.TxID
+	if txid == "" {
+		txid = "***"
+	} else if !txIDValido(txid) {
+		return "", fmt.Errorf("%w: txid %q deve ter até 25 letras ou dígitos", ErrBRCodeInvalido, txid)
+	}
+
+	// o primeiro campo grande demais vira o erro de Gerar
+	var err error
+	campo := func(id, valor string) string {
+		t, errCampo := tlv(id, valor)
+		if err == nil {
+			err = errCampo
+		}
+		return t
+	}
+
+	conta := campo(subcampoGUI, guiPix) + campo(subcampoChave, b.Chave)
+	if b.Descricao != "" {
+		conta += campo(subcampoDescricao, b.Descricao)
+	}
+
+	var payload strings.Builder
+	payload.WriteString(campo(campoFormato, "01"))
+	payload.WriteString(campo(campoContaRecebedor, conta))
+	payload.WriteString(campo(campoCategoria, "0000"))
+	payload.WriteString(campo(campoMoeda, "986"))
+	if b.Valor > 0 {
+		payload.WriteString(campo(campoValor, formatarDecimal(b.Valor, casasDecimais[BRL])))
+	}
+	payload.WriteString(campo(campoPais, "BR"))
+	payload.WriteString(campo(campoNome, truncar(semAcentos(b.Nome), 25)))
+	payload.WriteString(campo(campoCidade, truncar(semAcentos(b.Cidade), 15)))
+	payload.WriteString(campo(campoDadosAdicionais, campo(subcampoTxID, txid)))
+	payload.WriteString(campoCRC + "04")
+	if err != nil {
+		return "", err
+	}
+
+	return payload.String() + fmt.Sprintf("%04X", crc16(payload.String())), nil
+}
+
+// LerBRCode confere o CRC e extrai os campos de um "copia e cola".
+func LerBRCode(payload string) (BRCode, error) {
+	payload = strings.TrimSpace(payload)
+	if len(payload) < 8 || payload[len(payload)-8:len(payload)-4] != campoCRC+"04" {
+		return BRCode{}, fmt.Errorf("%w: sem campo CRC", ErrBRCodeInvalido)
+	}
+	esperado := fmt.Sprintf("%04X", crc16(payload[:len(payload)-4]))
+	if !strings.EqualFold(esperado, payload[len(payload)-4:]) {
+		return BRCode{}, ErrBRCodeCRC
+	}
+
+	campos, err := lerTLV(payload)
+	if err != nil {
+		return BRCode{}, err
+	}
+	if campos[campoFormato] != "01" {
+		return BRCode{}, fmt.Errorf("%w: formato %q", ErrBRCodeInvalido, campos[campoFormato])
+	}
+	conta, err := lerTLV(campos[campoContaRecebedor])
+	if err != nil {
+		return BRCode{}, err
+	}
+	if !strings.EqualFold(conta[subcampoGUI], guiPix) {
+		return BRCode{}, fmt.Errorf("%w: não é um código Pix", ErrBRCodeInvalido)
+	}
+
+	codigo := BRCode{
+		Chave:     conta[subcampoChave],
+		Descricao: conta[subcampoDescricao],
+		Nome:      campos[campoNome],
+		Cidade:    campos[campoCidade],
+	}
+	if valor, ok := campos[campoValor]; ok {
+		if codigo.Valor, err = lerDecimal(valor); err != nil {
+			return BRCode{}, fmt.Errorf("%w: valor %q", ErrBRCodeInvalido, valor)
+		}
+	}
+	if adicionais, ok := campos[campoDadosAdicionais]; ok {
+		extras, err := lerTLV(adicionais)
+		if err != nil {
+			return BRCode{}, err
+		}
+		codigo.TxID = extras[subcampoTxID]
+	}
+	return codigo, nil
+}
+
+// tlv monta um campo EMV. O tamanho tem só dois dígitos, então valores com
+// mais de 99 bytes não cabem.
+func tlv(id, valor string) (string, error) {
+	if len(valor) > 99 {
+		return "", fmt.Errorf("%w: campo %s com %d bytes, máximo 99", ErrBRCodeInvalido, id, len(valor))
+	}
+	return fmt.Sprintf("%s%02d%s", id, len(valor), valor), nil
+}
+
+// txIDValido aceita de 1 a 25 letras ou dígitos ASCII, o limite do BR Code
+// estático.
+func txIDValido(txid string) bool {
+	if len(txid) == 0 || len(txid) > 25 {
+		return false
+	}
+	for _, r := range txid {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func lerTLV(dados string) (map[string]string, error) {
+	campos := map[string]string{}
+	for len(dados) > 0 {
+		if len(dados) < 4 {
+			return nil, fmt.Errorf("%w: campo truncado", ErrBRCodeInvalido)
+		}
+		id := dados[:2]
+		tamanho, err := strconv.Atoi(dados[2:4])
+		if err != nil || len(dados) < 4+tamanho {
+			return nil, fmt.Errorf("%w: tamanho do campo %s", ErrBRCodeInvalido, id)
+		}
+		campos[id] = dados[4 : 4+tamanho]
+		dados = dados[4+tamanho:]
+	}
+	return campos, nil
+}
+
+// lerDecimal converte "123.45" em centavos.
+func lerDecimal(texto string) (int, error) {
+	inteiro, fracao, _ := strings.Cut(texto, ".")
+	if len(fracao) > 2 {
+		return 0, ErrValorInvalido
+	}
+	fracao += strings.Repeat("0", 2-len(fracao))
+	reais, err := strconv.Atoi(inteiro)
+	if err != nil {
+		return 0, err
+	}
+	centavos, err := strconv.Atoi(fracao)
+	if err != nil {
+		return 0, err
+	}
+	return reais*100 + centavos, nil
+}
+
+// crc16 é o CRC-16/CCITT-FALSE (polinômio 0x1021, valor inicial 0xFFFF)
+// exigido pelo BR Code.
+func crc16(dados string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(dados); i++ {
+		crc ^= uint16(dados[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// substitutosASCII dá a versão sem acento das letras latinas mais comuns.
+var substitutosASCII = map[rune]string{
+	'á': "a", 'à': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a",
+	'é': "e", 'è': "e", 'ê': "e", 'ë': "e",
+	'í': "i", 'ì': "i", 'î': "i", 'ï': "i",
+	'ó': "o", 'ò': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o",
+	'ú': "u", 'ù': "u", 'û': "u", 'ü': "u",
+	'ç': "c", 'ñ': "n", 'ý': "y", 'ÿ': "y", 'æ': "ae", 'œ': "oe", 'ß': "ss",
+	'Á': "A", 'À': "A", 'Â': "A", 'Ã': "A", 'Ä': "A", 'Å': "A",
+	'É': "E", 'È': "E", 'Ê': "E", 'Ë': "E",
+	'Í': "I", 'Ì': "I", 'Î': "I", 'Ï': "I",
+	'Ó': "O", 'Ò': "O", 'Ô': "O", 'Õ': "O", 'Ö': "O", 'Ø': "O",
+	'Ú': "U", 'Ù': "U", 'Û': "U", 'Ü': "U",
+	'Ç': "C", 'Ñ': "N", 'Ý': "Y", 'Æ': "AE", 'Œ': "OE",
+}
+
+// semAcentos troca letras acentuadas pela versão sem acento; o BR Code
+// só aceita caracteres ASCII no nome e na cidade, então o que não tiver
+// substituto (marcas combinantes, outros alfabetos, controles) é
+// descartado.
+func semAcentos(texto string) string {
+	var b strings.Builder
+	for _, r := range texto {
+		switch {
+		case r >= ' ' && r <= '~':
+			b.WriteRune(r)
+		case substitutosASCII[r] != "":
+			b.WriteString(substitutosASCII[r])
+		}
+	}
+	return b.String()
+}
+
+// truncar corta o texto em no máximo tamanho runas, sem partir nenhuma.
+func truncar(texto string, tamanho int) string {
+	for i := range texto {
+		if tamanho == 0 {
+			return texto[:i]
+		}
+		tamanho--
+	}
+	return texto
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// exemploBCB é o BR Code estático de exemplo do Manual de Padrões para
+// Iniciação do Pix.
+const exemploBCB = "00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-426655440000" +
+	"5204000053039865802BR5913Fulano de Tal6008BRASILIA62070503***63041D3D"
+
+func TestCRC16(t *testing.T) {
+	// valor de conferência do CRC-16/CCITT-FALSE
+	if got := crc16("123456789"); got != 0x29B1 {
+		t.Errorf("crc16(123456789) = %04X, quero 29B1", got)
+	}
+	if got := crc16(exemploBCB[:len(exemploBCB)-4]); got != 0x1D3D {
+		t.Errorf("crc16 do exemplo do BCB = %04X, quero 1D3D", got)
+	}
+}
+
+func TestGerarExemploBCB(t *testing.T) {
+	payload, err := BRCode{
+		Chave:  "123e4567-e12b-12d1-a456-426655440000",
+		Nome:   "Fulano de Tal",
+		Cidade: "BRASILIA",
+	}.Gerar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload != exemploBCB {
+		t.Errorf("payload:\n%s\nquero:\n%s", payload, exemploBCB)
+	}
+}
+
+func TestLerBRCode(t *testing.T) {
+	codigo, err := LerBRCode(exemploBCB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	esperado := BRCode{Chave: "123e4567-e12b-12d1-a456-426655440000", Nome: "Fulano de Tal", Cidade: "BRASILIA", TxID: "***"}
+	if codigo != esperado {
+		t.Errorf("LerBRCode = %+v, quero %+v", codigo, esperado)
+	}
+
+	original := BRCode{Chave: "+5511987654321", Descricao: "aluguel", Valor: 123456, Nome: "Ana", Cidade: "Recife", TxID: "Fatura2026x01"}
+	payload, err := original.Gerar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lido, err := LerBRCode(payload); err != nil || lido != original {
+		t.Errorf("ida e volta = %+v, %v; quero %+v", lido, err, original)
+	}
+
+	adulterado := strings.Replace(exemploBCB, "Fulano", "Fulana", 1)
+	if _, err := LerBRCode(adulterado); !errors.Is(err, ErrBRCodeCRC) {
+		t.Errorf("payload adulterado: erro = %v, quero ErrBRCodeCRC", err)
+	}
+	if _, err := LerBRCode("000201"); !errors.Is(err, ErrBRCodeInvalido) {
+		t.Errorf("payload sem CRC: erro = %v, quero ErrBRCodeInvalido", err)
+	}
+}
+
+func TestGerarValida(t *testing.T) {
+	base := BRCode{Chave: "+5511987654321", Nome: "Ana", Cidade: "Recife"}
+	casos := []struct {
+		nome   string
+		mudar  func(*BRCode)
+		valido bool
+	}{
+		{"txid com 25 caracteres", func(b *BRCode) { b.TxID = strings.Repeat("a1", 12) + "Z" }, true},
+		{"txid com 26 caracteres", func(b *BRCode) { b.TxID = strings.Repeat("a", 26) }, false},
+		{"txid com 120 bytes", func(b *BRCode) { b.TxID = strings.Repeat("x", 120) }, false},
+		{"txid com hífen", func(b *BRCode) { b.TxID = "pedido-42" }, false},
+		{"txid com acento", func(b *BRCode) { b.TxID = "pedidoé" }, false},
+		{"descrição longa demais", func(b *BRCode) { b.Descricao = strings.Repeat("d", 80) }, false},
+		{"sem chave", func(b *BRCode) { b.Chave = "" }, false},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			codigo := base
+			c.mudar(&codigo)
+			_, err := codigo.Gerar()
+			if c.valido && err != nil {
+				t.Errorf("erro = %v, quero nil", err)
+			}
+			if !c.valido && !errors.Is(err, ErrBRCodeInvalido) {
+				t.Errorf("erro = %v, quero ErrBRCodeInvalido", err)
+			}
+		})
+	}
+}
+
+func TestTLVRejeitaMaisDe99Bytes(t *testing.T) {
+	if campo, err := tlv("05", strings.Repeat("x", 99)); err != nil || len(campo) != 103 {
+		t.Errorf("tlv com 99 bytes = %d bytes, %v", len(campo), err)
+	}
+	if _, err := tlv("05", strings.Repeat("x", 100)); !errors.Is(err, ErrBRCodeInvalido) {
+		t.Errorf("tlv com 100 bytes: erro = %v, quero ErrBRCodeInvalido", err)
+	}
+}
+
+func TestNomeECidadeSoEmASCII(t *testing.T) {
+	casos := []struct{ entrada, esperado string }{
+		{"São João", "Sao Joao"},
+		{"Muñoz Noël", "Munoz Noel"},
+		{"IBÁÑEZ", "IBANEZ"},
+		{"Jose\u0301", "Jose"}, // acento combinante
+		{"Zoë 東京", "Zoe "},
+	}
+	for _, c := range casos {
+		if got := semAcentos(c.entrada); got != c.esperado {
+			t.Errorf("semAcentos(%q) = %q, quero %q", c.entrada, got, c.esperado)
+		}
+	}
+
+	if got := truncar("ñandú", 3); got != "ñan" || !utf8.ValidString(got) {
+		t.Errorf("truncar por runas = %q, quero \"ñan\"", got)
+	}
+
+	payload, err := BRCode{Chave: "+5511987654321", Nome: "Ñuño Ibáñez Gonçalves Brontë", Cidade: "São Paulo"}.Gerar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codigo, err := LerBRCode(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codigo.Nome != "Nuno Ibanez Goncalves Bro" || codigo.Cidade != "Sao Paulo" {
+		t.Errorf("nome %q, cidade %q", codigo.Nome, codigo.Cidade)
+	}
+	for i := range len(payload) {
+		if payload[i] > '~' {
+			t.Fatalf("byte não ASCII %#x na posição %d de %q", payload[i], i, payload)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+var ErrTaxaIndisponivel = erros.Definir("cambio.taxa_indisponivel", erros.NaoEncontrado).Sentinela("taxa de câmbio indisponível")
+
+// TaxaDeCambio fornece quantas unidades de para valem uma unidade de de
+// (por exemplo, USD→BRL = 5.4321). A taxa é um racional exato para que o
+// arredondamento aconteça uma vez só, no final da conversão.
+type TaxaDeCambio interface {
+	Taxa(de, para Moeda) (*big.Rat, error)
+}
+
+// TabelaCambio é um TaxaDeCambio com taxas fixas, para testes e exemplos.
+// Se só a taxa de→para estiver cadastrada, para→de usa o inverso.
+type TabelaCambio struct {
+	mu    sync.RWMutex
+	taxas map[[2]Moeda]*big.Rat
+}
+
+func NovaTabelaCambio() *TabelaCambio {
+	return &TabelaCambio{taxas: map[[2]Moeda]*big.Rat{}}
+}
+
+// Definir cadastra a taxa em texto decimal ("5.4321").
+func (t *TabelaCambio) Definir(de, para Moeda, taxa string) error {
+	valor, ok := new(big.Rat).SetString(taxa)
+	if !ok || valor.Sign() <= 0 {
+		return fmt.Errorf("taxa inválida: %q", taxa)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.taxas[[2]Moeda{de, para}] = valor
+	return nil
+}
+
+func (t *TabelaCambio) Taxa(de, para Moeda) (*big.Rat, error) {
+	if de == para {
+		return big.NewRat(1, 1), nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if taxa, ok := t.taxas[[2]Moeda{de, para}]; ok {
+		return new(big.Rat).Set(taxa), nil
+	}
+	if inversa, ok := t.taxas[[2]Moeda{para, de}]; ok {
+		return new(big.Rat).Inv(inversa), nil
+	}
+	return nil, fmt.Errorf("%w: %s→%s", ErrTaxaIndisponivel, de, para)
+}
+
+// Arredondamento diz como a conversão chega a um valor inteiro na menor
+// unidade da moeda de destino.
+type Arredondamento int
+
+const (
+	// ArredondarMeioParaCima: 0,5 centavo vai para cima (longe do zero).
+	ArredondarMeioParaCima Arredondamento = iota
+	// ArredondarMeioParaPar: 0,5 centavo vai para o par mais próximo
+	// (arredondamento bancário).
+	ArredondarMeioParaPar
+	// ArredondarParaBaixo: descarta a fração (em direção ao zero).
+	ArredondarParaBaixo
+)
+
+func (a Arredondamento) aplicar(valor *big.Rat) int {
+	quociente, resto := new(big.Int).QuoRem(valor.Num(), valor.Denom(), new(big.Int))
+	inteiro := int(quociente.Int64())
+	if resto.Sign() == 0 || a == ArredondarParaBaixo {
+		return inteiro
+	}
+
+	sinal := valor.Sign()
+	// compara 2*|resto| com o denominador para saber se passou da metade
+	dobro := new(big.Int).Abs(resto)
+	dobro.Lsh(dobro, 1)
+	switch dobro.Cmp(valor.Denom()) {
+	case 1:
+		return inteiro + sinal
+	case 0:
+		if a == ArredondarMeioParaCima || inteiro%2 != 0 {
+			return inteiro + sinal
+		}
+	}
+	return inteiro
+}
+
+// Conversao registra uma conversão feita: os valores nas duas moedas, a
+// taxa aplicada e o arredondamento usado.
+type Conversao struct {
+	Origem         Dinheiro
+	Destino        Dinheiro
+	Taxa           string
+	Arredondamento Arredondamento
+}
+
+// Converter passa o valor para outra moeda, levando em conta as casas
+// decimais de cada uma, e arredonda só no final.
+func Converter(valor Dinheiro, para Moeda, provedor TaxaDeCambio, arredondamento Arredondamento) (Conversao, error) {
+	casasOrigem, err := valor.Moeda.CasasDecimais()
+	if err != nil {
+		return Conversao{}, err
+	}
+	casasDestino, err := para.CasasDecimais()
+	if err != nil {
+		return Conversao{}, err
+	}
+	taxa, err := provedor.Taxa(valor.Moeda, para)
+	if err != nil {
+		return Conversao{}, err
+	}
+
+	convertido := new(big.Rat).Mul(big.NewRat(int64(valor.Valor), 1), taxa)
+	escala := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(casasDestino-casasOrigem))), nil))
+	if casasDestino >= casasOrigem {
+		convertido.Mul(convertido, escala)
+	} else {
+		convertido.Quo(convertido, escala)
+	}
+
+	return Conversao{
+		Origem:         valor,
+		Destino:        Dinheiro{Valor: arredondamento.aplicar(convertido), Moeda: para},
+		Taxa:           taxa.FloatString(8),
+		Arredondamento: arredondamento,
+	}, nil
+}
+
+// contaCambio é a conta de posição de câmbio do banco em cada moeda: recebe
+// o valor que sai da origem e paga o valor que entra no destino.
+func contaCambio(moeda Moeda) string {
+	return "cambio-" + string(moeda)
+}
+
+// TransferirComCambio transfere valor (na moeda da origem) para uma conta em
+// outra moeda. Tudo entra num único lançamento, passando pelas contas de
+// câmbio de cada moeda, e a conversão aplicada fica registrada nele.
+func TransferirComCambio(origem, destino *Conta, valor int, provedor TaxaDeCambio, arredondamento Arredondamento) (Conversao, error) {
+	if valor <= 0 {
+		return Conversao{}, ErrValorInvalido
+	}
+	if origem == destino {
+		return Conversao{}, ErrMesmaConta
+	}
+	if origem.razao != destino.razao {
+		return Conversao{}, ErrRazoesDiferentes
+	}
+	conversao, err := Converter(Dinheiro{Valor: valor, Moeda: origem.moeda}, destino.moeda, provedor, arredondamento)
+	if err != nil {
+		return Conversao{}, err
+	}
+	if conversao.Destino.Valor <= 0 {
+		return Conversao{}, ErrValorInvalido
+	}
+
+	defer travarEmOrdem(origem, destino)()
+
+	operacao := Operacao{Tipo: OperacaoTransferencia, Origem: origem.id, Destino: destino.id, Valor: valor}
+	if err := origem.avaliar(operacao); err != nil {
+		return Conversao{}, err
+	}
+	saldo, err := origem.verificarSaldo(valor)
+	if err != nil {
+		return Conversao{}, err
+	}
+	partidas := []Partida{
+		{Conta: origem.id, Natureza: Debito, Valor: valor},
+		{Conta: destino.id, Natureza: Credito, Valor: conversao.Destino.Valor},
+	}
+	if origem.moeda != destino.moeda {
+		partidas = append(partidas,
+			Partida{Conta: contaCambio(origem.moeda), Natureza: Credito, Valor: valor},
+			Partida{Conta: contaCambio(destino.moeda), Natureza: Debito, Valor: conversao.Destino.Valor},
+		)
+	}
+	if _, err := origem.razao.LancarComCambio("transferência com câmbio", conversao, partidas...); err != nil {
+		return Conversao{}, err
+	}
+	origem.registrarUsoDoLimite(saldo, saldo-valor)
+	origem.registrarOperacao(operacao)
+	return conversao, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
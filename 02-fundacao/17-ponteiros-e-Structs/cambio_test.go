@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+)
+
+func tabelaDeExemplo(t *testing.T, taxas ...[3]string) *TabelaCambio {
+	t.Helper()
+	tabela := NovaTabelaCambio()
+	for _, taxa := range taxas {
+		if err := tabela.Definir(Moeda(taxa[0]), Moeda(taxa[1]), taxa[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tabela
+}
+
+func TestConverterArredondamentos(t *testing.T) {
+	// BRL→USD a 0,5 e 0,3: cada centavo de real vira meio centavo ou 0,3
+	// centavo de dólar, o que dá frações exatas de meio e acima/abaixo dele.
+	meio := tabelaDeExemplo(t, [3]string{"BRL", "USD", "0.5"})
+	terco := tabelaDeExemplo(t, [3]string{"BRL", "USD", "0.3"})
+	for _, c := range []struct {
+		tabela *TabelaCambio
+		valor  int
+		// meio para cima, meio para par, para baixo
+		quero [3]int
+	}{
+		{meio, 1, [3]int{1, 0, 0}},     // 0,5
+		{meio, 3, [3]int{2, 2, 1}},     // 1,5
+		{meio, 5, [3]int{3, 2, 2}},     // 2,5
+		{meio, 4, [3]int{2, 2, 2}},     // exato
+		{meio, -1, [3]int{-1, 0, 0}},   // -0,5
+		{meio, -3, [3]int{-2, -2, -1}}, // -1,5
+		{meio, -5, [3]int{-3, -2, -2}}, // -2,5
+		{terco, 7, [3]int{2, 2, 2}},    // 2,1
+		{terco, 9, [3]int{3, 3, 2}},    // 2,7
+		{terco, -9, [3]int{-3, -3, -2}},
+		{terco, -7, [3]int{-2, -2, -2}},
+	} {
+		for i, arredondamento := range []Arredondamento{ArredondarMeioParaCima, ArredondarMeioParaPar, ArredondarParaBaixo} {
+			conversao, err := Converter(Dinheiro{Valor: c.valor, Moeda: BRL}, USD, c.tabela, arredondamento)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := conversao.Destino; got != (Dinheiro{Valor: c.quero[i], Moeda: USD}) {
+				t.Errorf("Converter(%d, arredondamento %d) = %v, quero %d", c.valor, arredondamento, got, c.quero[i])
+			}
+		}
+	}
+}
+
+func TestConverterCasasDecimais(t *testing.T) {
+	tabela := tabelaDeExemplo(t, [3]string{"USD", "JPY", "150"}, [3]string{"USD", "BRL", "5.4321"})
+	for _, c := range []struct {
+		de    Dinheiro
+		para  Moeda
+		quero int
+		taxa  string
+	}{
+		{Dinheiro{1_050, USD}, JPY, 1_575, "150.00000000"}, // US$ 10,50 = ¥ 1575
+		{Dinheiro{1, USD}, JPY, 2, "150.00000000"},         // ¥ 1,5
+		{Dinheiro{1, JPY}, USD, 1, "0.00666667"},           // 0,67 centavo, pela inversa
+		{Dinheiro{100, USD}, BRL, 543, "5.43210000"},       // R$ 5,4321
+		{Dinheiro{777, BRL}, BRL, 777, "1.00000000"},
+	} {
+		conversao, err := Converter(c.de, c.para, tabela, ArredondarMeioParaCima)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if conversao.Destino.Valor != c.quero || conversao.Taxa != c.taxa || conversao.Origem != c.de {
+			t.Errorf("Converter(%v, %s) = %+v, quero %d à taxa %s", c.de, c.para, conversao, c.quero, c.taxa)
+		}
+	}
+
+	if _, err := Converter(Dinheiro{100, "XYZ"}, BRL, tabela, ArredondarMeioParaCima); !errors.Is(err, ErrMoedaDesconhecida) {
+		t.Errorf("Converter de moeda desconhecida = %v, quero ErrMoedaDesconhecida", err)
+	}
+	if _, err := Converter(Dinheiro{100, BRL}, EUR, tabela, ArredondarMeioParaCima); !errors.Is(err, ErrTaxaIndisponivel) {
+		t.Errorf("Converter sem taxa = %v, quero ErrTaxaIndisponivel", err)
+	}
+}
+
+func TestTabelaCambioInversa(t *testing.T) {
+	tabela := tabelaDeExemplo(t, [3]string{"USD", "BRL", "5"})
+
+	taxa, err := tabela.Taxa(BRL, USD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if taxa.Cmp(big.NewRat(1, 5)) != 0 {
+		t.Errorf("Taxa(BRL, USD) = %s, quero 1/5 pela inversa", taxa)
+	}
+
+	// A taxa devolvida é uma cópia: mexer nela não muda a tabela.
+	taxa.SetInt64(99)
+	if taxa, _ := tabela.Taxa(USD, BRL); taxa.Cmp(big.NewRat(5, 1)) != 0 {
+		t.Errorf("Taxa(USD, BRL) = %s depois de alterar a devolvida, quero 5", taxa)
+	}
+
+	// Cadastrada a direção contrária, ela tem precedência sobre a inversa.
+	if err := tabela.Definir(BRL, USD, "0.19"); err != nil {
+		t.Fatal(err)
+	}
+	if taxa, _ := tabela.Taxa(BRL, USD); taxa.Cmp(big.NewRat(19, 100)) != 0 {
+		t.Errorf("Taxa(BRL, USD) = %s, quero 0.19 cadastrada", taxa)
+	}
+
+	if taxa, err := tabela.Taxa(EUR, EUR); err != nil || taxa.Cmp(big.NewRat(1, 1)) != 0 {
+		t.Errorf("Taxa(EUR, EUR) = %v, %v; quero 1", taxa, err)
+	}
+	if _, err := tabela.Taxa(EUR, BRL); !errors.Is(err, ErrTaxaIndisponivel) {
+		t.Errorf("Taxa(EUR, BRL) = %v, quero ErrTaxaIndisponivel", err)
+	}
+	for _, invalida := range []string{"0", "-1", "abc", ""} {
+		if err := tabela.Definir(EUR, BRL, invalida); err == nil {
+			t.Errorf("Definir(%q) aceitou taxa inválida", invalida)
+		}
+	}
+}
+
+func TestTransferirComCambioLancaQuatroPartidas(t *testing.T) {
+	razao := NovoRazao(nil)
+	ana, err := NovaContaEmMoeda(razao, "Ana", BRL, 100_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := NovaContaEmMoeda(razao, "Bob", USD, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tabela := tabelaDeExemplo(t, [3]string{"USD", "BRL", "5"})
+
+	// R$ 100,01 a 1/5 dá US$ 20,002, arredondado para US$ 20,00.
+	conversao, err := TransferirComCambio(ana, bob, 10_001, tabela, ArredondarMeioParaCima)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conversao.Destino != (Dinheiro{Valor: 2_000, Moeda: USD}) {
+		t.Errorf("Destino = %v, quero US$ 20,00", conversao.Destino)
+	}
+
+	historico := razao.Historico()
+	ultimo := historico[len(historico)-1]
+	quero := []Partida{
+		{Conta: ana.ID(), Natureza: Debito, Valor: 10_001},
+		{Conta: bob.ID(), Natureza: Credito, Valor: 2_000},
+		{Conta: "cambio-BRL", Natureza: Credito, Valor: 10_001},
+		{Conta: "cambio-USD", Natureza: Debito, Valor: 2_000},
+	}
+	if !slices.Equal(ultimo.Partidas, quero) {
+		t.Errorf("partidas = %+v, quero %+v", ultimo.Partidas, quero)
+	}
+	if !ultimo.Balanceado() {
+		t.Error("lançamento de câmbio desbalanceado")
+	}
+	if ultimo.Cambio == nil || *ultimo.Cambio != conversao {
+		t.Errorf("Cambio = %+v, quero a conversão aplicada %+v", ultimo.Cambio, conversao)
+	}
+
+	// Cada moeda fecha em si: o que sai da Ana entra no câmbio em reais, e
+	// o que o Bob recebe sai do câmbio em dólares.
+	if ana.Saldo() != 89_999 || razao.Saldo("cambio-BRL") != 10_001 {
+		t.Errorf("reais: Ana %d, câmbio %d; quero 89999 e 10001", ana.Saldo(), razao.Saldo("cambio-BRL"))
+	}
+	if bob.Saldo() != 2_000 || razao.Saldo("cambio-USD") != -2_000 {
+		t.Errorf("dólares: Bob %d, câmbio %d; quero 2000 e -2000", bob.Saldo(), razao.Saldo("cambio-USD"))
+	}
+	if err := razao.Verificar(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTransferirComCambioRecusa(t *testing.T) {
+	razao := NovoRazao(nil)
+	ana, _ := NovaContaEmMoeda(razao, "Ana", BRL, 1_000)
+	bob, _ := NovaContaEmMoeda(razao, "Bob", USD, 0)
+	eva, _ := NovaContaEmMoeda(razao, "Eva", EUR, 0)
+	tabela := tabelaDeExemplo(t, [3]string{"USD", "BRL", "5"})
+	antes := len(razao.Historico())
+
+	var semSaldo *ErrSaldoInsuficiente
+	for _, c := range []struct {
+		nome    string
+		destino *Conta
+		valor   int
+		erro    func(error) bool
+	}{
+		{"valor que vira zero", bob, 1, func(err error) bool { return errors.Is(err, ErrValorInvalido) }},
+		{"sem taxa", eva, 100, func(err error) bool { return errors.Is(err, ErrTaxaIndisponivel) }},
+		{"sem saldo", bob, 1_001, func(err error) bool { return errors.As(err, &semSaldo) }},
+		{"mesma conta", ana, 100, func(err error) bool { return errors.Is(err, ErrMesmaConta) }},
+	} {
+		if _, err := TransferirComCambio(ana, c.destino, c.valor, tabela, ArredondarMeioParaCima); !c.erro(err) {
+			t.Errorf("%s: err = %v", c.nome, err)
+		}
+	}
+	if got := len(razao.Historico()); got != antes {
+		t.Errorf("%d lançamentos novos depois de transferências recusadas, quero 0", got-antes)
+	}
+	if ana.Saldo() != 1_000 {
+		t.Errorf("saldo da Ana = %d, quero 1000", ana.Saldo())
+	}
+}
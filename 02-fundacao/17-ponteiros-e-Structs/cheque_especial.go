@@ -0,0 +1,138 @@
+package main
+
+import (
+	"math"
+)
+
+const (
+	// ContaReceitaJuros recebe os juros cobrados no cheque especial em
+	// reais. Como no caixa, cada outra moeda tem a sua conta
+	// ("receita-juros-cheque-especial-USD").
+	ContaReceitaJuros = "receita-juros-cheque-especial"
+	// ContaIOFARecolher acumula o IOF cobrado dos clientes até o repasse,
+	// também separado por moeda.
+	ContaIOFARecolher = "iof-a-recolher"
+
+	// IOF sobre operações de crédito de pessoa física: alíquota diária sobre
+	// o saldo devedor e alíquota adicional sobre cada valor utilizado.
+	aliquotaIOFDiaria    = 0.000082
+	aliquotaIOFAdicional = 0.0038
+)
+
+func contaReceitaJuros(moeda Moeda) string {
+	if moeda == BRL {
+		return ContaReceitaJuros
+	}
+	return ContaReceitaJuros + "-" + string(moeda)
+}
+
+func contaIOFARecolher(moeda Moeda) string {
+	if moeda == BRL {
+		return ContaIOFARecolher
+	}
+	return ContaIOFARecolher + "-" + string(moeda)
+}
+
+// ChequeEspecial configura o limite da conta. O limite é em centavos e a
+// taxa é mensal (0.08 = 8% ao mês), convertida em taxa diária equivalente.
+type ChequeEspecial struct {
+	Limite          int
+	TaxaJurosMensal float64
+}
+
+// Encargos são os juros e o IOF apurados e ainda não lançados, em centavos
+// com fração; o arredondamento só acontece no fechamento do mês.
+type Encargos struct {
+	Juros float64
+	IOF   float64
+}
+
+// DefinirChequeEspecial configura o limite e a taxa da conta.
+func (c *Conta) DefinirChequeEspecial(cheque ChequeEspecial) error {
+	if cheque.Limite < 0 || cheque.TaxaJurosMensal < 0 {
+		return ErrValorInvalido
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cheque = cheque
+	return nil
+}
+
+func (c *Conta) Limite() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cheque.Limite
+}
+
+// EncargosAcumulados devolve o que já foi apurado no mês e ainda não foi lançado.
+func (c *Conta) EncargosAcumulados() Encargos {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.encargos
+}
+
+// registrarUsoDoLimite cobra o IOF adicional sobre a parte do débito que
+// entrou no limite. Deve ser chamado com c.mu travado, depois do lançamento.
+func (c *Conta) registrarUsoDoLimite(saldoAntes, saldoDepois int) {
+	usoAntes := max(0, -saldoAntes)
+	usoDepois := max(0, -saldoDepois)
+	if usoDepois > usoAntes {
+		c.encargos.IOF += float64(usoDepois-usoAntes) * aliquotaIOFAdicional
+	}
+}
+
+// ApurarDia calcula os juros e o IOF diário sobre o saldo devedor do momento
+// e os soma aos encargos do mês. Deve ser chamado uma vez por dia pela rotina
+// de fechamento diário.
+func (c *Conta) ApurarDia() Encargos {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	devedor := max(0, -c.Saldo())
+	if devedor == 0 {
+		return Encargos{}
+	}
+	taxaDiaria := math.Pow(1+c.cheque.TaxaJurosMensal, 1.0/30) - 1
+	dia := Encargos{
+		Juros: float64(devedor) * taxaDiaria,
+		IOF:   float64(devedor) * aliquotaIOFDiaria,
+	}
+	c.encargos.Juros += dia.Juros
+	c.encargos.IOF += dia.IOF
+	return dia
+}
+
+// FecharMes lança no razão os juros e o IOF acumulados, arredondados para
+// centavos, e zera o acumulado. Os encargos são debitados mesmo que passem
+// do limite, já que são devidos de qualquer forma.
+func (c *Conta) FecharMes() ([]Lancamento, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var lancamentos []Lancamento
+	encargos := []struct {
+		descricao string
+		conta     string
+		valor     *float64
+	}{
+		{"juros do cheque especial", contaReceitaJuros(c.moeda), &c.encargos.Juros},
+		{"IOF do cheque especial", contaIOFARecolher(c.moeda), &c.encargos.IOF},
+	}
+	for _, e := range encargos {
+		valor := int(math.Round(*e.valor))
+		if valor <= 0 {
+			*e.valor = 0
+			continue
+		}
+		lancamento, err := c.razao.Lancar(e.descricao,
+			Partida{Conta: c.id, Natureza: Debito, Valor: valor},
+			Partida{Conta: e.conta, Natureza: Credito, Valor: valor},
+		)
+		if err != nil {
+			return lancamentos, err
+		}
+		*e.valor = 0
+		lancamentos = append(lancamentos, lancamento)
+	}
+	return lancamentos, nil
+}
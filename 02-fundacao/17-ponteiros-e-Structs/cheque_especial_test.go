@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func contaComCheque(t *testing.T, razao *Razao, moeda Moeda, saldoInicial int) *Conta {
+	t.Helper()
+	conta, err := NovaContaEmMoeda(razao, "Ana", moeda, saldoInicial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conta.DefinirChequeEspecial(ChequeEspecial{Limite: 100_000, TaxaJurosMensal: 0.08}); err != nil {
+		t.Fatal(err)
+	}
+	return conta
+}
+
+func quase(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestIOFAdicionalSobreOUsoDoLimite(t *testing.T) {
+	conta := contaComCheque(t, NovoRazao(nil), BRL, 10_000)
+
+	// Só os 20.000 que passam do saldo entram no limite.
+	if err := conta.Sacar(30_000); err != nil {
+		t.Fatal(err)
+	}
+	if got := conta.EncargosAcumulados().IOF; !quase(got, 76) {
+		t.Errorf("IOF = %v, quero 76 (0,38%% de 20000)", got)
+	}
+
+	// Um depósito que não zera o negativo devolve limite; usar de novo a
+	// mesma parte volta a pagar IOF só sobre o que foi usado agora.
+	if err := conta.Depositar(5_000); err != nil {
+		t.Fatal(err)
+	}
+	if err := conta.Sacar(15_000); err != nil {
+		t.Fatal(err)
+	}
+	if got := conta.EncargosAcumulados().IOF; !quase(got, 76+57) {
+		t.Errorf("IOF = %v, quero 133 (mais 0,38%% de 15000)", got)
+	}
+
+	// Débito com saldo positivo não paga IOF.
+	outra := contaComCheque(t, NovoRazao(nil), BRL, 10_000)
+	if err := outra.Sacar(10_000); err != nil {
+		t.Fatal(err)
+	}
+	if got := outra.EncargosAcumulados(); got != (Encargos{}) {
+		t.Errorf("encargos sem usar o limite = %+v, quero zero", got)
+	}
+
+	// Acima do limite o saque é recusado e nada é cobrado.
+	var semSaldo *ErrSaldoInsuficiente
+	if err := outra.Sacar(100_001); !errors.As(err, &semSaldo) || semSaldo.Limite != 100_000 {
+		t.Errorf("Sacar acima do limite = %v, quero *ErrSaldoInsuficiente com o limite", err)
+	}
+	if got := outra.EncargosAcumulados(); got != (Encargos{}) {
+		t.Errorf("encargos depois de um saque recusado = %+v, quero zero", got)
+	}
+}
+
+func TestApurarDia(t *testing.T) {
+	conta := contaComCheque(t, NovoRazao(nil), BRL, 0)
+
+	if dia := conta.ApurarDia(); dia != (Encargos{}) {
+		t.Errorf("ApurarDia sem saldo devedor = %+v, quero zero", dia)
+	}
+
+	if err := conta.Sacar(50_000); err != nil {
+		t.Fatal(err)
+	}
+	iofAdicional := conta.EncargosAcumulados().IOF
+	dia := conta.ApurarDia()
+	// A taxa diária é a equivalente composta: 30 dias dela dão os 8% do mês.
+	if taxa := math.Pow(1+dia.Juros/50_000, 30) - 1; !quase(taxa, 0.08) {
+		t.Errorf("juros do dia = %v, taxa mensal equivalente %v, quero 0.08", dia.Juros, taxa)
+	}
+	if !quase(dia.IOF, 4.1) {
+		t.Errorf("IOF do dia = %v, quero 4.1 (0,0082%% de 50000)", dia.IOF)
+	}
+
+	acumulado := conta.EncargosAcumulados()
+	if !quase(acumulado.Juros, dia.Juros) || !quase(acumulado.IOF, iofAdicional+dia.IOF) {
+		t.Errorf("acumulado = %+v, quero os juros do dia e o IOF adicional mais o diário", acumulado)
+	}
+	if conta.Saldo() != -50_000 {
+		t.Errorf("saldo = %d, quero -50000: apurar não lança nada", conta.Saldo())
+	}
+}
+
+func TestFecharMes(t *testing.T) {
+	razao := NovoRazao(nil)
+	conta := contaComCheque(t, razao, BRL, 0)
+	if err := conta.Sacar(50_000); err != nil {
+		t.Fatal(err)
+	}
+	for range 30 {
+		conta.ApurarDia()
+	}
+
+	lancamentos, err := conta.FecharMes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lancamentos) != 2 {
+		t.Fatalf("FecharMes lançou %d, quero juros e IOF", len(lancamentos))
+	}
+	// juros: 30 × 128,433 = 3852,99; IOF: 190 de adicional + 30 × 4,1
+	const juros, iof = 3_853, 313
+	if got := razao.Saldo(ContaReceitaJuros); got != juros {
+		t.Errorf("saldo de %s = %d, quero %d", ContaReceitaJuros, got, juros)
+	}
+	if got := razao.Saldo(ContaIOFARecolher); got != iof {
+		t.Errorf("saldo de %s = %d, quero %d", ContaIOFARecolher, got, iof)
+	}
+	if got := conta.Saldo(); got != -50_000-juros-iof {
+		t.Errorf("saldo da conta = %d, quero %d", got, -50_000-juros-iof)
+	}
+	if got := conta.EncargosAcumulados(); got != (Encargos{}) {
+		t.Errorf("encargos depois do fechamento = %+v, quero zero", got)
+	}
+	if err := razao.Verificar(); err != nil {
+		t.Error(err)
+	}
+
+	// Nada acumulado, nada lançado.
+	if lancamentos, err := conta.FecharMes(); err != nil || len(lancamentos) != 0 {
+		t.Errorf("segundo FecharMes = %v, %v; quero nenhum lançamento", lancamentos, err)
+	}
+}
+
+func TestFecharMesPassaDoLimiteEArredonda(t *testing.T) {
+	razao := NovoRazao(nil)
+	conta := contaComCheque(t, razao, BRL, 0)
+	if err := conta.Sacar(100_000); err != nil {
+		t.Fatal(err)
+	}
+	conta.ApurarDia()
+	if _, err := conta.FecharMes(); err != nil {
+		t.Fatal(err)
+	}
+	// 380 de IOF adicional, 8,2 de IOF diário e 256,87 de juros
+	if got := conta.Saldo(); got != -100_000-380-8-257 {
+		t.Errorf("saldo = %d, quero %d: os encargos entram mesmo além do limite", got, -100_000-380-8-257)
+	}
+
+	// Frações abaixo de meio centavo são descartadas no fechamento.
+	pequena := contaComCheque(t, razao, BRL, 0)
+	if err := pequena.Sacar(100); err != nil {
+		t.Fatal(err)
+	}
+	lancamentos, err := pequena.FecharMes()
+	if err != nil || len(lancamentos) != 0 {
+		t.Errorf("FecharMes com 0,38 centavo = %v, %v; quero nenhum lançamento", lancamentos, err)
+	}
+	if got := pequena.EncargosAcumulados(); got != (Encargos{}) {
+		t.Errorf("encargos = %+v, quero zero depois do fechamento", got)
+	}
+}
+
+func TestFecharMesEmOutraMoeda(t *testing.T) {
+	razao := NovoRazao(nil)
+	conta := contaComCheque(t, razao, USD, 0)
+	if err := conta.Sacar(50_000); err != nil {
+		t.Fatal(err)
+	}
+	conta.ApurarDia()
+	if _, err := conta.FecharMes(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, nome := range []string{ContaReceitaJuros, ContaIOFARecolher} {
+		if got := razao.Saldo(nome); got != 0 {
+			t.Errorf("saldo de %s = %d, quero 0: os encargos em dólar não vão para a conta em reais", nome, got)
+		}
+		if got := razao.Saldo(nome + "-USD"); got <= 0 {
+			t.Errorf("saldo de %s-USD = %d, quero os encargos da conta em dólar", nome, got)
+		}
+	}
+}
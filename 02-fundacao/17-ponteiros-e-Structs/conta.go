@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+// ErrValorInvalido é retornado quando o valor da operação não é positivo.
+var ErrValorInvalido = erros.Definir("conta.valor_invalido", erros.Validacao).Sentinela("o valor deve ser maior que zero")
+
+// ErrMesmaConta é retornado ao transferir de uma conta para ela mesma.
+var ErrMesmaConta = erros.Definir("conta.mesma_conta", erros.Validacao).Sentinela("origem e destino são a mesma conta")
+
+// ErrRazoesDiferentes é retornado ao transferir entre contas de razões diferentes.
+var ErrRazoesDiferentes = erros.Definir("conta.razoes_diferentes", erros.Validacao).Sentinela("as contas estão em razões diferentes")
+
+// ErrSaldoInsuficiente é retornado quando a conta não tem saldo para a operação.
+// Use errors.As para ler o saldo e o valor pedido. O código é
+// "conta.saldo_insuficiente" (veja Unwrap).
+type ErrSaldoInsuficiente struct {
+	Saldo  int
+	Limite int
+	Valor  int
+}
+
+func (e *ErrSaldoInsuficiente) Error() string {
+	if e.Limite > 0 {
+		return fmt.Sprintf("saldo insuficiente: saldo %d, limite %d, valor pedido %d", e.Saldo, e.Limite, e.Valor)
+	}
+	return fmt.Sprintf("saldo insuficiente: saldo %d, valor pedido %d", e.Saldo, e.Valor)
+}
+
+var errSaldoInsuficiente = erros.Definir("conta.saldo_insuficiente", erros.Validacao).Novo(nil)
+
+// Unwrap liga o erro ao seu código, para erros.CodigoDe e erros.StatusHTTP.
+func (e *ErrSaldoInsuficiente) Unwrap() error {
+	return errSaldoInsuficiente
+}
+
+// Conta é uma conta de cliente no razão. Ela não guarda o saldo: o saldo é
+// derivado dos lançamentos do razão. O mutex garante que a verificação de
+// saldo e o lançamento aconteçam juntos quando várias goroutines usam a
+// mesma conta.
+type Conta struct {
+	mu      sync.Mutex
+	numero  int64
+	id      string
+	titular string
+	moeda   Moeda
+	razao   *Razao
+
+	cheque   ChequeEspecial
+	encargos Encargos
+	regras   *MotorRegras
+}
+
+var ultimoIDConta atomic.Int64
+
+// NewConta abre uma conta para o titular no razão padrão, com o saldo
+// inicial informado em centavos.
+func NewConta(titular string, saldoInicial int) (*Conta, error) {
+	return NovaContaNoRazao(razaoPadrao, titular, saldoInicial)
+}
+
+// NovaContaNoRazao abre uma conta em reais num razão específico. O saldo
+// inicial entra como um lançamento de abertura contra o caixa.
+func NovaContaNoRazao(razao *Razao, titular string, saldoInicial int) (*Conta, error) {
+	return NovaContaEmMoeda(razao, titular, BRL, saldoInicial)
+}
+
+// NovaContaEmMoeda abre uma conta denominada na moeda informada. O saldo
+// inicial é na menor unidade dessa moeda.
+func NovaContaEmMoeda(razao *Razao, titular string, moeda Moeda, saldoInicial int) (*Conta, error) {
+	if saldoInicial < 0 {
+		return nil, ErrValorInvalido
+	}
+	if _, err := moeda.CasasDecimais(); err != nil {
+		return nil, err
+	}
+	numero := ultimoIDConta.Add(1)
+	c := &Conta{
+		numero:  numero,
+		id:      fmt.Sprintf("conta-%d", numero),
+		titular: titular,
+		moeda:   moeda,
+		razao:   razao,
+	}
+	if saldoInicial > 0 {
+		if _, err := razao.Lancar("abertura de conta",
+			Partida{Conta: contaCaixa(moeda), Natureza: Debito, Valor: saldoInicial},
+			Partida{Conta: c.id, Natureza: Credito, Valor: saldoInicial},
+		); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// ID identifica a conta no razão.
+func (c *Conta) ID() string {
+	return c.id
+}
+
+func (c *Conta) Titular() string {
+	return c.titular
+}
+
+func (c *Conta) Moeda() Moeda {
+	return c.moeda
+}
+
+// Saldo devolve o saldo na menor unidade da moeda da conta.
+func (c *Conta) Saldo() int {
+	return c.razao.Saldo(c.id)
+}
+
+func (c *Conta) SaldoEmDinheiro() Dinheiro {
+	return Dinheiro{Valor: c.Saldo(), Moeda: c.moeda}
+}
+
+func (c *Conta) Depositar(valor int) error {
+	_, err := c.depositar(valor)
+	return err
+}
+
+// depositar devolve o saldo lido ainda com a conta travada, sem que outra
+// operação possa ter mexido nele depois do depósito.
+func (c *Conta) depositar(valor int) (int, error) {
+	if valor <= 0 {
+		return 0, ErrValorInvalido
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.razao.Lancar("depósito",
+		Partida{Conta: contaCaixa(c.moeda), Natureza: Debito, Valor: valor},
+		Partida{Conta: c.id, Natureza: Credito, Valor: valor},
+	); err != nil {
+		return c.Saldo(), err
+	}
+	return c.Saldo(), nil
+}
+
+func (c *Conta) Sacar(valor int) error {
+	_, err := c.sacar(valor)
+	return err
+}
+
+// sacar devolve o saldo depois do saque, como depositar.
+func (c *Conta) sacar(valor int) (int, error) {
+	if valor <= 0 {
+		return 0, ErrValorInvalido
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	operacao := Operacao{Tipo: OperacaoSaque, Origem: c.id, Valor: valor}
+	if err := c.avaliar(operacao); err != nil {
+		return c.Saldo(), err
+	}
+	saldo, err := c.verificarSaldo(valor)
+	if err != nil {
+		return saldo, err
+	}
+	if _, err := c.razao.Lancar("saque",
+		Partida{Conta: c.id, Natureza: Debito, Valor: valor},
+		Partida{Conta: contaCaixa(c.moeda), Natureza: Credito, Valor: valor},
+	); err != nil {
+		return saldo, err
+	}
+	c.registrarUsoDoLimite(saldo, saldo-valor)
+	c.registrarOperacao(operacao)
+	return saldo - valor, nil
+}
+
+// UsarRegras liga o motor de regras da conta: saques e transferências que
+// saem dela passam a ser avaliados antes de aplicados. nil desliga.
+func (c *Conta) UsarRegras(motor *MotorRegras) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.regras = motor
+}
+
+// avaliar passa a operação pelas regras da conta, se houver. Deve ser
+// chamado com c.mu travado.
+func (c *Conta) avaliar(op Operacao) error {
+	if c.regras == nil {
+		return nil
+	}
+	return erroDaDecisao(c.regras.Avaliar(op))
+}
+
+// registrarOperacao guarda a operação aplicada no histórico das regras.
+// Deve ser chamado com c.mu travado.
+func (c *Conta) registrarOperacao(op Operacao) {
+	if c.regras != nil {
+		c.regras.Registrar(op)
+	}
+}
+
+// verificarSaldo confere se o saldo mais o limite do cheque especial cobrem
+// o valor e devolve o saldo antes da operação. Deve ser chamado com c.mu
+// travado.
+func (c *Conta) verificarSaldo(valor int) (int, error) {
+	saldo := c.Saldo()
+	if saldo+c.cheque.Limite < valor {
+		return saldo, &ErrSaldoInsuficiente{Saldo: saldo, Limite: c.cheque.Limite, Valor: valor}
+	}
+	return saldo, nil
+}
+
+// Transferir move o valor da origem para o destino num único lançamento,
+// então ou as duas contas mudam ou nenhuma muda. As duas contas precisam
+// estar no mesmo razão e na mesma moeda; entre moedas diferentes use
+// TransferirComCambio.
+func Transferir(origem, destino *Conta, valor int) error {
+	_, err := transferir(origem, destino, valor, OperacaoTransferencia, "transferência")
+	return err
+}
+
+// transferir devolve o saldo da origem lido com as duas contas travadas.
+// Nas recusas antes de travar, o saldo devolvido é zero.
+func transferir(origem, destino *Conta, valor int, tipo TipoOperacao, descricao string) (int, error) {
+	if valor <= 0 {
+		return 0, ErrValorInvalido
+	}
+	if origem == destino {
+		return 0, ErrMesmaConta
+	}
+	if origem.razao != destino.razao {
+		return 0, ErrRazoesDiferentes
+	}
+	if origem.moeda != destino.moeda {
+		return 0, &ErrMoedasDiferentes{Esperada: origem.moeda, Recebida: destino.moeda}
+	}
+
+	defer travarEmOrdem(origem, destino)()
+
+	operacao := Operacao{Tipo: tipo, Origem: origem.id, Destino: destino.id, Valor: valor}
+	if err := origem.avaliar(operacao); err != nil {
+		return origem.Saldo(), err
+	}
+	saldo, err := origem.verificarSaldo(valor)
+	if err != nil {
+		return saldo, err
+	}
+	if _, err := origem.razao.Lancar(descricao,
+		Partida{Conta: origem.id, Natureza: Debito, Valor: valor},
+		Partida{Conta: destino.id, Natureza: Credito, Valor: valor},
+	); err != nil {
+		return saldo, err
+	}
+	origem.registrarUsoDoLimite(saldo, saldo-valor)
+	origem.registrarOperacao(operacao)
+	return saldo - valor, nil
+}
+
+// travarEmOrdem trava as duas contas sempre na ordem do número da conta e
+// devolve a função que destrava. Se cada transferência travasse primeiro a
+// origem, A→B e B→A ao mesmo tempo poderiam ficar esperando uma pela outra
+// para sempre; com uma ordem global isso não acontece.
+func travarEmOrdem(a, b *Conta) func() {
+	if b.numero < a.numero {
+		a, b = b, a
+	}
+	a.mu.Lock()
+	b.mu.Lock()
+	return func() {
+		b.mu.Unlock()
+		a.mu.Unlock()
+	}
+}
+
+// simular mostra como ficaria o saldo depois de um movimento de valor, sem
+// alterar a conta. Para projeções de vários meses, use Projetar.
+func (c *Conta) simular(valor int) int {
+	projetado := c.Saldo() + valor
+	println(projetado) // Imprime o saldo simulado
+	return projetado
+}
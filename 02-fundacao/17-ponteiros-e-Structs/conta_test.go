@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestContaOperacoes(t *testing.T) {
+	razao := NovoRazao(nil)
+	a, err := NovaContaNoRazao(razao, "Ana", 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NovaContaNoRazao(razao, "Bia", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Depositar(500); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Sacar(300); err != nil {
+		t.Fatal(err)
+	}
+	if err := Transferir(a, b, 200); err != nil {
+		t.Fatal(err)
+	}
+	if a.Saldo() != 1_000 || b.Saldo() != 200 {
+		t.Fatalf("saldos = %d e %d, quero 1000 e 200", a.Saldo(), b.Saldo())
+	}
+
+	var semSaldo *ErrSaldoInsuficiente
+	if err := b.Sacar(201); !errors.As(err, &semSaldo) || semSaldo.Saldo != 200 || semSaldo.Valor != 201 {
+		t.Errorf("Sacar acima do saldo: %v", err)
+	}
+	if err := Transferir(b, a, 201); !errors.As(err, &semSaldo) {
+		t.Errorf("Transferir acima do saldo: %v", err)
+	}
+	for _, err := range []error{a.Depositar(0), a.Sacar(-1), Transferir(a, b, 0)} {
+		if !errors.Is(err, ErrValorInvalido) {
+			t.Errorf("valor inválido: %v", err)
+		}
+	}
+	if err := Transferir(a, a, 1); !errors.Is(err, ErrMesmaConta) {
+		t.Errorf("mesma conta: %v", err)
+	}
+	if outra, _ := NovaContaNoRazao(NovoRazao(nil), "Cris", 0); !errors.Is(Transferir(a, outra, 1), ErrRazoesDiferentes) {
+		t.Error("transferência entre razões diferentes deveria falhar")
+	}
+	if a.Saldo() != 1_000 || b.Saldo() != 200 {
+		t.Errorf("operações recusadas mudaram os saldos: %d e %d", a.Saldo(), b.Saldo())
+	}
+}
+
+// TestContaSaquesConcorrentes dispara mais saques do que o saldo cobre ao
+// mesmo tempo na mesma conta: a verificação de saldo e o lançamento
+// precisam acontecer juntos, senão dois saques veem o mesmo saldo e a conta
+// fica negativa. Com go test -race, também pega acessos sem trava.
+func TestContaSaquesConcorrentes(t *testing.T) {
+	conta, err := NovaContaNoRazao(NovoRazao(nil), "Ana", 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		aceitos int
+	)
+	for range 50 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if conta.Sacar(100) == nil {
+				mu.Lock()
+				aceitos++
+				mu.Unlock()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			conta.Saldo()
+		}()
+	}
+	wg.Wait()
+
+	if aceitos != 10 {
+		t.Errorf("%d saques aceitos, quero 10", aceitos)
+	}
+	if saldo := conta.Saldo(); saldo != 0 {
+		t.Errorf("saldo final = %d, quero 0", saldo)
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+// Tipos de evento da conta com event sourcing. Em vez de alterar um saldo,
+// cada operação acrescenta um evento ao log, e o estado da conta é
+// reconstruído aplicando os eventos em ordem.
+const (
+	EventoContaAberta           = "ContaAberta"
+	EventoDepositado            = "Depositado"
+	EventoSacado                = "Sacado"
+	EventoTransferenciaEnviada  = "TransferenciaEnviada"
+	EventoTransferenciaRecebida = "TransferenciaRecebida"
+)
+
+// versoesEventos é a versão atual do esquema de cada evento.
+var versoesEventos = map[string]int{
+	EventoContaAberta:           1,
+	EventoDepositado:            2,
+	EventoSacado:                1,
+	EventoTransferenciaEnviada:  1,
+	EventoTransferenciaRecebida: 1,
+}
+
+var ErrContaNaoEncontrada = erros.Definir("conta.nao_encontrada", erros.NaoEncontrado).Sentinela("conta não encontrada")
+
+type ContaAberta struct {
+	Titular      string `json:"titular"`
+	SaldoInicial int    `json:"saldo_inicial"`
+}
+
+// Depositado na versão 2 guarda o valor em centavos. A versão 1 guardava
+// reais com casas decimais ("valor_reais"); veja upcastDepositadoV1.
+type Depositado struct {
+	Valor int `json:"valor"`
+}
+
+type Sacado struct {
+	Valor int `json:"valor"`
+}
+
+type TransferenciaEnviada struct {
+	Destino string `json:"destino"`
+	Valor   int    `json:"valor"`
+}
+
+type TransferenciaRecebida struct {
+	Origem string `json:"origem"`
+	Valor  int    `json:"valor"`
+}
+
+// UpcastersPadrao traz os conversores dos esquemas antigos ainda presentes
+// nos logs.
+func UpcastersPadrao() Upcasters {
+	u := Upcasters{}
+	u.Registrar(EventoDepositado, 1, upcastDepositadoV1)
+	return u
+}
+
+func upcastDepositadoV1(e Evento) (Evento, error) {
+	var v1 struct {
+		ValorReais float64 `json:"valor_reais"`
+	}
+	if err := json.Unmarshal(e.Dados, &v1); err != nil {
+		return e, err
+	}
+	dados, err := json.Marshal(Depositado{Valor: int(math.Round(v1.ValorReais * 100))})
+	if err != nil {
+		return e, err
+	}
+	e.Dados = dados
+	return e, nil
+}
+
+// EstadoConta é o estado de uma conta reconstruído a partir dos eventos.
+// Seq é a sequência do último evento aplicado.
+type EstadoConta struct {
+	ID      string `json:"id"`
+	Titular string `json:"titular"`
+	Saldo   int    `json:"saldo"`
+	Seq     uint64 `json:"seq"`
+	Eventos int    `json:"eventos"`
+}
+
+// Aplicar avança o estado com um evento.
+func (e *EstadoConta) Aplicar(evento Evento) error {
+	switch evento.Tipo {
+	case EventoContaAberta:
+		var dados ContaAberta
+		if err := json.Unmarshal(evento.Dados, &dados); err != nil {
+			return err
+		}
+		e.ID, e.Titular, e.Saldo = evento.ContaID, dados.Titular, dados.SaldoInicial
+	case EventoDepositado:
+		var dados Depositado
+		if err := json.Unmarshal(evento.Dados, &dados); err != nil {
+			return err
+		}
+		e.Saldo += dados.Valor
+	case EventoSacado:
+		var dados Sacado
+		if err := json.Unmarshal(evento.Dados, &dados); err != nil {
+			return err
+		}
+		e.Saldo -= dados.Valor
+	case EventoTransferenciaEnviada:
+		var dados TransferenciaEnviada
+		if err := json.Unmarshal(evento.Dados, &dados); err != nil {
+			return err
+		}
+		e.Saldo -= dados.Valor
+	case EventoTransferenciaRecebida:
+		var dados TransferenciaRecebida
+		if err := json.Unmarshal(evento.Dados, &dados); err != nil {
+			return err
+		}
+		e.Saldo += dados.Valor
+	default:
+		return fmt.Errorf("evento desconhecido: %s", evento.Tipo)
+	}
+	e.Seq = evento.Seq
+	e.Eventos++
+	return nil
+}
+
+// RepositorioEventos executa as operações da conta gravando eventos no log.
+// A cada intervalo de eventos de uma conta é salvo um snapshot do estado,
+// para que carregar a conta não precise reler o log inteiro. O último
+// snapshot de cada conta fica em memória depois da primeira leitura; o
+// disco só é lido de novo na próxima abertura do repositório.
+type RepositorioEventos struct {
+	log          *LogEventos
+	relogio      Relogio
+	dirSnapshots string
+	intervalo    int
+
+	mu        sync.Mutex
+	snapshots map[string]EstadoConta
+}
+
+// NovoRepositorioEventos usa o log informado e guarda os snapshots em
+// dirSnapshots. intervalo <= 0 desliga os snapshots.
+func NovoRepositorioEventos(log *LogEventos, relogio Relogio, dirSnapshots string, intervalo int) (*RepositorioEventos, error) {
+	if relogio == nil {
+		relogio = relogioSistema{}
+	}
+	if err := os.MkdirAll(dirSnapshots, 0o755); err != nil {
+		return nil, err
+	}
+	return &RepositorioEventos{
+		log:          log,
+		relogio:      relogio,
+		dirSnapshots: dirSnapshots,
+		intervalo:    intervalo,
+		snapshots:    map[string]EstadoConta{},
+	}, nil
+}
+
+// Abrir cria uma conta nova e devolve o id, formado pela sequência do evento
+// de abertura, o que garante que ele não se repete.
+func (r *RepositorioEventos) Abrir(titular string, saldoInicial int) (string, error) {
+	if saldoInicial < 0 {
+		return "", ErrValorInvalido
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := fmt.Sprintf("es-%d", r.log.UltimaSeq()+1)
+	_, err := r.anexar(id, EventoContaAberta, ContaAberta{Titular: titular, SaldoInicial: saldoInicial})
+	return id, err
+}
+
+func (r *RepositorioEventos) Depositar(id string, valor int) error {
+	if valor <= 0 {
+		return ErrValorInvalido
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.carregar(id); err != nil {
+		return err
+	}
+	return r.anexarComSnapshot(id, EventoDepositado, Depositado{Valor: valor})
+}
+
+func (r *RepositorioEventos) Sacar(id string, valor int) error {
+	if valor <= 0 {
+		return ErrValorInvalido
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	estado, err := r.carregar(id)
+	if err != nil {
+		return err
+	}
+	if estado.Saldo < valor {
+		return &ErrSaldoInsuficiente{Saldo: estado.Saldo, Valor: valor}
+	}
+	return r.anexarComSnapshot(id, EventoSacado, Sacado{Valor: valor})
+}
+
+func (r *RepositorioEventos) Transferir(origem, destino string, valor int) error {
+	if valor <= 0 {
+		return ErrValorInvalido
+	}
+	if origem == destino {
+		return ErrMesmaConta
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	estado, err := r.carregar(origem)
+	if err != nil {
+		return err
+	}
+	if _, err := r.carregar(destino); err != nil {
+		return err
+	}
+	if estado.Saldo < valor {
+		return &ErrSaldoInsuficiente{Saldo: estado.Saldo, Valor: valor}
+	}
+	// Débito e crédito vão juntos num único registro do log: se um deles
+	// não for gravado, o outro também não é, e o dinheiro não some.
+	agora := r.relogio.Agora()
+	_, err = r.log.AnexarLote(
+		NovoEvento{ContaID: origem, Tipo: EventoTransferenciaEnviada, Versao: versoesEventos[EventoTransferenciaEnviada], Data: agora,
+			Dados: TransferenciaEnviada{Destino: destino, Valor: valor}},
+		NovoEvento{ContaID: destino, Tipo: EventoTransferenciaRecebida, Versao: versoesEventos[EventoTransferenciaRecebida], Data: agora,
+			Dados: TransferenciaRecebida{Origem: origem, Valor: valor}},
+	)
+	if err != nil {
+		return err
+	}
+	r.atualizarSnapshot(origem)
+	r.atualizarSnapshot(destino)
+	return nil
+}
+
+// Carregar reconstrói o estado atual da conta: parte do último snapshot e
+// aplica só os eventos posteriores a ele.
+func (r *RepositorioEventos) Carregar(id string) (EstadoConta, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.carregar(id)
+}
+
+// Reproduzir reconstrói a conta do zero até a sequência informada, ignorando
+// os snapshots. Serve para ver o estado em qualquer ponto do passado.
+func (r *RepositorioEventos) Reproduzir(id string, ateSeq uint64) (EstadoConta, error) {
+	return reproduzir(EstadoConta{}, r.log.Eventos(id, 0, ateSeq))
+}
+
+func (r *RepositorioEventos) carregar(id string) (EstadoConta, error) {
+	snapshot, err := r.lerSnapshot(id)
+	if err != nil {
+		return EstadoConta{}, err
+	}
+	return reproduzir(snapshot, r.log.Eventos(id, snapshot.Seq, 0))
+}
+
+func reproduzir(estado EstadoConta, eventos []Evento) (EstadoConta, error) {
+	for _, evento := range eventos {
+		if err := estado.Aplicar(evento); err != nil {
+			return EstadoConta{}, err
+		}
+	}
+	if estado.ID == "" {
+		return EstadoConta{}, ErrContaNaoEncontrada
+	}
+	return estado, nil
+}
+
+func (r *RepositorioEventos) anexar(id, tipo string, dados any) (Evento, error) {
+	return r.log.Anexar(id, tipo, versoesEventos[tipo], r.relogio.Agora(), dados)
+}
+
+// anexarComSnapshot grava o evento e atualiza o snapshot da conta. Deve ser
+// chamado com r.mu travado.
+func (r *RepositorioEventos) anexarComSnapshot(id, tipo string, dados any) error {
+	if _, err := r.anexar(id, tipo, dados); err != nil {
+		return err
+	}
+	r.atualizarSnapshot(id)
+	return nil
+}
+
+// atualizarSnapshot salva um snapshot novo se a conta acumulou intervalo
+// eventos desde o último. O evento já está no log quando ele roda, então
+// uma falha aqui não é devolvida: o snapshot é só um atalho, e devolver
+// erro levaria quem chamou a repetir uma operação que já foi feita.
+func (r *RepositorioEventos) atualizarSnapshot(id string) {
+	if err := r.salvarSnapshotSeNecessario(id); err != nil {
+		fmt.Fprintf(os.Stderr, "snapshot da conta %s: %v\n", id, err)
+	}
+}
+
+// salvarSnapshotSeNecessario deve ser chamado com r.mu travado.
+func (r *RepositorioEventos) salvarSnapshotSeNecessario(id string) error {
+	if r.intervalo <= 0 {
+		return nil
+	}
+	snapshot, err := r.lerSnapshot(id)
+	if err != nil {
+		return err
+	}
+	estado, err := reproduzir(snapshot, r.log.Eventos(id, snapshot.Seq, 0))
+	if err != nil {
+		return err
+	}
+	if estado.Eventos-snapshot.Eventos < r.intervalo {
+		return nil
+	}
+	return r.salvarSnapshot(estado)
+}
+
+func (r *RepositorioEventos) caminhoSnapshot(id string) string {
+	return filepath.Join(r.dirSnapshots, id+".json")
+}
+
+// lerSnapshot devolve o snapshot guardado em memória ou, na primeira vez,
+// o do disco. Deve ser chamado com r.mu travado.
+func (r *RepositorioEventos) lerSnapshot(id string) (EstadoConta, error) {
+	if estado, ok := r.snapshots[id]; ok {
+		return estado, nil
+	}
+	conteudo, err := os.ReadFile(r.caminhoSnapshot(id))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return EstadoConta{}, err
+	}
+	var estado EstadoConta
+	if err == nil && json.Unmarshal(conteudo, &estado) != nil {
+		// snapshot é só um atalho: se estiver ilegível, refaz pelo log
+		estado = EstadoConta{}
+	}
+	r.snapshots[id] = estado
+	return estado, nil
+}
+
+func (r *RepositorioEventos) salvarSnapshot(estado EstadoConta) error {
+	conteudo, err := json.Marshal(estado)
+	if err != nil {
+		return err
+	}
+	temporario := r.caminhoSnapshot(estado.ID) + ".tmp"
+	if err := os.WriteFile(temporario, conteudo, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(temporario, r.caminhoSnapshot(estado.ID)); err != nil {
+		return err
+	}
+	r.snapshots[estado.ID] = estado
+	return nil
+}
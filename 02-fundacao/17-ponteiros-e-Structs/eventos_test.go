@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func abrirRepositorio(t *testing.T, dir string) (*RepositorioEventos, *LogEventos) {
+	t.Helper()
+	log, err := AbrirLogEventos(filepath.Join(dir, "contas.log"), UpcastersPadrao(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { log.Fechar() })
+	repo, err := NovoRepositorioEventos(log, NovoRelogioFake(time.Unix(0, 0)), filepath.Join(dir, "snapshots"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo, log
+}
+
+func saldoEventos(t *testing.T, repo *RepositorioEventos, id string) int {
+	t.Helper()
+	estado, err := repo.Carregar(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return estado.Saldo
+}
+
+func TestTransferirGravaDebitoECreditoJuntos(t *testing.T) {
+	dir := t.TempDir()
+	repo, log := abrirRepositorio(t, dir)
+	origem, _ := repo.Abrir("Ana", 10_000)
+	destino, _ := repo.Abrir("Bia", 0)
+	if err := repo.Transferir(origem, destino, 2_500); err != nil {
+		t.Fatal(err)
+	}
+	if got := log.UltimaSeq(); got != 4 {
+		t.Fatalf("UltimaSeq = %d, quero 4 (2 aberturas + débito + crédito)", got)
+	}
+	log.Fechar()
+
+	// Reabrindo, os dois lados continuam lá.
+	repo, _ = abrirRepositorio(t, dir)
+	if got := saldoEventos(t, repo, origem); got != 7_500 {
+		t.Errorf("saldo da origem = %d, quero 7500", got)
+	}
+	if got := saldoEventos(t, repo, destino); got != 2_500 {
+		t.Errorf("saldo do destino = %d, quero 2500", got)
+	}
+}
+
+func TestTransferirInterrompidaNaoPerdeDinheiro(t *testing.T) {
+	dir := t.TempDir()
+	repo, log := abrirRepositorio(t, dir)
+	origem, _ := repo.Abrir("Ana", 10_000)
+	destino, _ := repo.Abrir("Bia", 0)
+	caminho := filepath.Join(dir, "contas.log")
+	info, err := os.Stat(caminho)
+	if err != nil {
+		t.Fatal(err)
+	}
+	antes := info.Size()
+	if err := repo.Transferir(origem, destino, 2_500); err != nil {
+		t.Fatal(err)
+	}
+	log.Fechar()
+
+	// Simula uma queda no meio da gravação: o registro do lote fica pela
+	// metade. Débito e crédito têm de sumir juntos.
+	info, err = os.Stat(caminho)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(caminho, antes+(info.Size()-antes)/2); err != nil {
+		t.Fatal(err)
+	}
+	os.RemoveAll(filepath.Join(dir, "snapshots"))
+
+	repo, _ = abrirRepositorio(t, dir)
+	origemSaldo, destinoSaldo := saldoEventos(t, repo, origem), saldoEventos(t, repo, destino)
+	if origemSaldo+destinoSaldo != 10_000 {
+		t.Fatalf("origem %d + destino %d = %d, quero 10000", origemSaldo, destinoSaldo, origemSaldo+destinoSaldo)
+	}
+	if origemSaldo != 10_000 {
+		t.Errorf("saldo da origem = %d, quero 10000 (transferência descartada)", origemSaldo)
+	}
+}
+
+func TestCarregarPartindoDoSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	repo, _ := abrirRepositorio(t, dir)
+	id, _ := repo.Abrir("Ana", 0)
+	// abertura e três depósitos: o snapshot do quarto evento tem saldo 300
+	for range 3 {
+		if err := repo.Depositar(id, 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	caminho := filepath.Join(dir, "snapshots", id+".json")
+	conteudo, err := os.ReadFile(caminho)
+	if err != nil {
+		t.Fatalf("snapshot não foi salvo: %v", err)
+	}
+
+	// Um snapshot adulterado no disco não afeta o repositório aberto, que
+	// guarda o seu em memória e não relê o arquivo a cada operação.
+	adulterado := strings.Replace(string(conteudo), `"saldo":300`, `"saldo":999999`, 1)
+	if adulterado == string(conteudo) {
+		t.Fatalf("snapshot inesperado: %s", conteudo)
+	}
+	if err := os.WriteFile(caminho, []byte(adulterado), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Depositar(id, 100); err != nil {
+		t.Fatal(err)
+	}
+	if got := saldoEventos(t, repo, id); got != 400 {
+		t.Errorf("saldo = %d, quero 400", got)
+	}
+
+	// Um repositório novo lê o snapshot do disco e aplica só os eventos
+	// depois dele: o saldo adulterado aparece, prova de que os eventos
+	// anteriores ao snapshot não foram reaplicados.
+	repo, err = NovoRepositorioEventos(repo.log, nil, filepath.Join(dir, "snapshots"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := saldoEventos(t, repo, id); got != 999999+100 {
+		t.Errorf("saldo = %d, quero o do snapshot mais o depósito seguinte", got)
+	}
+	if estado, _ := repo.Reproduzir(id, 0); estado.Saldo != 400 {
+		t.Errorf("Reproduzir ignora snapshots e deveria dar 400, deu %d", estado.Saldo)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// MovimentoExtrato é uma linha do extrato. Valor é positivo para créditos e
+// negativo para débitos; Saldo é o saldo logo depois do movimento.
+type MovimentoExtrato struct {
+	Seq       int       `json:"seq"`
+	Data      time.Time `json:"data"`
+	Descricao string    `json:"descricao"`
+	Valor     int       `json:"valor"`
+	Saldo     int       `json:"saldo"`
+}
+
+// Extrato traz os movimentos de uma conta num período, com saldo de abertura
+// e de fechamento. Os valores estão na menor unidade da moeda (centavos, no
+// caso do real; ienes inteiros, no do iene).
+type Extrato struct {
+	ContaID      string             `json:"conta"`
+	Titular      string             `json:"titular"`
+	Moeda        Moeda              `json:"moeda"`
+	Inicio       time.Time          `json:"inicio"`
+	Fim          time.Time          `json:"fim"`
+	SaldoInicial int                `json:"saldo_inicial"`
+	SaldoFinal   int                `json:"saldo_final"`
+	Movimentos   []MovimentoExtrato `json:"movimentos"`
+}
+
+// Extrato monta o extrato da conta com os lançamentos entre inicio e fim,
+// inclusive.
+func (c *Conta) Extrato(inicio, fim time.Time) Extrato {
+	extrato := Extrato{ContaID: c.id, Titular: c.titular, Moeda: c.moeda, Inicio: inicio, Fim: fim}
+
+	saldo := 0
+	for _, l := range c.razao.Historico() {
+		if l.Data.After(fim) {
+			break
+		}
+		valor := 0
+		for _, p := range l.Partidas {
+			if p.Conta == c.id {
+				valor += efeito(p)
+			}
+		}
+		if valor == 0 {
+			continue
+		}
+		saldo += valor
+		if l.Data.Before(inicio) {
+			extrato.SaldoInicial = saldo
+			continue
+		}
+		extrato.Movimentos = append(extrato.Movimentos, MovimentoExtrato{
+			Seq:       l.Seq,
+			Data:      l.Data,
+			Descricao: l.Descricao,
+			Valor:     valor,
+			Saldo:     saldo,
+		})
+	}
+	extrato.SaldoFinal = saldo
+	return extrato
+}
+
+// FormatoExtrato escolhe a saída de Extrato.Escrever.
+type FormatoExtrato string
+
+const (
+	ExtratoTexto FormatoExtrato = "texto"
+	ExtratoCSV   FormatoExtrato = "csv"
+	ExtratoJSON  FormatoExtrato = "json"
+	ExtratoOFX   FormatoExtrato = "ofx"
+)
+
+func (e Extrato) Escrever(w io.Writer, formato FormatoExtrato) error {
+	switch formato {
+	case ExtratoTexto:
+		return e.EscreverTexto(w)
+	case ExtratoCSV:
+		return e.EscreverCSV(w)
+	case ExtratoJSON:
+		return e.EscreverJSON(w)
+	case ExtratoOFX:
+		return e.EscreverOFX(w)
+	}
+	return fmt.Errorf("formato de extrato desconhecido: %q", formato)
+}
+
+// EscreverTexto gera o extrato em colunas alinhadas, com valores no formato
+// brasileiro e as casas decimais da moeda da conta.
+func (e Extrato) EscreverTexto(w io.Writer) error {
+	casas := e.casas()
+	fmt.Fprintf(w, "Extrato da conta %s (%s)\n", e.ContaID, e.Titular)
+	fmt.Fprintf(w, "Período: %s a %s\n\n", e.Inicio.Format("02/01/2006"), e.Fim.Format("02/01/2006"))
+
+	// descrições alinhadas à esquerda e valores à direita
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Data\tDescrição\t%14s\t%14s\n", "Valor", "Saldo")
+	fmt.Fprintf(tw, "\tSaldo anterior\t%14s\t%14s\n", "", formatarValor(e.SaldoInicial, casas))
+	for _, m := range e.Movimentos {
+		fmt.Fprintf(tw, "%s\t%s\t%14s\t%14s\n",
+			m.Data.Format("02/01/2006"), m.Descricao, formatarValor(m.Valor, casas), formatarValor(m.Saldo, casas))
+	}
+	fmt.Fprintf(tw, "\tSaldo final\t%14s\t%14s\n", "", formatarValor(e.SaldoFinal, casas))
+	return tw.Flush()
+}
+
+// EscreverCSV gera uma linha por movimento, com ponto como separador
+// decimal para que planilhas e ferramentas de importação leiam os números.
+func (e Extrato) EscreverCSV(w io.Writer) error {
+	casas := e.casas()
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"data", "descricao", "valor", "saldo"})
+	cw.Write([]string{e.Inicio.Format(time.DateOnly), "saldo anterior", "", formatarDecimal(e.SaldoInicial, casas)})
+	for _, m := range e.Movimentos {
+		cw.Write([]string{m.Data.Format(time.DateOnly), m.Descricao, formatarDecimal(m.Valor, casas), formatarDecimal(m.Saldo, casas)})
+	}
+	cw.Write([]string{e.Fim.Format(time.DateOnly), "saldo final", "", formatarDecimal(e.SaldoFinal, casas)})
+	cw.Flush()
+	return cw.Error()
+}
+
+func (e Extrato) EscreverJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(e)
+}
+
+// EscreverOFX gera um OFX 1.02 (SGML), o formato aceito pela maioria dos
+// programas de finanças pessoais.
+func (e Extrato) EscreverOFX(w io.Writer) error {
+	casas := e.casas()
+	const formatoData = "20060102150405"
+
+	fmt.Fprint(w, "OFXHEADER:100\nDATA:OFXSGML\nVERSION:102\nSECURITY:NONE\nENCODING:UTF-8\nCHARSET:NONE\nCOMPRESSION:NONE\nOLDFILEUID:NONE\nNEWFILEUID:NONE\n\n")
+	fmt.Fprint(w, "<OFX>\n<BANKMSGSRSV1>\n<STMTTRNRS>\n<TRNUID>1\n<STATUS>\n<CODE>0\n<SEVERITY>INFO\n</STATUS>\n<STMTRS>\n")
+	fmt.Fprintf(w, "<CURDEF>%s\n", e.Moeda)
+	fmt.Fprintf(w, "<BANKACCTFROM>\n<BANKID>0\n<ACCTID>%s\n<ACCTTYPE>CHECKING\n</BANKACCTFROM>\n", e.ContaID)
+	fmt.Fprintf(w, "<BANKTRANLIST>\n<DTSTART>%s\n<DTEND>%s\n", e.Inicio.Format(formatoData), e.Fim.Format(formatoData))
+	for _, m := range e.Movimentos {
+		tipo := "CREDIT"
+		if m.Valor < 0 {
+			tipo = "DEBIT"
+		}
+		fmt.Fprintf(w, "<STMTTRN>\n<TRNTYPE>%s\n<DTPOSTED>%s\n<TRNAMT>%s\n<FITID>%s-%d\n<MEMO>%s\n</STMTTRN>\n",
+			tipo, m.Data.Format(formatoData), formatarDecimal(m.Valor, casas), e.ContaID, m.Seq, escaparSGML(m.Descricao))
+	}
+	fmt.Fprint(w, "</BANKTRANLIST>\n")
+	_, err := fmt.Fprintf(w, "<LEDGERBAL>\n<BALAMT>%s\n<DTASOF>%s\n</LEDGERBAL>\n</STMTRS>\n</STMTTRNRS>\n</BANKMSGSRSV1>\n</OFX>\n",
+		formatarDecimal(e.SaldoFinal, casas), e.Fim.Format(formatoData))
+	return err
+}
+
+func escaparSGML(texto string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(texto)
+}
+
+// casas devolve as casas decimais da moeda do extrato. Extratos sem moeda
+// são de contas antigas, sempre em reais.
+func (e Extrato) casas() int {
+	if e.Moeda == "" {
+		return casasDecimais[BRL]
+	}
+	casas, err := e.Moeda.CasasDecimais()
+	if err != nil {
+		return casasDecimais[BRL]
+	}
+	return casas
+}
+
+// formatarValor escreve um valor na menor unidade da moeda no formato
+// brasileiro: com 2 casas, 123456 vira "1.234,56"; com 0 (iene), "123.456".
+func formatarValor(valor, casas int) string {
+	sinal := ""
+	if valor < 0 {
+		sinal = "-"
+		valor = -valor
+	}
+	escala := potenciaDe10(casas)
+	inteiro := strconv.Itoa(valor / escala)
+	for i := len(inteiro) - 3; i > 0; i -= 3 {
+		inteiro = inteiro[:i] + "." + inteiro[i:]
+	}
+	if casas == 0 {
+		return sinal + inteiro
+	}
+	return fmt.Sprintf("%s%s,%0*d", sinal, inteiro, casas, valor%escala)
+}
+
+// formatarDecimal escreve um valor na menor unidade da moeda com ponto
+// decimal e sem separador de milhar: com 2 casas, -123456 vira "-1234.56".
+func formatarDecimal(valor, casas int) string {
+	sinal := ""
+	if valor < 0 {
+		sinal = "-"
+		valor = -valor
+	}
+	escala := potenciaDe10(casas)
+	if casas == 0 {
+		return fmt.Sprintf("%s%d", sinal, valor)
+	}
+	return fmt.Sprintf("%s%d.%0*d", sinal, valor/escala, casas, valor%escala)
+}
+
+func potenciaDe10(n int) int {
+	p := 1
+	for range n {
+		p *= 10
+	}
+	return p
+}
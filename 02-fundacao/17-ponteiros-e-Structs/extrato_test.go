@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// go test -run Extrato -atualizar regrava os arquivos de testdata depois de
+// uma mudança proposital no formato.
+var atualizar = flag.Bool("atualizar", false, "regrava os arquivos golden de testdata")
+
+// extratoDeExemplo monta um extrato com saldo anterior, crédito, débito,
+// transferência e uma descrição que precisa de escape no OFX.
+func extratoDeExemplo(t *testing.T, moeda Moeda) Extrato {
+	t.Helper()
+	relogio := NovoRelogioFake(time.Date(2025, 3, 28, 10, 0, 0, 0, time.UTC))
+	razao := NovoRazao(relogio)
+	conta, err := NovaContaEmMoeda(razao, "Ana & Cia", moeda, 150_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outra, err := NovaContaEmMoeda(razao, "Bia", moeda, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	relogio.Avancar(5 * 24 * time.Hour) // 02/04
+	if err := conta.Depositar(123_456); err != nil {
+		t.Fatal(err)
+	}
+	relogio.Avancar(24 * time.Hour)
+	if err := conta.Sacar(20_050); err != nil {
+		t.Fatal(err)
+	}
+	relogio.Avancar(24 * time.Hour)
+	if err := Transferir(conta, outra, 123_400); err != nil {
+		t.Fatal(err)
+	}
+	relogio.Avancar(24 * time.Hour)
+	if _, err := razao.Lancar("estorno <tarifa> & ajuste",
+		Partida{Conta: contaCaixa(moeda), Natureza: Debito, Valor: 990},
+		Partida{Conta: conta.ID(), Natureza: Credito, Valor: 990},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	inicio := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	fim := time.Date(2025, 4, 30, 23, 59, 59, 0, time.UTC)
+	extrato := conta.Extrato(inicio, fim)
+	// O id vem de um contador global e muda com a ordem dos testes.
+	extrato.ContaID = "conta-1"
+	return extrato
+}
+
+func TestExtratoGolden(t *testing.T) {
+	casos := []struct {
+		arquivo string
+		moeda   Moeda
+		formato FormatoExtrato
+	}{
+		{"extrato.txt", BRL, ExtratoTexto},
+		{"extrato.csv", BRL, ExtratoCSV},
+		{"extrato.json", BRL, ExtratoJSON},
+		{"extrato.ofx", BRL, ExtratoOFX},
+		{"extrato_jpy.txt", JPY, ExtratoTexto},
+		{"extrato_jpy.ofx", JPY, ExtratoOFX},
+	}
+	for _, caso := range casos {
+		t.Run(caso.arquivo, func(t *testing.T) {
+			var saida bytes.Buffer
+			if err := extratoDeExemplo(t, caso.moeda).Escrever(&saida, caso.formato); err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", caso.arquivo)
+			if *atualizar {
+				if err := os.WriteFile(golden, saida.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			esperado, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (rode com -atualizar para criar)", err)
+			}
+			if !bytes.Equal(saida.Bytes(), esperado) {
+				t.Errorf("saída difere de %s:\n--- obtido\n%s\n--- esperado\n%s", golden, saida.Bytes(), esperado)
+			}
+		})
+	}
+}
+
+func TestExtratoFormatoDesconhecido(t *testing.T) {
+	if err := extratoDeExemplo(t, BRL).Escrever(&bytes.Buffer{}, "xml"); err == nil {
+		t.Fatal("esperava erro para formato desconhecido")
+	}
+}
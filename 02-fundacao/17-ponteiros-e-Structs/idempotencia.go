@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+var (
+	// ErrConflitoIdempotencia é retornado quando uma chave já usada chega
+	// com uma operação diferente da original.
+	ErrConflitoIdempotencia = erros.Definir("idempotencia.conflito", erros.Conflito).Sentinela("chave de idempotência já usada com outra operação")
+	ErrChaveIdempotencia    = erros.Definir("idempotencia.chave_vazia", erros.Validacao).Sentinela("chave de idempotência vazia")
+	// ErrOperacaoInterrompida é o que recebe quem repete uma chave cuja
+	// operação terminou em panic.
+	ErrOperacaoInterrompida = erros.Definir("idempotencia.operacao_interrompida", erros.Interno).Sentinela("a operação foi interrompida por um panic")
+)
+
+// ResultadoOperacao é o que uma operação idempotente devolve. Referencia
+// identifica o que a operação criou, quando for o caso (o id de uma conta
+// aberta, por exemplo). Repetida indica que a operação não foi executada de
+// novo: o resultado é o da primeira vez.
+type ResultadoOperacao struct {
+	Saldo      int
+	Referencia string
+	Repetida   bool
+}
+
+type entradaIdempotencia struct {
+	impressao string
+	expira    time.Time
+	pronta    chan struct{}
+	resultado ResultadoOperacao
+	err       error
+}
+
+// Idempotencia guarda o resultado de cada operação pela chave enviada pelo
+// cliente, para que uma nova tentativa com a mesma chave devolva o resultado
+// original em vez de aplicar a operação de novo. Erros também são guardados:
+// repetir um saque recusado devolve a mesma recusa.
+type Idempotencia struct {
+	relogio  Relogio
+	retencao time.Duration
+
+	mu            sync.Mutex
+	entradas      map[string]*entradaIdempotencia
+	proximaLimpar time.Time
+}
+
+// NovaIdempotencia cria o registro de chaves. Cada chave vale pelo tempo de
+// retenção, contado a partir da primeira execução. As chaves vencidas são
+// apagadas durante as inserções, no máximo uma varredura por período de
+// retenção, então o mapa não cresce sem limite mesmo que ninguém chame
+// Limpar.
+func NovaIdempotencia(relogio Relogio, retencao time.Duration) *Idempotencia {
+	if relogio == nil {
+		relogio = relogioSistema{}
+	}
+	return &Idempotencia{relogio: relogio, retencao: retencao, entradas: map[string]*entradaIdempotencia{}}
+}
+
+// Executar roda op uma única vez por chave. impressao identifica a operação e
+// os seus parâmetros; a mesma chave com outra impressão é um conflito.
+// Chamadas simultâneas com a mesma chave esperam a primeira terminar.
+func (i *Idempotencia) Executar(chave, impressao string, op func() (ResultadoOperacao, error)) (ResultadoOperacao, error) {
+	if chave == "" {
+		return ResultadoOperacao{}, ErrChaveIdempotencia
+	}
+
+	i.mu.Lock()
+	agora := i.relogio.Agora()
+	if entrada, ok := i.entradas[chave]; ok && agora.Before(entrada.expira) {
+		i.mu.Unlock()
+		if entrada.impressao != impressao {
+			return ResultadoOperacao{}, ErrConflitoIdempotencia
+		}
+		<-entrada.pronta
+		resultado := entrada.resultado
+		resultado.Repetida = true
+		return resultado, entrada.err
+	}
+	if !agora.Before(i.proximaLimpar) {
+		i.limpar(agora)
+		i.proximaLimpar = agora.Add(i.retencao)
+	}
+	entrada := &entradaIdempotencia{
+		impressao: impressao,
+		expira:    agora.Add(i.retencao),
+		pronta:    make(chan struct{}),
+	}
+	i.entradas[chave] = entrada
+	i.mu.Unlock()
+
+	// Mesmo que op entre em panic, quem espera pela chave é liberado e
+	// recebe um erro, em vez de ficar bloqueado para sempre. O panic segue
+	// para quem chamou primeiro.
+	defer func() {
+		if p := recover(); p != nil {
+			entrada.err = fmt.Errorf("%w: %v", ErrOperacaoInterrompida, p)
+			close(entrada.pronta)
+			panic(p)
+		}
+		close(entrada.pronta)
+	}()
+	entrada.resultado, entrada.err = op()
+	return entrada.resultado, entrada.err
+}
+
+// Limpar remove as chaves vencidas e devolve quantas foram removidas.
+func (i *Idempotencia) Limpar() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.limpar(i.relogio.Agora())
+}
+
+// limpar deve ser chamado com i.mu travado.
+func (i *Idempotencia) limpar(agora time.Time) int {
+	removidas := 0
+	for chave, entrada := range i.entradas {
+		if !agora.Before(entrada.expira) {
+			delete(i.entradas, chave)
+			removidas++
+		}
+	}
+	return removidas
+}
+
+func (i *Idempotencia) Depositar(chave string, c *Conta, valor int) (ResultadoOperacao, error) {
+	return i.Executar(chave, fmt.Sprintf("depositar|%s|%d", c.id, valor), func() (ResultadoOperacao, error) {
+		saldo, err := c.depositar(valor)
+		return ResultadoOperacao{Saldo: saldo}, err
+	})
+}
+
+func (i *Idempotencia) Sacar(chave string, c *Conta, valor int) (ResultadoOperacao, error) {
+	return i.Executar(chave, fmt.Sprintf("sacar|%s|%d", c.id, valor), func() (ResultadoOperacao, error) {
+		saldo, err := c.sacar(valor)
+		return ResultadoOperacao{Saldo: saldo}, err
+	})
+}
+
+// Transferir devolve o saldo da origem depois da transferência.
+func (i *Idempotencia) Transferir(chave string, origem, destino *Conta, valor int) (ResultadoOperacao, error) {
+	return i.Executar(chave, fmt.Sprintf("transferir|%s|%s|%d", origem.id, destino.id, valor), func() (ResultadoOperacao, error) {
+		saldo, err := transferir(origem, destino, valor, OperacaoTransferencia, "transferência")
+		return ResultadoOperacao{Saldo: saldo}, err
+	})
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestIdempotenciaPanicLiberaQuemEspera(t *testing.T) {
+	idem := NovaIdempotencia(NovoRelogioFake(time.Unix(0, 0)), time.Hour)
+	comecou, solta := make(chan struct{}), make(chan struct{})
+
+	panicou := make(chan any)
+	go func() {
+		defer func() { panicou <- recover() }()
+		idem.Executar("k1", "op", func() (ResultadoOperacao, error) {
+			close(comecou)
+			<-solta
+			panic("falhou no meio")
+		})
+	}()
+	<-comecou
+
+	repetida := make(chan error)
+	go func() {
+		_, err := idem.Executar("k1", "op", func() (ResultadoOperacao, error) {
+			t.Error("a operação não deveria rodar de novo")
+			return ResultadoOperacao{}, nil
+		})
+		repetida <- err
+	}()
+	close(solta)
+
+	if p := <-panicou; p != "falhou no meio" {
+		t.Fatalf("panic repassado = %v, quero o original", p)
+	}
+	select {
+	case err := <-repetida:
+		if !errors.Is(err, ErrOperacaoInterrompida) {
+			t.Fatalf("erro = %v, quero ErrOperacaoInterrompida", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("quem esperava pela chave ficou bloqueado")
+	}
+}
+
+func TestIdempotenciaRepeticaoDevolveOResultadoOriginal(t *testing.T) {
+	idem := NovaIdempotencia(NovoRelogioFake(time.Unix(0, 0)), time.Hour)
+	conta, err := NovaContaNoRazao(NovoRazao(nil), "Ana", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	primeira, err := idem.Depositar("pedido-1", conta, 500)
+	if err != nil || primeira.Saldo != 1500 || primeira.Repetida {
+		t.Fatalf("primeira = %+v, %v; quero saldo 1500", primeira, err)
+	}
+	// um movimento sem chave no meio não muda o resultado guardado
+	if err := conta.Sacar(200); err != nil {
+		t.Fatal(err)
+	}
+	repetida, err := idem.Depositar("pedido-1", conta, 500)
+	if err != nil || repetida.Saldo != 1500 || !repetida.Repetida {
+		t.Errorf("repetida = %+v, %v; quero o saldo original 1500 com Repetida", repetida, err)
+	}
+	if conta.Saldo() != 1300 {
+		t.Errorf("saldo = %d, quero 1300 (o depósito não pode ser aplicado de novo)", conta.Saldo())
+	}
+
+	// a recusa também é guardada
+	_, errSaque := idem.Sacar("pedido-2", conta, 5000)
+	var semSaldo *ErrSaldoInsuficiente
+	if !errors.As(errSaque, &semSaldo) {
+		t.Fatalf("saque grande = %v, quero saldo insuficiente", errSaque)
+	}
+	if err := conta.Depositar(10_000); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := idem.Sacar("pedido-2", conta, 5000); !errors.As(err, &semSaldo) {
+		t.Errorf("saque repetido = %v, quero a mesma recusa", err)
+	}
+}
+
+func TestIdempotenciaConflito(t *testing.T) {
+	idem := NovaIdempotencia(NovoRelogioFake(time.Unix(0, 0)), time.Hour)
+	razao := NovoRazao(nil)
+	ana, _ := NovaContaNoRazao(razao, "Ana", 1000)
+	bia, _ := NovaContaNoRazao(razao, "Bia", 0)
+
+	if _, err := idem.Transferir("pedido-1", ana, bia, 100); err != nil {
+		t.Fatal(err)
+	}
+	outras := []func() (ResultadoOperacao, error){
+		func() (ResultadoOperacao, error) { return idem.Transferir("pedido-1", ana, bia, 200) },
+		func() (ResultadoOperacao, error) { return idem.Transferir("pedido-1", bia, ana, 100) },
+		func() (ResultadoOperacao, error) { return idem.Depositar("pedido-1", ana, 100) },
+	}
+	for i, op := range outras {
+		if _, err := op(); !errors.Is(err, ErrConflitoIdempotencia) {
+			t.Errorf("operação %d: erro = %v, quero ErrConflitoIdempotencia", i, err)
+		}
+	}
+	if ana.Saldo() != 900 || bia.Saldo() != 100 {
+		t.Errorf("saldos = %d e %d, quero 900 e 100", ana.Saldo(), bia.Saldo())
+	}
+	if _, err := idem.Depositar("", ana, 1); !errors.Is(err, ErrChaveIdempotencia) {
+		t.Errorf("chave vazia: erro = %v, quero ErrChaveIdempotencia", err)
+	}
+}
+
+func TestIdempotenciaChaveVence(t *testing.T) {
+	relogio := NovoRelogioFake(time.Unix(0, 0))
+	idem := NovaIdempotencia(relogio, time.Hour)
+	conta, _ := NovaContaNoRazao(NovoRazao(nil), "Ana", 0)
+
+	if _, err := idem.Depositar("pedido-1", conta, 100); err != nil {
+		t.Fatal(err)
+	}
+	relogio.Avancar(time.Hour - time.Second)
+	if r, _ := idem.Depositar("pedido-1", conta, 100); !r.Repetida {
+		t.Error("a chave venceu antes da retenção")
+	}
+	relogio.Avancar(time.Second)
+	// vencida, a chave pode ser reusada, inclusive com outra operação
+	r, err := idem.Depositar("pedido-1", conta, 300)
+	if err != nil || r.Repetida || r.Saldo != 400 {
+		t.Errorf("depois da retenção = %+v, %v; quero uma execução nova com saldo 400", r, err)
+	}
+}
+
+func TestIdempotenciaApagaVencidasAoInserir(t *testing.T) {
+	relogio := NovoRelogioFake(time.Unix(0, 0))
+	idem := NovaIdempotencia(relogio, time.Hour)
+	operacao := func() (ResultadoOperacao, error) { return ResultadoOperacao{}, nil }
+
+	for i := range 100 {
+		idem.Executar(fmt.Sprintf("antiga-%d", i), "op", operacao)
+	}
+	relogio.Avancar(2 * time.Hour)
+	idem.Executar("nova", "op", operacao)
+
+	idem.mu.Lock()
+	restantes := len(idem.entradas)
+	idem.mu.Unlock()
+	if restantes != 1 {
+		t.Errorf("%d chaves no registro, quero só a nova", restantes)
+	}
+	if n := idem.Limpar(); n != 0 {
+		t.Errorf("Limpar removeu %d, quero 0", n)
+	}
+	relogio.Avancar(time.Hour)
+	if n := idem.Limpar(); n != 1 {
+		t.Errorf("Limpar removeu %d, quero 1", n)
+	}
+}
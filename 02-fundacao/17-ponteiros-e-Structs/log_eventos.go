@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+// ErrLogCorrompido é retornado quando um registro no meio do log não confere
+// com o CRC. Um registro incompleto no final do arquivo não é erro: é uma
+// gravação interrompida, e o log é truncado no último registro bom.
+var ErrLogCorrompido = erros.Definir("eventos.log_corrompido", erros.Interno).Sentinela("log de eventos corrompido")
+
+// Evento é o envelope gravado no log. Dados traz o evento em si (ContaAberta,
+// Depositado...) em JSON, na versão indicada por Versao.
+type Evento struct {
+	Seq     uint64          `json:"seq"`
+	ContaID string          `json:"conta"`
+	Tipo    string          `json:"tipo"`
+	Versao  int             `json:"versao"`
+	Data    time.Time       `json:"data"`
+	Dados   json.RawMessage `json:"dados"`
+}
+
+// Upcaster converte um evento da versão em que foi gravado para a versão
+// seguinte. Os upcasters são indexados por tipo e versão de origem e são
+// aplicados em cadeia até chegar à versão atual.
+type Upcaster func(Evento) (Evento, error)
+
+type chaveUpcaster struct {
+	tipo   string
+	versao int
+}
+
+// Upcasters guarda os conversores de esquema registrados.
+type Upcasters map[chaveUpcaster]Upcaster
+
+func (u Upcasters) Registrar(tipo string, deVersao int, conversor Upcaster) {
+	u[chaveUpcaster{tipo, deVersao}] = conversor
+}
+
+// Atualizar aplica os upcasters até não haver mais nenhum para a versão do evento.
+func (u Upcasters) Atualizar(e Evento) (Evento, error) {
+	for {
+		conversor, ok := u[chaveUpcaster{e.Tipo, e.Versao}]
+		if !ok {
+			return e, nil
+		}
+		proximo, err := conversor(e)
+		if err != nil {
+			return e, fmt.Errorf("upcast de %s v%d: %w", e.Tipo, e.Versao, err)
+		}
+		proximo.Versao = e.Versao + 1
+		e = proximo
+	}
+}
+
+// LogEventos é um arquivo só de acréscimo. Cada registro tem o tamanho do
+// conteúdo (4 bytes), o CRC32 do conteúdo (4 bytes) e o evento em JSON, ou
+// um array JSON de eventos quando vários precisam ser gravados juntos (veja
+// AnexarLote). Os eventos lidos ficam também em memória, já atualizados
+// pelos upcasters, com um índice por conta: buscar os eventos de uma conta
+// depois de uma sequência não percorre os das outras contas nem os
+// anteriores a ela.
+type LogEventos struct {
+	mu          sync.Mutex
+	arquivo     *os.File
+	tamanho     int64 // até onde o arquivo tem registros completos
+	eventos     []Evento
+	porConta    map[string][]int // posições em eventos, em ordem de Seq
+	sincronizar bool
+}
+
+// AbrirLogEventos abre (ou cria) o log, lê todos os registros e trunca uma
+// eventual gravação incompleta no final. Com sincronizar, cada Anexar faz
+// fsync antes de retornar.
+func AbrirLogEventos(caminho string, upcasters Upcasters, sincronizar bool) (*LogEventos, error) {
+	arquivo, err := os.OpenFile(caminho, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	l := &LogEventos{arquivo: arquivo, porConta: map[string][]int{}, sincronizar: sincronizar}
+
+	valido, err := l.ler(upcasters)
+	if err != nil {
+		arquivo.Close()
+		return nil, err
+	}
+	if err := arquivo.Truncate(valido); err != nil {
+		arquivo.Close()
+		return nil, err
+	}
+	if _, err := arquivo.Seek(valido, io.SeekStart); err != nil {
+		arquivo.Close()
+		return nil, err
+	}
+	l.tamanho = valido
+	return l, nil
+}
+
+// ler carrega os eventos e devolve até onde o arquivo está íntegro.
+func (l *LogEventos) ler(upcasters Upcasters) (int64, error) {
+	info, err := l.arquivo.Stat()
+	if err != nil {
+		return 0, err
+	}
+	tamanho := info.Size()
+	leitor := bufio.NewReader(l.arquivo)
+
+	var posicao int64
+	cabecalho := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(leitor, cabecalho); err != nil {
+			// fim do arquivo ou cabeçalho cortado: para no último registro bom
+			return posicao, nil
+		}
+		comprimento := int64(binary.BigEndian.Uint32(cabecalho[0:4]))
+		crc := binary.BigEndian.Uint32(cabecalho[4:8])
+		fim := posicao + 8 + comprimento
+		if fim > tamanho {
+			// O tamanho não entra no CRC, então um registro que passa do fim
+			// do arquivo pode ser uma gravação interrompida ou um tamanho
+			// corrompido. Só no primeiro caso não há nada íntegro depois.
+			depois, err := l.haRegistroDepois(posicao+1, tamanho)
+			if err != nil {
+				return 0, err
+			}
+			if depois {
+				return 0, fmt.Errorf("%w: tamanho inválido no registro na posição %d", ErrLogCorrompido, posicao)
+			}
+			return posicao, nil
+		}
+
+		conteudo := make([]byte, comprimento)
+		if _, err := io.ReadFull(leitor, conteudo); err != nil {
+			return posicao, nil
+		}
+		if crc32.ChecksumIEEE(conteudo) != crc {
+			if fim == tamanho {
+				return posicao, nil
+			}
+			return 0, fmt.Errorf("%w: registro na posição %d", ErrLogCorrompido, posicao)
+		}
+
+		var lote []Evento
+		if len(conteudo) > 0 && conteudo[0] == '[' {
+			err = json.Unmarshal(conteudo, &lote)
+		} else {
+			lote = make([]Evento, 1)
+			err = json.Unmarshal(conteudo, &lote[0])
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%w: registro na posição %d: %v", ErrLogCorrompido, posicao, err)
+		}
+		for _, evento := range lote {
+			evento, err := upcasters.Atualizar(evento)
+			if err != nil {
+				return 0, err
+			}
+			l.indexar(evento)
+		}
+		posicao = fim
+	}
+}
+
+// haRegistroDepois procura, de inicio até o fim do arquivo, um registro
+// com CRC válido. Uma gravação interrompida deixa no máximo um registro
+// pela metade no final; achar um registro inteiro depois do ponto de corte
+// quer dizer que o corte não estava no fim. Só roda quando a leitura já
+// encontrou um problema, então ler o resto do arquivo de uma vez é aceitável.
+func (l *LogEventos) haRegistroDepois(inicio, tamanho int64) (bool, error) {
+	resto, err := io.ReadAll(io.NewSectionReader(l.arquivo, inicio, tamanho-inicio))
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i+8 < len(resto); i++ {
+		comprimento := int(binary.BigEndian.Uint32(resto[i : i+4]))
+		if comprimento == 0 || comprimento > len(resto)-i-8 {
+			continue
+		}
+		conteudo := resto[i+8 : i+8+comprimento]
+		if crc32.ChecksumIEEE(conteudo) == binary.BigEndian.Uint32(resto[i+4:i+8]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// NovoEvento é um evento ainda sem sequência, para AnexarLote.
+type NovoEvento struct {
+	ContaID string
+	Tipo    string
+	Versao  int
+	Data    time.Time
+	Dados   any
+}
+
+// Anexar grava um evento novo com a próxima sequência.
+func (l *LogEventos) Anexar(contaID, tipo string, versao int, data time.Time, dados any) (Evento, error) {
+	eventos, err := l.AnexarLote(NovoEvento{ContaID: contaID, Tipo: tipo, Versao: versao, Data: data, Dados: dados})
+	if err != nil {
+		return Evento{}, err
+	}
+	return eventos[0], nil
+}
+
+// AnexarLote grava vários eventos, com sequências consecutivas, num único
+// registro. Como o CRC cobre o registro inteiro, uma gravação interrompida
+// descarta o lote todo: ou todos os eventos entram no log, ou nenhum. É o
+// que impede uma transferência de ficar só com o débito.
+func (l *LogEventos) AnexarLote(novos ...NovoEvento) ([]Evento, error) {
+	if len(novos) == 0 {
+		return nil, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	eventos := make([]Evento, len(novos))
+	for i, novo := range novos {
+		dados, err := json.Marshal(novo.Dados)
+		if err != nil {
+			return nil, err
+		}
+		eventos[i] = Evento{
+			Seq:     uint64(len(l.eventos) + i + 1),
+			ContaID: novo.ContaID,
+			Tipo:    novo.Tipo,
+			Versao:  novo.Versao,
+			Data:    novo.Data,
+			Dados:   dados,
+		}
+	}
+	// Um evento sozinho é gravado como objeto, o formato de sempre; só os
+	// lotes usam array.
+	var conteudo []byte
+	var err error
+	if len(eventos) == 1 {
+		conteudo, err = json.Marshal(eventos[0])
+	} else {
+		conteudo, err = json.Marshal(eventos)
+	}
+	if err != nil {
+		return nil, err
+	}
+	registro := make([]byte, 8+len(conteudo))
+	binary.BigEndian.PutUint32(registro[0:4], uint32(len(conteudo)))
+	binary.BigEndian.PutUint32(registro[4:8], crc32.ChecksumIEEE(conteudo))
+	copy(registro[8:], conteudo)
+
+	if err := l.gravar(registro); err != nil {
+		return nil, err
+	}
+	for _, evento := range eventos {
+		l.indexar(evento)
+	}
+	return eventos, nil
+}
+
+// indexar guarda o evento em memória e no índice da conta. Deve ser chamado
+// com l.mu travado (ou antes de o log ser devolvido por AbrirLogEventos).
+func (l *LogEventos) indexar(evento Evento) {
+	l.porConta[evento.ContaID] = append(l.porConta[evento.ContaID], len(l.eventos))
+	l.eventos = append(l.eventos, evento)
+}
+
+// gravar acrescenta o registro ao arquivo. Se a escrita ou o fsync falhar,
+// o arquivo volta ao tamanho anterior: sem isso, o próximo registro ficaria
+// depois de um pedaço inválido e o log não abriria mais. Deve ser chamado
+// com l.mu travado.
+func (l *LogEventos) gravar(registro []byte) error {
+	_, err := l.arquivo.Write(registro)
+	if err == nil && l.sincronizar {
+		err = l.arquivo.Sync()
+	}
+	if err != nil {
+		l.arquivo.Truncate(l.tamanho)
+		l.arquivo.Seek(l.tamanho, io.SeekStart)
+		return err
+	}
+	l.tamanho += int64(len(registro))
+	return nil
+}
+
+// Eventos devolve os eventos da conta com sequência maior que depoisDe e
+// menor ou igual a ate (ate == 0 significa sem limite). A busca binária no
+// índice da conta pula direto para o primeiro evento depois de depoisDe, então
+// carregar a partir de um snapshot custa só os eventos posteriores a ele.
+func (l *LogEventos) Eventos(contaID string, depoisDe, ate uint64) []Evento {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	posicoes := l.porConta[contaID]
+	inicio := sort.Search(len(posicoes), func(i int) bool {
+		return l.eventos[posicoes[i]].Seq > depoisDe
+	})
+	var eventos []Evento
+	for _, p := range posicoes[inicio:] {
+		e := l.eventos[p]
+		if ate != 0 && e.Seq > ate {
+			break
+		}
+		eventos = append(eventos, e)
+	}
+	return eventos
+}
+
+// UltimaSeq devolve a sequência do último evento gravado.
+func (l *LogEventos) UltimaSeq() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return uint64(len(l.eventos))
+}
+
+func (l *LogEventos) Fechar() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.arquivo.Close()
+}
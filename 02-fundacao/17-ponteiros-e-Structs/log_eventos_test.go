@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// logComEventos grava n depósitos num log novo e devolve o caminho e onde
+// começa cada registro.
+func logComEventos(t *testing.T, n int) (string, []int64) {
+	t.Helper()
+	caminho := filepath.Join(t.TempDir(), "contas.log")
+	log, err := AbrirLogEventos(caminho, UpcastersPadrao(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inicios []int64
+	for i := range n {
+		info, err := os.Stat(caminho)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inicios = append(inicios, info.Size())
+		if _, err := log.Anexar("es-1", EventoDepositado, 2, time.Unix(0, 0), Depositado{Valor: 100 * (i + 1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := log.Fechar(); err != nil {
+		t.Fatal(err)
+	}
+	return caminho, inicios
+}
+
+func TestLogEventosTruncaGravacaoInterrompida(t *testing.T) {
+	caminho, _ := logComEventos(t, 3)
+	info, err := os.Stat(caminho)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(caminho, info.Size()-5); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := AbrirLogEventos(caminho, UpcastersPadrao(), false)
+	if err != nil {
+		t.Fatalf("abrir log com o fim cortado: %v", err)
+	}
+	defer log.Fechar()
+	if got := log.UltimaSeq(); got != 2 {
+		t.Fatalf("UltimaSeq = %d, quero 2", got)
+	}
+}
+
+func TestLogEventosTamanhoCorrompidoNoMeio(t *testing.T) {
+	caminho, inicios := logComEventos(t, 3)
+	arquivo, err := os.OpenFile(caminho, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tamanho [4]byte
+	binary.BigEndian.PutUint32(tamanho[:], 1<<30)
+	if _, err := arquivo.WriteAt(tamanho[:], inicios[1]); err != nil {
+		t.Fatal(err)
+	}
+	arquivo.Close()
+
+	_, err = AbrirLogEventos(caminho, UpcastersPadrao(), false)
+	if !errors.Is(err, ErrLogCorrompido) {
+		t.Fatalf("erro = %v, quero ErrLogCorrompido", err)
+	}
+}
+
+func TestLogEventosIndicePorConta(t *testing.T) {
+	log, err := AbrirLogEventos(filepath.Join(t.TempDir(), "contas.log"), UpcastersPadrao(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Fechar()
+
+	contas := []string{"es-1", "es-2", "es-3"}
+	for i := range 30 {
+		// es-1 recebe um evento por vez, es-2 dois e es-3 três, intercalados
+		conta := contas[min(i%6, 2)]
+		if _, err := log.Anexar(conta, EventoDepositado, 2, time.Unix(0, 0), Depositado{Valor: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	todos := log.Eventos("es-1", 0, 0)
+	todos = append(todos, log.Eventos("es-2", 0, 0)...)
+	todos = append(todos, log.Eventos("es-3", 0, 0)...)
+	if len(todos) != 30 {
+		t.Fatalf("%d eventos nas três contas, quero 30", len(todos))
+	}
+
+	for _, conta := range append(contas, "es-9") {
+		for depoisDe := range uint64(32) {
+			for _, ate := range []uint64{0, depoisDe, depoisDe + 1, depoisDe + 7, 30} {
+				var esperados []uint64
+				for _, e := range todos {
+					if e.ContaID == conta && e.Seq > depoisDe && (ate == 0 || e.Seq <= ate) {
+						esperados = append(esperados, e.Seq)
+					}
+				}
+				var obtidos []uint64
+				for _, e := range log.Eventos(conta, depoisDe, ate) {
+					obtidos = append(obtidos, e.Seq)
+				}
+				if !slices.Equal(obtidos, slices.Sorted(slices.Values(esperados))) {
+					t.Fatalf("Eventos(%s, %d, %d) = %v, quero %v", conta, depoisDe, ate, obtidos, esperados)
+				}
+			}
+		}
+	}
+}
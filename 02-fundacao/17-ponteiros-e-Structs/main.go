@@ -1,21 +1,304 @@
 package main
 
-type Conta struct {
-	saldo int
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"02-fundacao/02-fundacao/17-ponteiros-e-Structs/boleto"
+)
+
+func main() {
+	replay := flag.String("replay", "", "log de eventos para reconstruir uma conta (use com -conta e -ate)")
+	replayConta := flag.String("conta", "", "id da conta a reconstruir com -replay")
+	replayAte := flag.Uint64("ate", 0, "sequência até onde reconstruir com -replay (0 = até o fim)")
+	servidor := flag.String("servidor", "", "sobe a API HTTP no endereço informado (ex.: :8080)")
+	flag.Parse()
+	if *servidor != "" {
+		api := NovoServidor(NovoRazao(nil), NovaIdempotencia(nil, 24*time.Hour))
+		fmt.Println("API ouvindo em", *servidor)
+		if err := http.ListenAndServe(*servidor, api); err != nil {
+			fmt.Println("Erro:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *replay != "" {
+		if err := reconstruirConta(*replay, *replayConta, *replayAte); err != nil {
+			fmt.Println("Erro:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	// Criação de uma Conta pelo construtor (saldo em centavos)
+	conta, err := NewConta("João", 100)
+	if err != nil {
+		fmt.Println("Erro:", err)
+		return
+	}
+
+	// Chama o método simular: o saldo da conta não muda
+	conta.simular(200)
+
+	// Imprime o saldo após a simulação (continua 100)
+	println(conta.Saldo())
+
+	// Projeção de 12 meses com depósitos mensais e juros compostos
+	tabela, err := conta.Projetar(ParametrosProjecao{
+		Meses:          12,
+		DepositoMensal: 10000,
+		TaxaMensal:     0.01,
+		Juros:          JurosCompostos,
+	})
+	if err != nil {
+		fmt.Println("Erro:", err)
+		return
+	}
+	for _, linha := range tabela {
+		fmt.Printf("mês %2d: %d + juros %d + depósitos %d = %d\n",
+			linha.Mes, linha.SaldoInicial, linha.Juros, linha.Depositos, linha.SaldoFinal)
+	}
+
+	// Comparação de cenários com taxas diferentes
+	cenarios, _ := CompararCenarios(conta.Saldo(), ParametrosProjecao{Meses: 3, DepositoMensal: 10000}, 0.005, 0.01, 0.02)
+	for i, saldos := range cenarios.Saldos {
+		fmt.Println("mês", i+1, saldos)
+	}
+
+	// Operações bancárias
+	maria, _ := NewConta("Maria", 0)
+	if err := Transferir(conta, maria, 50); err != nil {
+		fmt.Println("Erro:", err)
+	}
+
+	var semSaldo *ErrSaldoInsuficiente
+	if err := maria.Sacar(1000); errors.As(err, &semSaldo) {
+		fmt.Printf("Saque negado: saldo %d, pedido %d\n", semSaldo.Saldo, semSaldo.Valor)
+	}
+
+	// Vários depósitos ao mesmo tempo: o mutex garante que nenhum se perde
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			maria.Depositar(1)
+		}()
+	}
+	wg.Wait()
+	fmt.Println(conta.Titular(), conta.Saldo(), maria.Titular(), maria.Saldo())
+
+	// Os saldos vêm do razão: cada operação gerou um lançamento balanceado
+	balancete := razaoPadrao.Balancete()
+	for _, linha := range balancete.Linhas {
+		fmt.Printf("%-8s D %6d  C %6d\n", linha.Conta, linha.Debitos, linha.Creditos)
+	}
+	fmt.Println("balancete fechado:", balancete.Fechado(), "verificação:", razaoPadrao.Verificar())
+
+	// Cheque especial: a conta pode ficar negativa até o limite
+	pedro, _ := NewConta("Pedro", 10000)
+	pedro.DefinirChequeEspecial(ChequeEspecial{Limite: 50000, TaxaJurosMensal: 0.08})
+	if err := pedro.Sacar(30000); err != nil {
+		fmt.Println("Erro:", err)
+	}
+	for dia := 0; dia < 30; dia++ {
+		pedro.ApurarDia()
+	}
+	encargos := pedro.EncargosAcumulados()
+	fmt.Printf("saldo %d, juros %.2f, IOF %.2f\n", pedro.Saldo(), encargos.Juros, encargos.IOF)
+	pedro.FecharMes()
+	fmt.Println("saldo depois do fechamento:", pedro.Saldo())
+
+	// Idempotência: a segunda tentativa com a mesma chave não deposita de novo
+	idempotencia := NovaIdempotencia(nil, 24*time.Hour)
+	for tentativa := 0; tentativa < 2; tentativa++ {
+		resultado, err := idempotencia.Depositar("pedido-42", maria, 500)
+		fmt.Println("depósito:", resultado.Saldo, "repetido:", resultado.Repetida, err)
+	}
+	if _, err := idempotencia.Depositar("pedido-42", maria, 900); errors.Is(err, ErrConflitoIdempotencia) {
+		fmt.Println("Erro:", err)
+	}
+
+	// Conta com event sourcing: o estado vem do log de eventos
+	if err := exemploEventos(); err != nil {
+		fmt.Println("Erro:", err)
+	}
+
+	// Pix: chaves no diretório local, transferência por chave e "copia e cola"
+	diretorio := NovoDiretorioPix()
+	if _, err := diretorio.Registrar(ChaveCPF, "529.982.247-25", maria); err != nil {
+		fmt.Println("Erro:", err)
+	}
+	evp, _ := diretorio.Registrar(ChaveAleatoria, "", maria)
+	if _, err := diretorio.TransferirPix(pedro, "52998224725", 1000); err != nil {
+		fmt.Println("Erro:", err)
+	}
+	copiaECola, err := BRCode{Chave: evp, Valor: 2500, Nome: maria.Titular(), Cidade: "São Paulo"}.Gerar()
+	if err != nil {
+		fmt.Println("Erro:", err)
+	}
+	fmt.Println(copiaECola)
+	if _, err := diretorio.PagarBRCode(pedro, copiaECola, 0); err != nil {
+		fmt.Println("Erro:", err)
+	}
+	fmt.Println("Maria depois do Pix:", maria.Saldo())
+
+	// Conta em dólar: transferir para uma conta em reais exige câmbio
+	dolares, _ := NovaContaEmMoeda(razaoPadrao, "Ana", USD, 10000)
+	var outraMoeda *ErrMoedasDiferentes
+	if err := Transferir(dolares, maria, 1000); errors.As(err, &outraMoeda) {
+		fmt.Println("Erro:", err)
+	}
+	cambio := NovaTabelaCambio()
+	cambio.Definir(USD, BRL, "5.4321")
+	conversao, err := TransferirComCambio(dolares, maria, 1999, cambio, ArredondarMeioParaPar)
+	if err != nil {
+		fmt.Println("Erro:", err)
+	}
+	fmt.Println(conversao.Origem, "->", conversao.Destino, "taxa", conversao.Taxa)
+
+	// Agendamento mensal no dia 31, com o relógio simulado
+	relogio := NovoRelogioFake(time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC))
+	agendador := NovoAgendador(relogio, Backoff{Inicial: time.Hour, Maximo: 8 * time.Hour, MaxTentativas: 3})
+	aluguel, _ := NewConta("Aluguel", 0)
+	agendador.Agendar(Agendamento{Origem: maria, Destino: aluguel, Valor: 1000, Frequencia: Mensal, DiaDoMes: 31, Inicio: relogio.Agora()})
+	for dia := 0; dia < 90; dia++ {
+		agendador.Executar()
+		relogio.Avancar(24 * time.Hour)
+	}
+	for _, execucao := range agendador.Historico() {
+		fmt.Println(execucao.Prevista.Format("02/01/2006"), execucao.Status, execucao.Erro)
+	}
+
+	// Regras antifraude carregadas de um arquivo JSON e recarregadas quando ele muda
+	if err := exemploRegras(maria, aluguel); err != nil {
+		fmt.Println("Erro:", err)
+	}
+
+	// Boleto de cobrança para a conta da Maria
+	cobranca := boleto.Boleto{
+		Banco:      "001",
+		Vencimento: time.Date(2026, 11, 10, 0, 0, 0, 0, time.UTC),
+		Valor:      15990,
+		CampoLivre: "0000001234567000000012317",
+	}
+	linha, err := cobranca.LinhaDigitavel()
+	if err != nil {
+		fmt.Println("Erro:", err)
+	}
+	fmt.Println(boleto.Formatar(linha))
+	lido, err := boleto.LerLinhaDigitavel(boleto.Formatar(linha), time.Now())
+	fmt.Println(lido.Vencimento.Format("02/01/2006"), lido.Valor, err)
+
+	// Extrato do dia em texto e OFX
+	hoje := time.Now()
+	inicio := time.Date(hoje.Year(), hoje.Month(), hoje.Day(), 0, 0, 0, 0, hoje.Location())
+	extrato := conta.Extrato(inicio, inicio.AddDate(0, 0, 1).Add(-time.Nanosecond))
+	extrato.Escrever(os.Stdout, ExtratoTexto)
+	extrato.Escrever(os.Stdout, ExtratoOFX)
 }
 
-func NewConta() *Conta{
-	return &Conta{saldo:0}
+func exemploEventos() error {
+	dir, err := os.MkdirTemp("", "eventos")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	log, err := AbrirLogEventos(filepath.Join(dir, "contas.log"), UpcastersPadrao(), true)
+	if err != nil {
+		return err
+	}
+	defer log.Fechar()
+	repositorio, err := NovoRepositorioEventos(log, nil, filepath.Join(dir, "snapshots"), 3)
+	if err != nil {
+		return err
+	}
+
+	ana, _ := repositorio.Abrir("Ana", 1000)
+	bia, _ := repositorio.Abrir("Bia", 0)
+	for i := 0; i < 5; i++ {
+		repositorio.Depositar(ana, 100)
+	}
+	if err := repositorio.Transferir(ana, bia, 700); err != nil {
+		return err
+	}
+
+	atual, err := repositorio.Carregar(ana)
+	if err != nil {
+		return err
+	}
+	passado, err := repositorio.Reproduzir(ana, 4)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Ana agora:", atual.Saldo, "na sequência 4:", passado.Saldo)
+	return nil
 }
 
-func (c *Conta) simular(valor int) int {
-	c.saldo += valor
-	println(c.saldo)
-	return c.saldo
+// reconstruirConta é a ferramenta de replay: lê o log e imprime o estado da
+// conta na sequência pedida.
+func reconstruirConta(caminho, conta string, ate uint64) error {
+	log, err := AbrirLogEventos(caminho, UpcastersPadrao(), false)
+	if err != nil {
+		return err
+	}
+	defer log.Fechar()
+
+	estado, err := reproduzir(EstadoConta{}, log.Eventos(conta, 0, ate))
+	if err != nil {
+		return err
+	}
+	saida, err := json.MarshalIndent(estado, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(saida))
+	return nil
 }
 
-func main() {
-	conta := Conta{saldo:100}
-	conta.simular(200)
-	println(conta.saldo)
+func exemploRegras(origem, destino *Conta) error {
+	arquivo, err := os.CreateTemp("", "regras-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(arquivo.Name())
+	fmt.Fprint(arquivo, `{"regras": [{"tipo": "limite_diario", "limite": 300}, {"tipo": "velocidade", "maximo": 2, "janela_minutos": 10}]}`)
+	arquivo.Close()
+
+	regras, err := CarregarRegras(arquivo.Name())
+	if err != nil {
+		return err
+	}
+	motor := NovoMotorRegras(nil, regras...)
+	origem.UsarRegras(motor)
+	defer origem.UsarRegras(nil)
+
+	ctx, cancelar := context.WithCancel(context.Background())
+	defer cancelar()
+	erros := motor.Observar(ctx, arquivo.Name(), 50*time.Millisecond)
+
+	for i := 0; i < 4; i++ {
+		fmt.Println("transferência", i+1, Transferir(origem, destino, 100))
+	}
+
+	// bloqueia o destino sem reiniciar nada
+	time.Sleep(10 * time.Millisecond)
+	os.WriteFile(arquivo.Name(), []byte(`{"regras": [{"tipo": "destinos_bloqueados", "destinos": ["`+destino.ID()+`"]}]}`), 0o644)
+	time.Sleep(200 * time.Millisecond)
+	fmt.Println("depois do reload:", Transferir(origem, destino, 100))
+
+	select {
+	case err := <-erros:
+		return err
+	default:
+		return nil
+	}
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+// Moeda é um código ISO 4217 ("BRL", "USD"...).
+type Moeda string
+
+const (
+	BRL Moeda = "BRL"
+	USD Moeda = "USD"
+	EUR Moeda = "EUR"
+	JPY Moeda = "JPY"
+)
+
+// casasDecimais diz quantas casas tem a menor unidade de cada moeda
+// conhecida, conforme a ISO 4217 (o iene não tem centavos).
+var casasDecimais = map[Moeda]int{
+	BRL:   2,
+	USD:   2,
+	EUR:   2,
+	JPY:   0,
+	"GBP": 2,
+	"ARS": 2,
+	"CLP": 0,
+}
+
+var ErrMoedaDesconhecida = erros.Definir("moeda.desconhecida", erros.Validacao).Sentinela("moeda desconhecida")
+
+// CasasDecimais devolve as casas da menor unidade da moeda.
+func (m Moeda) CasasDecimais() (int, error) {
+	casas, ok := casasDecimais[m]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrMoedaDesconhecida, string(m))
+	}
+	return casas, nil
+}
+
+// ErrMoedasDiferentes é retornado ao combinar valores ou contas de moedas
+// diferentes sem passar por uma conversão.
+type ErrMoedasDiferentes struct {
+	Esperada Moeda
+	Recebida Moeda
+}
+
+func (e *ErrMoedasDiferentes) Error() string {
+	return fmt.Sprintf("moedas diferentes: esperado %s, recebido %s", e.Esperada, e.Recebida)
+}
+
+var errMoedasDiferentes = erros.Definir("moeda.diferentes", erros.Validacao).Novo(nil)
+
+// Unwrap liga o erro ao seu código, para erros.CodigoDe e erros.StatusHTTP.
+func (e *ErrMoedasDiferentes) Unwrap() error {
+	return errMoedasDiferentes
+}
+
+// Dinheiro é um valor na menor unidade da moeda (centavos, no caso do real).
+type Dinheiro struct {
+	Valor int
+	Moeda Moeda
+}
+
+func (d Dinheiro) Somar(outro Dinheiro) (Dinheiro, error) {
+	if d.Moeda != outro.Moeda {
+		return Dinheiro{}, &ErrMoedasDiferentes{Esperada: d.Moeda, Recebida: outro.Moeda}
+	}
+	return Dinheiro{Valor: d.Valor + outro.Valor, Moeda: d.Moeda}, nil
+}
+
+func (d Dinheiro) Subtrair(outro Dinheiro) (Dinheiro, error) {
+	if d.Moeda != outro.Moeda {
+		return Dinheiro{}, &ErrMoedasDiferentes{Esperada: d.Moeda, Recebida: outro.Moeda}
+	}
+	return Dinheiro{Valor: d.Valor - outro.Valor, Moeda: d.Moeda}, nil
+}
+
+func (d Dinheiro) String() string {
+	casas, err := d.Moeda.CasasDecimais()
+	if err != nil || casas == 0 {
+		return fmt.Sprintf("%s %d", d.Moeda, d.Valor)
+	}
+	escala := 1
+	for i := 0; i < casas; i++ {
+		escala *= 10
+	}
+	sinal, valor := "", d.Valor
+	if valor < 0 {
+		sinal, valor = "-", -valor
+	}
+	return fmt.Sprintf("%s %s%d.%0*d", d.Moeda, sinal, valor/escala, casas, valor%escala)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var parametroCaminho = regexp.MustCompile(`\{(\w+)\}`)
+
+// openAPI serve o documento OpenAPI 3 gerado a partir da tabela de rotas.
+func (s *Servidor) openAPI(w http.ResponseWriter, r *http.Request) {
+	escreverJSON(w, http.StatusOK, s.DocumentoOpenAPI())
+}
+
+// DocumentoOpenAPI monta o documento a partir das rotas registradas e dos
+// tipos de requisição e resposta de cada uma.
+func (s *Servidor) DocumentoOpenAPI() map[string]any {
+	esquemas := map[string]any{}
+	caminhos := map[string]any{}
+
+	for _, r := range s.rotas {
+		operacao := map[string]any{
+			"summary":     r.resumo,
+			"operationId": strings.ToLower(r.metodo) + idOperacao(r.caminho),
+		}
+
+		var parametros []any
+		for _, nome := range parametroCaminho.FindAllStringSubmatch(r.caminho, -1) {
+			parametros = append(parametros, map[string]any{
+				"name": nome[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+			})
+		}
+		for _, p := range r.parametros {
+			parametros = append(parametros, map[string]any{
+				"name": p.nome, "in": "query", "description": p.descricao, "schema": map[string]any{"type": "string"},
+			})
+		}
+		if r.metodo == http.MethodPost {
+			parametros = append(parametros, map[string]any{
+				"name": "Idempotency-Key", "in": "header",
+				"description": "repetir a chave devolve o resultado original sem aplicar a operação de novo",
+				"schema":      map[string]any{"type": "string"},
+			})
+		}
+		if len(parametros) > 0 {
+			operacao["parameters"] = parametros
+		}
+
+		if r.requisicao != nil {
+			operacao["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": referenciaEsquema(reflect.TypeOf(r.requisicao), esquemas)}},
+			}
+		}
+		erro := map[string]any{
+			"description": "erro",
+			"content":     map[string]any{"application/json": map[string]any{"schema": referenciaEsquema(reflect.TypeOf(ErroResposta{}), esquemas)}},
+		}
+		conteudo := map[string]any{"application/json": map[string]any{"schema": referenciaEsquema(reflect.TypeOf(r.resposta), esquemas)}}
+		for _, tipo := range r.conteudos {
+			conteudo[tipo] = map[string]any{"schema": map[string]any{"type": "string"}}
+		}
+		operacao["responses"] = map[string]any{
+			strconv.Itoa(r.status): map[string]any{
+				"description": http.StatusText(r.status),
+				"content":     conteudo,
+			},
+			"default": erro,
+		}
+
+		item, ok := caminhos[r.caminho].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			caminhos[r.caminho] = item
+		}
+		item[strings.ToLower(r.metodo)] = operacao
+	}
+
+	return map[string]any{
+		"openapi":    "3.0.3",
+		"info":       map[string]any{"title": "API de contas", "version": "1.0.0"},
+		"paths":      caminhos,
+		"components": map[string]any{"schemas": esquemas},
+	}
+}
+
+// referenciaEsquema registra o esquema do tipo em esquemas (structs viram
+// componentes nomeados) e devolve como referenciá-lo.
+func referenciaEsquema(tipo reflect.Type, esquemas map[string]any) map[string]any {
+	for tipo.Kind() == reflect.Pointer {
+		tipo = tipo.Elem()
+	}
+	switch {
+	case tipo == reflect.TypeOf(time.Time{}):
+		return map[string]any{"type": "string", "format": "date-time"}
+	case tipo.Kind() == reflect.Struct:
+		if _, ok := esquemas[tipo.Name()]; !ok {
+			esquemas[tipo.Name()] = nil // evita recursão infinita em tipos que se referenciam
+			esquemas[tipo.Name()] = esquemaStruct(tipo, esquemas)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + tipo.Name()}
+	case tipo.Kind() == reflect.Slice || tipo.Kind() == reflect.Array:
+		return map[string]any{"type": "array", "items": referenciaEsquema(tipo.Elem(), esquemas)}
+	case tipo.Kind() == reflect.String:
+		return map[string]any{"type": "string"}
+	case tipo.Kind() == reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case tipo.Kind() >= reflect.Int && tipo.Kind() <= reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case tipo.Kind() == reflect.Float32 || tipo.Kind() == reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
+
+func esquemaStruct(tipo reflect.Type, esquemas map[string]any) map[string]any {
+	propriedades := map[string]any{}
+	var obrigatorios []string
+	for i := 0; i < tipo.NumField(); i++ {
+		campo := tipo.Field(i)
+		if !campo.IsExported() {
+			continue
+		}
+		nome, opcoes, _ := strings.Cut(campo.Tag.Get("json"), ",")
+		if nome == "-" {
+			continue
+		}
+		if nome == "" {
+			nome = campo.Name
+		}
+		propriedades[nome] = referenciaEsquema(campo.Type, esquemas)
+		if !strings.Contains(opcoes, "omitempty") {
+			obrigatorios = append(obrigatorios, nome)
+		}
+	}
+	esquema := map[string]any{"type": "object", "properties": propriedades}
+	if len(obrigatorios) > 0 {
+		esquema["required"] = obrigatorios
+	}
+	return esquema
+}
+
+// idOperacao transforma "/contas/{id}/depositos" em "ContasIdDepositos".
+func idOperacao(caminho string) string {
+	var id strings.Builder
+	for _, parte := range strings.FieldsFunc(caminho, func(r rune) bool { return r == '/' || r == '{' || r == '}' }) {
+		id.WriteString(strings.ToUpper(parte[:1]) + parte[1:])
+	}
+	return id.String()
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"sync"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+// TipoChavePix é o tipo de uma chave registrada no diretório.
+type TipoChavePix string
+
+const (
+	ChaveCPF       TipoChavePix = "cpf"
+	ChaveEmail     TipoChavePix = "email"
+	ChaveTelefone  TipoChavePix = "telefone"
+	ChaveAleatoria TipoChavePix = "evp"
+
+	// maxChavesPorConta é o limite do DICT para contas de pessoa física.
+	maxChavesPorConta = 5
+)
+
+var (
+	ErrChavePixInvalida      = erros.Definir("pix.chave_invalida", erros.Validacao).Sentinela("chave Pix inválida")
+	ErrChavePixJaRegistrada  = erros.Definir("pix.chave_ja_registrada", erros.Conflito).Sentinela("chave Pix já registrada")
+	ErrChavePixNaoEncontrada = erros.Definir("pix.chave_nao_encontrada", erros.NaoEncontrado).Sentinela("chave Pix não encontrada")
+	ErrLimiteChavesPix       = erros.Definir("pix.limite_chaves", erros.Conflito).Sentinela("a conta já tem o máximo de chaves Pix")
+)
+
+type registroPix struct {
+	tipo  TipoChavePix
+	conta *Conta
+}
+
+// DiretorioPix faz o papel do DICT (o diretório central de chaves do Banco
+// Central) localmente: cada chave aponta para uma única conta.
+type DiretorioPix struct {
+	mu     sync.RWMutex
+	chaves map[string]registroPix
+}
+
+func NovoDiretorioPix() *DiretorioPix {
+	return &DiretorioPix{chaves: map[string]registroPix{}}
+}
+
+// Registrar valida e normaliza a chave e associa à conta. Para chaves
+// aleatórias, passe chave vazia: o diretório gera o UUID. Devolve a chave
+// como ficou registrada.
+func (d *DiretorioPix) Registrar(tipo TipoChavePix, chave string, conta *Conta) (string, error) {
+	if tipo == ChaveAleatoria && chave == "" {
+		evp, err := gerarEVP()
+		if err != nil {
+			return "", err
+		}
+		chave = evp
+	}
+	normalizada, err := NormalizarChavePix(tipo, chave)
+	if err != nil {
+		return "", err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.chaves[normalizada]; ok {
+		return "", fmt.Errorf("%w: %s", ErrChavePixJaRegistrada, normalizada)
+	}
+	total := 0
+	for _, r := range d.chaves {
+		if r.conta == conta {
+			total++
+		}
+	}
+	if total >= maxChavesPorConta {
+		return "", ErrLimiteChavesPix
+	}
+	d.chaves[normalizada] = registroPix{tipo: tipo, conta: conta}
+	return normalizada, nil
+}
+
+// Resolver encontra a conta dona da chave. A chave é procurada como foi
+// digitada e, se não encontrada, normalizada como cada um dos tipos.
+func (d *DiretorioPix) Resolver(chave string) (*Conta, TipoChavePix, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	_, r, ok := d.localizar(chave)
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %s", ErrChavePixNaoEncontrada, chave)
+	}
+	return r.conta, r.tipo, nil
+}
+
+// Remover apaga a chave, aceita como foi digitada ou normalizada, do mesmo
+// jeito que Resolver.
+func (d *DiretorioPix) Remover(chave string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	registrada, _, ok := d.localizar(chave)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrChavePixNaoEncontrada, chave)
+	}
+	delete(d.chaves, registrada)
+	return nil
+}
+
+// localizar devolve a chave como está registrada e o seu registro. Deve ser
+// chamado com d.mu travado.
+func (d *DiretorioPix) localizar(chave string) (string, registroPix, bool) {
+	if r, ok := d.chaves[chave]; ok {
+		return chave, r, true
+	}
+	for _, tipo := range []TipoChavePix{ChaveCPF, ChaveEmail, ChaveTelefone, ChaveAleatoria} {
+		normalizada, err := NormalizarChavePix(tipo, chave)
+		if err != nil {
+			continue
+		}
+		if r, ok := d.chaves[normalizada]; ok {
+			return normalizada, r, true
+		}
+	}
+	return "", registroPix{}, false
+}
+
+// TransferirPix resolve a chave e transfere na hora para a conta dona dela.
+func (d *DiretorioPix) TransferirPix(origem *Conta, chave string, valor int) (*Conta, error) {
+	destino, _, err := d.Resolver(chave)
+	if err != nil {
+		return nil, err
+	}
+	_, err = transferir(origem, destino, valor, OperacaoPix, "pix")
+	return destino, err
+}
+
+// PagarBRCode lê um "copia e cola", resolve a chave contida nele e transfere.
+// Se o código não traz valor, é usado o valor informado.
+func (d *DiretorioPix) PagarBRCode(origem *Conta, payload string, valor int) (*Conta, error) {
+	codigo, err := LerBRCode(payload)
+	if err != nil {
+		return nil, err
+	}
+	if codigo.Valor > 0 {
+		valor = codigo.Valor
+	}
+	return d.TransferirPix(origem, codigo.Chave, valor)
+}
+
+var (
+	apenasDigitos = regexp.MustCompile(`\D`)
+	formatoEVP    = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+)
+
+// NormalizarChavePix valida a chave do tipo informado e devolve a forma
+// usada no diretório: CPF só com dígitos, e-mail em minúsculas, telefone no
+// formato +55DDNNNNNNNNN e chave aleatória em minúsculas.
+func NormalizarChavePix(tipo TipoChavePix, chave string) (string, error) {
+	chave = strings.TrimSpace(chave)
+	switch tipo {
+	case ChaveCPF:
+		cpf := apenasDigitos.ReplaceAllString(chave, "")
+		if !cpfValido(cpf) {
+			return "", fmt.Errorf("%w: CPF %q", ErrChavePixInvalida, chave)
+		}
+		return cpf, nil
+	case ChaveEmail:
+		endereco, err := mail.ParseAddress(chave)
+		if err != nil || endereco.Address != chave || len(chave) > 77 {
+			return "", fmt.Errorf("%w: e-mail %q", ErrChavePixInvalida, chave)
+		}
+		return strings.ToLower(chave), nil
+	case ChaveTelefone:
+		digitos := apenasDigitos.ReplaceAllString(chave, "")
+		if strings.HasPrefix(chave, "+") || len(digitos) == 13 {
+			digitos = strings.TrimPrefix(digitos, "55")
+		}
+		if len(digitos) != 11 || digitos[2] != '9' {
+			return "", fmt.Errorf("%w: telefone %q", ErrChavePixInvalida, chave)
+		}
+		return "+55" + digitos, nil
+	case ChaveAleatoria:
+		evp := strings.ToLower(chave)
+		if !formatoEVP.MatchString(evp) {
+			return "", fmt.Errorf("%w: chave aleatória %q", ErrChavePixInvalida, chave)
+		}
+		return evp, nil
+	}
+	return "", fmt.Errorf("%w: tipo %q", ErrChavePixInvalida, tipo)
+}
+
+// cpfValido confere os dois dígitos verificadores.
+func cpfValido(cpf string) bool {
+	if len(cpf) != 11 || strings.Count(cpf, cpf[:1]) == 11 {
+		return false
+	}
+	for _, n := range []int{9, 10} {
+		soma := 0
+		for i := 0; i < n; i++ {
+			soma += int(cpf[i]-'0') * (n + 1 - i)
+		}
+		digito := soma * 10 % 11 % 10
+		if digito != int(cpf[n]-'0') {
+			return false
+		}
+	}
+	return true
+}
+
+// gerarEVP gera um UUID versão 4, o formato das chaves aleatórias.
+func gerarEVP() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDiretorioPixRemoverNormalizaAChave(t *testing.T) {
+	conta, err := NovaContaNoRazao(NovoRazao(nil), "Ana", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	casos := []struct {
+		tipo               TipoChavePix
+		registrar, remover string
+		registrada         string
+	}{
+		{ChaveCPF, "52998224725", "529.982.247-25", "52998224725"},
+		{ChaveTelefone, "+5511987654321", "(11) 98765-4321", "+5511987654321"},
+		{ChaveEmail, "ana@exemplo.com.br", " ana@exemplo.com.br ", "ana@exemplo.com.br"},
+	}
+	for _, c := range casos {
+		t.Run(string(c.tipo), func(t *testing.T) {
+			d := NovoDiretorioPix()
+			registrada, err := d.Registrar(c.tipo, c.registrar, conta)
+			if err != nil || registrada != c.registrada {
+				t.Fatalf("Registrar = %q, %v; quero %q", registrada, err, c.registrada)
+			}
+			if err := d.Remover(c.remover); err != nil {
+				t.Fatalf("Remover(%q) = %v", c.remover, err)
+			}
+			if _, _, err := d.Resolver(c.registrada); !errors.Is(err, ErrChavePixNaoEncontrada) {
+				t.Errorf("chave ainda resolve depois de removida: %v", err)
+			}
+			if err := d.Remover(c.remover); !errors.Is(err, ErrChavePixNaoEncontrada) {
+				t.Errorf("segunda remoção = %v, quero ErrChavePixNaoEncontrada", err)
+			}
+		})
+	}
+}
+
+func TestDiretorioPixResolverELimite(t *testing.T) {
+	razao := NovoRazao(nil)
+	ana, _ := NovaContaNoRazao(razao, "Ana", 0)
+	bia, _ := NovaContaNoRazao(razao, "Bia", 0)
+	d := NovoDiretorioPix()
+
+	if _, err := d.Registrar(ChaveCPF, "529.982.247-25", ana); err != nil {
+		t.Fatal(err)
+	}
+	conta, tipo, err := d.Resolver("529.982.247-25")
+	if err != nil || conta != ana || tipo != ChaveCPF {
+		t.Errorf("Resolver = %v, %q, %v; quero a conta da Ana", conta, tipo, err)
+	}
+	if _, err := d.Registrar(ChaveCPF, "52998224725", bia); !errors.Is(err, ErrChavePixJaRegistrada) {
+		t.Errorf("CPF repetido: erro = %v, quero ErrChavePixJaRegistrada", err)
+	}
+	if _, err := d.Registrar(ChaveCPF, "529.982.247-26", bia); !errors.Is(err, ErrChavePixInvalida) {
+		t.Errorf("CPF com DV errado: erro = %v, quero ErrChavePixInvalida", err)
+	}
+
+	for range maxChavesPorConta - 1 {
+		if _, err := d.Registrar(ChaveAleatoria, "", ana); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.Registrar(ChaveAleatoria, "", ana); !errors.Is(err, ErrLimiteChavesPix) {
+		t.Errorf("sexta chave: erro = %v, quero ErrLimiteChavesPix", err)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"math"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+// TipoJuros define como os juros de uma projeção são calculados.
+type TipoJuros int
+
+const (
+	// JurosSimples rende só sobre o capital aplicado (saldo inicial mais
+	// depósitos menos saques), sem render sobre os juros já ganhos.
+	JurosSimples TipoJuros = iota
+	// JurosCompostos rende sobre o saldo do início de cada mês.
+	JurosCompostos
+)
+
+var (
+	ErrMesesInvalidos = erros.Definir("projecao.meses_invalidos", erros.Validacao).Sentinela("a projeção precisa de pelo menos um mês")
+	ErrTaxaInvalida   = erros.Definir("projecao.taxa_invalida", erros.Validacao).Sentinela("a taxa não pode ser menor que -100%")
+)
+
+// ParametrosProjecao descreve um cenário: quanto entra e sai por mês e a
+// taxa mensal (0.01 = 1% ao mês). Valores em centavos.
+type ParametrosProjecao struct {
+	Meses          int
+	DepositoMensal int
+	SaqueMensal    int
+	TaxaMensal     float64
+	Juros          TipoJuros
+}
+
+// MesProjetado é uma linha da tabela de projeção. Os juros do mês são
+// calculados sobre o saldo de abertura; depósitos e saques entram no fim do mês.
+type MesProjetado struct {
+	Mes          int
+	SaldoInicial int
+	Juros        int
+	Depositos    int
+	Saques       int
+	SaldoFinal   int
+}
+
+// Projetar calcula mês a mês o saldo a partir de saldoInicial. É uma função
+// pura: não mexe em nenhuma conta.
+func Projetar(saldoInicial int, p ParametrosProjecao) ([]MesProjetado, error) {
+	if p.Meses <= 0 {
+		return nil, ErrMesesInvalidos
+	}
+	if p.TaxaMensal < -1 {
+		return nil, ErrTaxaInvalida
+	}
+
+	tabela := make([]MesProjetado, 0, p.Meses)
+	saldo := saldoInicial
+	capital := saldoInicial
+	for mes := 1; mes <= p.Meses; mes++ {
+		base := saldo
+		if p.Juros == JurosSimples {
+			base = capital
+		}
+		juros := int(math.Round(float64(base) * p.TaxaMensal))
+
+		linha := MesProjetado{
+			Mes:          mes,
+			SaldoInicial: saldo,
+			Juros:        juros,
+			Depositos:    p.DepositoMensal,
+			Saques:       p.SaqueMensal,
+		}
+		saldo += juros + p.DepositoMensal - p.SaqueMensal
+		capital += p.DepositoMensal - p.SaqueMensal
+		linha.SaldoFinal = saldo
+		tabela = append(tabela, linha)
+	}
+	return tabela, nil
+}
+
+// Projetar projeta o saldo atual da conta sem alterá-lo.
+func (c *Conta) Projetar(p ParametrosProjecao) ([]MesProjetado, error) {
+	return Projetar(c.Saldo(), p)
+}
+
+// ComparacaoCenarios põe lado a lado o saldo final de cada mês para várias
+// taxas: Saldos[i][j] é o saldo no fim do mês i+1 com a taxa Taxas[j].
+type ComparacaoCenarios struct {
+	Taxas  []float64
+	Saldos [][]int
+}
+
+// CompararCenarios roda a mesma projeção para cada taxa informada,
+// ignorando a TaxaMensal de p.
+func CompararCenarios(saldoInicial int, p ParametrosProjecao, taxas ...float64) (ComparacaoCenarios, error) {
+	if p.Meses <= 0 {
+		return ComparacaoCenarios{}, ErrMesesInvalidos
+	}
+
+	comparacao := ComparacaoCenarios{Taxas: taxas, Saldos: make([][]int, p.Meses)}
+	for i := range comparacao.Saldos {
+		comparacao.Saldos[i] = make([]int, len(taxas))
+	}
+	for j, taxa := range taxas {
+		p.TaxaMensal = taxa
+		tabela, err := Projetar(saldoInicial, p)
+		if err != nil {
+			return ComparacaoCenarios{}, err
+		}
+		for i, linha := range tabela {
+			comparacao.Saldos[i][j] = linha.SaldoFinal
+		}
+	}
+	return comparacao, nil
+}
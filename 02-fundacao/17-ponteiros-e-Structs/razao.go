@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+// ErrLancamentoDesbalanceado é retornado quando a soma dos débitos de um
+// lançamento não bate com a soma dos créditos.
+var ErrLancamentoDesbalanceado = erros.Definir("razao.lancamento_desbalanceado", erros.Validacao).Sentinela("lançamento desbalanceado: débitos diferentes dos créditos")
+
+// ContaCaixa é a contrapartida dos depósitos e saques em reais: o dinheiro
+// que entra ou sai do banco. Cada outra moeda tem o seu caixa ("caixa-USD").
+const ContaCaixa = "caixa"
+
+func contaCaixa(moeda Moeda) string {
+	if moeda == BRL {
+		return ContaCaixa
+	}
+	return ContaCaixa + "-" + string(moeda)
+}
+
+// Natureza diz se uma partida debita ou credita a conta.
+type Natureza int
+
+const (
+	Debito Natureza = iota
+	Credito
+)
+
+func (n Natureza) String() string {
+	if n == Debito {
+		return "D"
+	}
+	return "C"
+}
+
+// Partida é uma linha de um lançamento: um débito ou um crédito numa conta.
+type Partida struct {
+	Conta    string
+	Natureza Natureza
+	Valor    int
+}
+
+// Lancamento é um registro imutável do razão. Seq é a ordem em que foi gravado.
+type Lancamento struct {
+	Seq       int
+	Data      time.Time
+	Descricao string
+	Partidas  []Partida
+	// Cambio registra a conversão aplicada nas transferências entre moedas.
+	Cambio *Conversao
+}
+
+// Balanceado informa se os débitos somam o mesmo que os créditos.
+func (l Lancamento) Balanceado() bool {
+	debitos, creditos := 0, 0
+	for _, p := range l.Partidas {
+		if p.Natureza == Debito {
+			debitos += p.Valor
+		} else {
+			creditos += p.Valor
+		}
+	}
+	return debitos == creditos && debitos > 0
+}
+
+// Razao é o livro de partidas dobradas. Os lançamentos só são acrescentados,
+// nunca alterados, e os saldos das contas são derivados deles: saldo é a soma
+// dos créditos menos a soma dos débitos.
+type Razao struct {
+	relogio Relogio
+
+	mu          sync.RWMutex
+	lancamentos []Lancamento
+	saldos      map[string]int
+}
+
+func NovoRazao(relogio Relogio) *Razao {
+	if relogio == nil {
+		relogio = relogioSistema{}
+	}
+	return &Razao{relogio: relogio, saldos: map[string]int{}}
+}
+
+// razaoPadrao é o razão usado pelas contas criadas com NewConta.
+var razaoPadrao = NovoRazao(nil)
+
+// Lancar grava um lançamento com as partidas informadas. Lançamentos
+// desbalanceados ou com valores que não sejam positivos são recusados.
+func (r *Razao) Lancar(descricao string, partidas ...Partida) (Lancamento, error) {
+	return r.registrar(Lancamento{Descricao: descricao, Partidas: partidas})
+}
+
+// LancarComCambio grava o lançamento junto com a conversão de moeda aplicada.
+func (r *Razao) LancarComCambio(descricao string, conversao Conversao, partidas ...Partida) (Lancamento, error) {
+	return r.registrar(Lancamento{Descricao: descricao, Partidas: partidas, Cambio: &conversao})
+}
+
+func (r *Razao) registrar(lancamento Lancamento) (Lancamento, error) {
+	for _, p := range lancamento.Partidas {
+		if p.Valor <= 0 {
+			return Lancamento{}, ErrValorInvalido
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lancamento = copiarLancamento(lancamento)
+	lancamento.Seq = len(r.lancamentos) + 1
+	lancamento.Data = r.relogio.Agora()
+	if !lancamento.Balanceado() {
+		return Lancamento{}, ErrLancamentoDesbalanceado
+	}
+	r.lancamentos = append(r.lancamentos, lancamento)
+	for _, p := range lancamento.Partidas {
+		r.saldos[p.Conta] += efeito(p)
+	}
+	return copiarLancamento(lancamento), nil
+}
+
+// Saldo devolve o saldo atual da conta.
+func (r *Razao) Saldo(conta string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.saldos[conta]
+}
+
+// SaldoEm refaz o saldo da conta considerando só os lançamentos com data
+// até t (inclusive).
+func (r *Razao) SaldoEm(conta string, t time.Time) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	saldo := 0
+	for _, l := range r.lancamentos {
+		if l.Data.After(t) {
+			continue
+		}
+		for _, p := range l.Partidas {
+			if p.Conta == conta {
+				saldo += efeito(p)
+			}
+		}
+	}
+	return saldo
+}
+
+// Historico devolve uma cópia dos lançamentos; alterar a cópia não afeta o razão.
+func (r *Razao) Historico() []Lancamento {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	historico := make([]Lancamento, len(r.lancamentos))
+	for i, l := range r.lancamentos {
+		historico[i] = copiarLancamento(l)
+	}
+	return historico
+}
+
+// LinhaBalancete traz os totais de uma conta no balancete.
+type LinhaBalancete struct {
+	Conta    string
+	Debitos  int
+	Creditos int
+}
+
+// Balancete é o balancete de verificação: em partidas dobradas o total de
+// débitos tem que ser igual ao total de créditos.
+type Balancete struct {
+	Linhas        []LinhaBalancete
+	TotalDebitos  int
+	TotalCreditos int
+}
+
+func (b Balancete) Fechado() bool {
+	return b.TotalDebitos == b.TotalCreditos
+}
+
+// Balancete soma débitos e créditos de cada conta, em ordem de nome.
+func (r *Razao) Balancete() Balancete {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	totais := map[string]*LinhaBalancete{}
+	var balancete Balancete
+	for _, l := range r.lancamentos {
+		for _, p := range l.Partidas {
+			linha, ok := totais[p.Conta]
+			if !ok {
+				linha = &LinhaBalancete{Conta: p.Conta}
+				totais[p.Conta] = linha
+			}
+			if p.Natureza == Debito {
+				linha.Debitos += p.Valor
+				balancete.TotalDebitos += p.Valor
+			} else {
+				linha.Creditos += p.Valor
+				balancete.TotalCreditos += p.Valor
+			}
+		}
+	}
+	for _, linha := range totais {
+		balancete.Linhas = append(balancete.Linhas, *linha)
+	}
+	sort.Slice(balancete.Linhas, func(i, j int) bool { return balancete.Linhas[i].Conta < balancete.Linhas[j].Conta })
+	return balancete
+}
+
+// Verificar percorre o histórico e aponta o primeiro lançamento
+// desbalanceado ou fora de sequência.
+func (r *Razao) Verificar() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i, l := range r.lancamentos {
+		if l.Seq != i+1 {
+			return fmt.Errorf("lançamento %d fora de sequência (esperado %d)", l.Seq, i+1)
+		}
+		if !l.Balanceado() {
+			return fmt.Errorf("lançamento %d: %w", l.Seq, ErrLancamentoDesbalanceado)
+		}
+	}
+	return nil
+}
+
+func efeito(p Partida) int {
+	if p.Natureza == Debito {
+		return -p.Valor
+	}
+	return p.Valor
+}
+
+func copiarLancamento(l Lancamento) Lancamento {
+	l.Partidas = append([]Partida{}, l.Partidas...)
+	if l.Cambio != nil {
+		cambio := *l.Cambio
+		l.Cambio = &cambio
+	}
+	return l
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"02-fundacao/02-fundacao/08-funcao/erros"
+)
+
+// TipoOperacao identifica a operação avaliada pelas regras.
+type TipoOperacao string
+
+const (
+	OperacaoSaque         TipoOperacao = "saque"
+	OperacaoTransferencia TipoOperacao = "transferencia"
+	OperacaoPix           TipoOperacao = "pix"
+)
+
+// Operacao é o que as regras recebem para avaliar, antes de ser aplicada.
+type Operacao struct {
+	Tipo    TipoOperacao
+	Origem  string
+	Destino string
+	Valor   int
+	Data    time.Time
+}
+
+// Resultado de uma regra, do mais brando ao mais severo.
+type Resultado int
+
+const (
+	Permitir Resultado = iota
+	Revisar
+	Negar
+)
+
+func (r Resultado) String() string {
+	return [...]string{"permitir", "revisar", "negar"}[r]
+}
+
+// Decisao é a resposta de uma regra. Regra e Motivo explicam decisões que
+// não sejam Permitir.
+type Decisao struct {
+	Resultado Resultado
+	Regra     string
+	Motivo    string
+}
+
+// ErrOperacaoNegada é retornado quando uma regra nega a operação.
+type ErrOperacaoNegada struct {
+	Decisao Decisao
+}
+
+func (e *ErrOperacaoNegada) Error() string {
+	return fmt.Sprintf("operação negada pela regra %s: %s", e.Decisao.Regra, e.Decisao.Motivo)
+}
+
+var errOperacaoNegada = erros.Definir("regras.operacao_negada", erros.Validacao).Novo(nil)
+
+// Unwrap liga o erro ao seu código, para erros.CodigoDe.
+func (e *ErrOperacaoNegada) Unwrap() error {
+	return errOperacaoNegada
+}
+
+// ErrOperacaoEmRevisao é retornado quando uma regra pede revisão manual; a
+// operação não é aplicada.
+type ErrOperacaoEmRevisao struct {
+	Decisao Decisao
+}
+
+func (e *ErrOperacaoEmRevisao) Error() string {
+	return fmt.Sprintf("operação enviada para revisão pela regra %s: %s", e.Decisao.Regra, e.Decisao.Motivo)
+}
+
+// A revisão é um conflito: a operação não foi aplicada e precisa ser
+// enviada de novo depois de aprovada.
+var errOperacaoEmRevisao = erros.Definir("regras.operacao_em_revisao", erros.Conflito).Novo(nil)
+
+// Unwrap liga o erro ao seu código, para erros.CodigoDe e erros.StatusHTTP.
+func (e *ErrOperacaoEmRevisao) Unwrap() error {
+	return errOperacaoEmRevisao
+}
+
+// Regra avalia uma operação olhando o histórico das operações já aplicadas.
+type Regra interface {
+	Nome() string
+	Avaliar(op Operacao, historico []Operacao) Decisao
+}
+
+// LimiteDiario limita a soma das saídas da conta no mesmo dia.
+type LimiteDiario struct {
+	Limite int
+}
+
+func (LimiteDiario) Nome() string { return "limite_diario" }
+
+func (r LimiteDiario) Avaliar(op Operacao, historico []Operacao) Decisao {
+	ano, mes, dia := op.Data.Date()
+	total := op.Valor
+	for _, h := range historico {
+		if a, m, d := h.Data.Date(); h.Origem == op.Origem && a == ano && m == mes && d == dia {
+			total += h.Valor
+		}
+	}
+	if total > r.Limite {
+		return Decisao{Negar, r.Nome(), fmt.Sprintf("saídas do dia somariam %d, limite %d", total, r.Limite)}
+	}
+	return Decisao{Resultado: Permitir}
+}
+
+// LimitePixNoturno limita o total de Pix enviados no período noturno (por
+// padrão das 20h às 6h, como na regra do Banco Central).
+type LimitePixNoturno struct {
+	Inicio int
+	Fim    int
+	Limite int
+}
+
+func (LimitePixNoturno) Nome() string { return "pix_noturno" }
+
+func (r LimitePixNoturno) Avaliar(op Operacao, historico []Operacao) Decisao {
+	if op.Tipo != OperacaoPix || !r.noturno(op.Data) {
+		return Decisao{Resultado: Permitir}
+	}
+	inicioDaNoite := r.inicioDaNoite(op.Data)
+	total := op.Valor
+	for _, h := range historico {
+		if h.Tipo == OperacaoPix && h.Origem == op.Origem && !h.Data.Before(inicioDaNoite) && r.noturno(h.Data) {
+			total += h.Valor
+		}
+	}
+	if total > r.Limite {
+		return Decisao{Negar, r.Nome(), fmt.Sprintf("Pix noturnos somariam %d, limite %d", total, r.Limite)}
+	}
+	return Decisao{Resultado: Permitir}
+}
+
+func (r LimitePixNoturno) noturno(t time.Time) bool {
+	return t.Hour() >= r.Inicio || t.Hour() < r.Fim
+}
+
+func (r LimitePixNoturno) inicioDaNoite(t time.Time) time.Time {
+	inicio := time.Date(t.Year(), t.Month(), t.Day(), r.Inicio, 0, 0, 0, t.Location())
+	if t.Hour() < r.Fim {
+		inicio = inicio.AddDate(0, 0, -1)
+	}
+	return inicio
+}
+
+// Velocidade manda para revisão quando a conta já fez Maximo saídas dentro
+// da janela de tempo.
+type Velocidade struct {
+	Maximo int
+	Janela time.Duration
+}
+
+func (Velocidade) Nome() string { return "velocidade" }
+
+func (r Velocidade) Avaliar(op Operacao, historico []Operacao) Decisao {
+	desde := op.Data.Add(-r.Janela)
+	quantidade := 0
+	for _, h := range historico {
+		if h.Origem == op.Origem && h.Tipo != OperacaoSaque && h.Data.After(desde) {
+			quantidade++
+		}
+	}
+	if op.Tipo != OperacaoSaque && quantidade >= r.Maximo {
+		return Decisao{Revisar, r.Nome(), fmt.Sprintf("%d transferências nos últimos %s", quantidade, r.Janela)}
+	}
+	return Decisao{Resultado: Permitir}
+}
+
+// DestinosBloqueados nega transferências para as contas listadas.
+type DestinosBloqueados struct {
+	Destinos map[string]bool
+}
+
+func (DestinosBloqueados) Nome() string { return "destinos_bloqueados" }
+
+func (r DestinosBloqueados) Avaliar(op Operacao, _ []Operacao) Decisao {
+	if op.Destino != "" && r.Destinos[op.Destino] {
+		return Decisao{Negar, r.Nome(), fmt.Sprintf("destino %s bloqueado", op.Destino)}
+	}
+	return Decisao{Resultado: Permitir}
+}
+
+// regraComJanela é implementada pelas regras que olham o histórico: a
+// janela diz até quanto tempo para trás elas consultam.
+type regraComJanela interface {
+	JanelaHistorico() time.Duration
+}
+
+// Um dia inteiro: o limite diário olha desde a meia-noite.
+func (LimiteDiario) JanelaHistorico() time.Duration { return 24 * time.Hour }
+
+// A noite começa no dia anterior quando a operação é de madrugada.
+func (LimitePixNoturno) JanelaHistorico() time.Duration { return 48 * time.Hour }
+
+func (r Velocidade) JanelaHistorico() time.Duration { return r.Janela }
+
+// retencaoMinima é quanto o histórico guarda mesmo sem regras que peçam:
+// assim trocar as regras por outras com janela de até um dia não começa
+// com o histórico vazio.
+const retencaoMinima = 48 * time.Hour
+
+// MotorRegras passa cada operação pela cadeia de regras e guarda o histórico
+// das operações aplicadas, que as regras usam para somar e contar. Só fica
+// no histórico o que alguma regra ainda pode consultar (veja retencao), para
+// que a memória não cresça sem limite num servidor que roda por meses.
+type MotorRegras struct {
+	relogio Relogio
+
+	mu        sync.Mutex
+	regras    []Regra
+	retencao  time.Duration
+	historico []Operacao
+}
+
+func NovoMotorRegras(relogio Relogio, regras ...Regra) *MotorRegras {
+	if relogio == nil {
+		relogio = relogioSistema{}
+	}
+	return &MotorRegras{relogio: relogio, regras: regras, retencao: retencaoDe(regras)}
+}
+
+// retencaoDe é a maior janela entre as regras, e no mínimo retencaoMinima.
+func retencaoDe(regras []Regra) time.Duration {
+	retencao := retencaoMinima
+	for _, r := range regras {
+		if comJanela, ok := r.(regraComJanela); ok {
+			retencao = max(retencao, comJanela.JanelaHistorico())
+		}
+	}
+	return retencao
+}
+
+// Avaliar roda as regras em ordem e devolve a decisão mais severa. A
+// primeira negação encerra a avaliação.
+func (m *MotorRegras) Avaliar(op Operacao) Decisao {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if op.Data.IsZero() {
+		op.Data = m.relogio.Agora()
+	}
+	final := Decisao{Resultado: Permitir}
+	for _, regra := range m.regras {
+		decisao := regra.Avaliar(op, m.historico)
+		if decisao.Resultado > final.Resultado {
+			final = decisao
+		}
+		if final.Resultado == Negar {
+			break
+		}
+	}
+	return final
+}
+
+// Registrar guarda uma operação aplicada no histórico.
+func (m *MotorRegras) Registrar(op Operacao) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	agora := m.relogio.Agora()
+	if op.Data.IsZero() {
+		op.Data = agora
+	}
+	m.historico = append(m.historico, op)
+	m.podar(agora)
+}
+
+// podar descarta do começo do histórico as operações mais velhas que a
+// retenção. As operações são registradas em ordem de data, então as velhas
+// estão todas no começo. Deve ser chamado com m.mu travado.
+func (m *MotorRegras) podar(agora time.Time) {
+	limite := agora.Add(-m.retencao)
+	velhas := 0
+	for velhas < len(m.historico) && m.historico[velhas].Data.Before(limite) {
+		velhas++
+	}
+	// Refatiar não copia nada; o começo descartado do array é liberado
+	// quando o append seguinte precisar de um array maior.
+	m.historico = m.historico[velhas:]
+}
+
+// Trocar substitui a cadeia de regras; o histórico é mantido. Uma regra
+// nova com janela maior que a das anteriores só enxerga o que ainda estava
+// retido.
+func (m *MotorRegras) Trocar(regras ...Regra) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.regras = regras
+	m.retencao = retencaoDe(regras)
+}
+
+// configRegras é o formato do arquivo de regras:
+//
+//	{"regras": [
+//	  {"tipo": "limite_diario", "limite": 500000},
+//	  {"tipo": "pix_noturno", "inicio": 20, "fim": 6, "limite": 100000},
+//	  {"tipo": "velocidade", "maximo": 5, "janela_minutos": 10},
+//	  {"tipo": "destinos_bloqueados", "destinos": ["conta-99"]}
+//	]}
+type configRegras struct {
+	Regras []struct {
+		Tipo          string   `json:"tipo"`
+		Limite        int      `json:"limite"`
+		Inicio        *int     `json:"inicio"`
+		Fim           *int     `json:"fim"`
+		Maximo        int      `json:"maximo"`
+		JanelaMinutos int      `json:"janela_minutos"`
+		Destinos      []string `json:"destinos"`
+	} `json:"regras"`
+}
+
+// CarregarRegras lê a cadeia de regras de um arquivo JSON. Valores fora do
+// intervalo (limite negativo, hora fora de 0 a 23, janela vazia) são erro:
+// num recarregamento, isso mantém as regras atuais em vez de trocá-las por
+// uma regra que não protege nada.
+func CarregarRegras(caminho string) ([]Regra, error) {
+	conteudo, err := os.ReadFile(caminho)
+	if err != nil {
+		return nil, err
+	}
+	var config configRegras
+	if err := json.Unmarshal(conteudo, &config); err != nil {
+		return nil, fmt.Errorf("arquivo de regras %s: %w", caminho, err)
+	}
+
+	var regras []Regra
+	for i, c := range config.Regras {
+		invalida := func(formato string, args ...any) error {
+			return fmt.Errorf("arquivo de regras %s: regra %d (%s): %s", caminho, i, c.Tipo, fmt.Sprintf(formato, args...))
+		}
+		if c.Limite < 0 {
+			return nil, invalida("limite negativo %d", c.Limite)
+		}
+		for _, hora := range []*int{c.Inicio, c.Fim} {
+			if hora != nil && (*hora < 0 || *hora > 23) {
+				return nil, invalida("hora %d fora de 0 a 23", *hora)
+			}
+		}
+		switch c.Tipo {
+		case "limite_diario":
+			regras = append(regras, LimiteDiario{Limite: c.Limite})
+		case "pix_noturno":
+			regra := LimitePixNoturno{Inicio: 20, Fim: 6, Limite: c.Limite}
+			if c.Inicio != nil {
+				regra.Inicio = *c.Inicio
+			}
+			if c.Fim != nil {
+				regra.Fim = *c.Fim
+			}
+			regras = append(regras, regra)
+		case "velocidade":
+			if c.Maximo < 1 || c.JanelaMinutos < 1 {
+				return nil, invalida("maximo e janela_minutos precisam ser positivos")
+			}
+			regras = append(regras, Velocidade{Maximo: c.Maximo, Janela: time.Duration(c.JanelaMinutos) * time.Minute})
+		case "destinos_bloqueados":
+			destinos := map[string]bool{}
+			for _, d := range c.Destinos {
+				destinos[d] = true
+			}
+			regras = append(regras, DestinosBloqueados{Destinos: destinos})
+		default:
+			return nil, fmt.Errorf("arquivo de regras %s: regra %d com tipo desconhecido %q", caminho, i, c.Tipo)
+		}
+	}
+	return regras, nil
+}
+
+// Observar confere o arquivo a cada intervalo e recarrega as regras quando
+// a data de modificação ou o tamanho mudam. Se o arquivo novo tiver erro, as regras atuais continuam valendo
+// e o erro é enviado no canal (sem bloquear, se ninguém estiver lendo). O
+// canal é fechado quando o contexto acaba.
+func (m *MotorRegras) Observar(ctx context.Context, caminho string, intervalo time.Duration) <-chan error {
+	erros := make(chan error, 1)
+	go func() {
+		defer close(erros)
+		var ultimaModificacao time.Time
+		var ultimoTamanho int64 = -1
+		ticker := time.NewTicker(intervalo)
+		defer ticker.Stop()
+		for {
+			info, err := os.Stat(caminho)
+			if err == nil && (!info.ModTime().Equal(ultimaModificacao) || info.Size() != ultimoTamanho) {
+				ultimaModificacao, ultimoTamanho = info.ModTime(), info.Size()
+				var regras []Regra
+				if regras, err = CarregarRegras(caminho); err == nil {
+					m.Trocar(regras...)
+				}
+			}
+			if err != nil {
+				select {
+				case erros <- err:
+				default:
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return erros
+}
+
+// erroDaDecisao converte uma decisão no erro devolvido pelas operações da conta.
+func erroDaDecisao(decisao Decisao) error {
+	switch decisao.Resultado {
+	case Negar:
+		return &ErrOperacaoNegada{Decisao: decisao}
+	case Revisar:
+		return &ErrOperacaoEmRevisao{Decisao: decisao}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMotorRegrasPodaHistorico(t *testing.T) {
+	relogio := NovoRelogioFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	motor := NovoMotorRegras(relogio, LimiteDiario{Limite: 1_000}, Velocidade{Maximo: 3, Janela: time.Hour})
+
+	// Um mês de operações, uma por hora: o histórico fica do tamanho da
+	// retenção, não do mês.
+	for range 30 * 24 {
+		motor.Registrar(Operacao{Tipo: OperacaoTransferencia, Origem: "a", Valor: 1})
+		relogio.Avancar(time.Hour)
+	}
+	motor.mu.Lock()
+	retidas := len(motor.historico)
+	motor.mu.Unlock()
+	if retidas > 49 {
+		t.Fatalf("histórico com %d operações, quero no máximo 49 (48 horas)", retidas)
+	}
+
+	// As regras continuam enxergando o que está dentro da janela.
+	if d := motor.Avaliar(Operacao{Tipo: OperacaoTransferencia, Origem: "a", Valor: 990}); d.Resultado != Negar {
+		t.Errorf("limite diário com o histórico podado: %v, quero negar", d.Resultado)
+	}
+}
+
+func TestCarregarRegrasRejeitaValoresForaDoIntervalo(t *testing.T) {
+	casos := map[string]string{
+		"limite negativo":   `{"regras": [{"tipo": "limite_diario", "limite": -1}]}`,
+		"hora de início":    `{"regras": [{"tipo": "pix_noturno", "inicio": 24, "limite": 100}]}`,
+		"hora de fim":       `{"regras": [{"tipo": "pix_noturno", "fim": -3, "limite": 100}]}`,
+		"janela vazia":      `{"regras": [{"tipo": "velocidade", "maximo": 5}]}`,
+		"máximo zero":       `{"regras": [{"tipo": "velocidade", "janela_minutos": 10}]}`,
+		"tipo desconhecido": `{"regras": [{"tipo": "outra"}]}`,
+	}
+	for nome, conteudo := range casos {
+		t.Run(nome, func(t *testing.T) {
+			caminho := filepath.Join(t.TempDir(), "regras.json")
+			if err := os.WriteFile(caminho, []byte(conteudo), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := CarregarRegras(caminho); err == nil || !strings.Contains(err.Error(), "regra 0") {
+				t.Fatalf("erro = %v, quero um erro apontando a regra 0", err)
+			}
+		})
+	}
+
+	caminho := filepath.Join(t.TempDir(), "regras.json")
+	os.WriteFile(caminho, []byte(`{"regras": [{"tipo": "pix_noturno", "inicio": 22, "fim": 0, "limite": 0}]}`), 0o644)
+	if _, err := CarregarRegras(caminho); err != nil {
+		t.Fatalf("regras válidas: %v", err)
+	}
+}
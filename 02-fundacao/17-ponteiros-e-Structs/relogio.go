@@ -0,0 +1,39 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Relogio é a fonte de horário. Em produção usamos o relógio do sistema; nos
+// exemplos e simulações dá para injetar um relógio controlado.
+type Relogio interface {
+	Agora() time.Time
+}
+
+type relogioSistema struct{}
+
+func (relogioSistema) Agora() time.Time { return time.Now() }
+
+// RelogioFake é um relógio parado que só anda quando mandado. Serve para
+// simular a passagem do tempo no agendador e nas retenções.
+type RelogioFake struct {
+	mu    sync.Mutex
+	agora time.Time
+}
+
+func NovoRelogioFake(inicio time.Time) *RelogioFake {
+	return &RelogioFake{agora: inicio}
+}
+
+func (r *RelogioFake) Agora() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.agora
+}
+
+func (r *RelogioFake) Avancar(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.agora = r.agora.Add(d)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestTransferenciasConcorrentes abre várias contas num razão próprio e
+// dispara transferências aleatórias entre elas a partir de várias
+// goroutines, inclusive A→B e B→A ao mesmo tempo. No final confere que o
+// dinheiro total não mudou, que o balancete fecha e que nenhuma goroutine
+// ficou para trás. Se as transferências não terminarem dentro do timeout,
+// provavelmente houve um deadlock. Rode com go test -race para que o
+// detector de corridas também confira os acessos às contas.
+func TestTransferenciasConcorrentes(t *testing.T) {
+	const (
+		contasNoTeste = 50
+		saldoInicial  = 10_000
+		goroutines    = 32
+		timeout       = time.Minute
+	)
+	transferencias := 100_000
+	if testing.Short() {
+		transferencias = 5_000
+	}
+	semente := time.Now().UnixNano()
+	t.Logf("semente %d", semente)
+
+	goroutinesAntes := runtime.NumGoroutine()
+	razao := NovoRazao(nil)
+	contas := make([]*Conta, contasNoTeste)
+	for i := range contas {
+		conta, err := NovaContaNoRazao(razao, fmt.Sprintf("stress-%d", i), saldoInicial)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contas[i] = conta
+	}
+	totalInicial := somarSaldos(contas)
+
+	var (
+		wg                    sync.WaitGroup
+		mu                    sync.Mutex
+		executadas, recusadas int
+		erroInesperado        error
+	)
+	porGoroutine := transferencias / goroutines
+	for g := 0; g < goroutines; g++ {
+		quantidade := porGoroutine
+		if g == 0 {
+			quantidade += transferencias % goroutines
+		}
+		sorteio := rand.New(rand.NewSource(semente + int64(g)))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, negadas := 0, 0
+			var falha error
+			for i := 0; i < quantidade; i++ {
+				origem := contas[sorteio.Intn(len(contas))]
+				destino := contas[sorteio.Intn(len(contas))]
+				valor := 1 + sorteio.Intn(saldoInicial/2+1)
+
+				err := Transferir(origem, destino, valor)
+				var semSaldo *ErrSaldoInsuficiente
+				switch {
+				case err == nil:
+					ok++
+				case errors.As(err, &semSaldo), errors.Is(err, ErrMesmaConta):
+					negadas++
+				default:
+					falha = err
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			executadas += ok
+			recusadas += negadas
+			if falha != nil && erroInesperado == nil {
+				erroInesperado = falha
+			}
+		}()
+	}
+
+	terminou := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(terminou)
+	}()
+	select {
+	case <-terminou:
+	case <-time.After(timeout):
+		t.Fatalf("transferências não terminaram em %s (deadlock?)", timeout)
+	}
+	t.Logf("%d executadas, %d recusadas", executadas, recusadas)
+
+	if erroInesperado != nil {
+		t.Fatalf("erro inesperado: %v", erroInesperado)
+	}
+	if executadas == 0 {
+		t.Fatal("nenhuma transferência foi executada")
+	}
+	if total := somarSaldos(contas); total != totalInicial {
+		t.Errorf("total mudou de %d para %d", totalInicial, total)
+	}
+	if !razao.Balancete().Fechado() {
+		t.Error("balancete não fecha")
+	}
+	if err := razao.Verificar(); err != nil {
+		t.Error(err)
+	}
+	if depois := esperarGoroutines(goroutinesAntes, time.Second); depois > goroutinesAntes {
+		t.Errorf("%d goroutines vazaram", depois-goroutinesAntes)
+	}
+}
+
+func somarSaldos(contas []*Conta) int {
+	total := 0
+	for _, c := range contas {
+		total += c.Saldo()
+	}
+	return total
+}
+
+// esperarGoroutines dá um tempo para as goroutines auxiliares terminarem
+// antes de contar, já que elas saem um pouco depois do wg.Done.
+func esperarGoroutines(esperado int, limite time.Duration) int {
+	prazo := time.Now().Add(limite)
+	for {
+		atual := runtime.NumGoroutine()
+		if atual <= esperado || time.Now().After(prazo) {
+			return atual
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
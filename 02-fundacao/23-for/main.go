@@ -1,6 +1,11 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+
+	"02-fundacao/02-fundacao/07-maps/kv"
+)
 
 func main() {
 	/*
@@ -95,4 +100,53 @@ func main() {
 	dois
 	três
 	*/
+
+	/*
+		5. O Loop for...range sobre um iterador
+
+		Desde o Go 1.23 o range também aceita funções iteradoras (iter.Seq e iter.Seq2). O kv.Store guarda o mapa em disco e o método Todos devolve um iter.Seq2: o laço é igual ao do map, mas as chaves vêm em ordem e os dados sobrevivem ao fechamento do Store. O exemplo grava num diretório temporário, fecha, reabre e lê de volta do disco; no fim o diretório é apagado.
+	*/
+	dir, err := os.MkdirTemp("", "23-for-estudantes-")
+	if err != nil {
+		fmt.Println("kv:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	if err := gravarEstudantes(dir, estudantes); err != nil {
+		fmt.Println("kv:", err)
+		return
+	}
+	store, err := kv.Abrir[string, int](dir, kv.Opcoes{})
+	if err != nil {
+		fmt.Println("kv:", err)
+		return
+	}
+	defer store.Fechar()
+
+	// Ao contrário do map, a ordem é sempre a mesma: Isis, João, Maria.
+	fmt.Printf("\nEstudantes lidos do disco: %d\n", store.Len())
+	for nome, idade := range store.Todos() {
+		fmt.Println("Nome:", nome, "Idade:", idade)
+	}
+}
+
+// gravarEstudantes grava os estudantes num Store novo e o fecha, para que a
+// leitura em main venha do disco e não da memória.
+func gravarEstudantes(dir string, estudantes map[string]int) error {
+	store, err := kv.Abrir[string, int](dir, kv.Opcoes{})
+	if err != nil {
+		return err
+	}
+	for nome, idade := range estudantes {
+		if err := store.Set(nome, idade); err != nil {
+			store.Fechar()
+			return err
+		}
+	}
+	if err := store.Set("Isis", 22); err != nil {
+		store.Fechar()
+		return err
+	}
+	return store.Fechar()
 }